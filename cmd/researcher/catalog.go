@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/catalog"
+)
+
+// runCatalog implements "researcher catalog": it groups the selected
+// captures into an endpoint catalog with inferred schemas.
+func runCatalog(args []string) error {
+	fs := flag.NewFlagSet("catalog", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "catalog [flags]")
+	var sel captureFlags
+	sel.register(fs)
+	format := fs.String("format", "md", "output format: md or json")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "md" && *format != "json" {
+		return fmt.Errorf("-format must be md or json")
+	}
+	c, err := buildCatalog(sel)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, func(w io.Writer) error {
+		if *format == "json" {
+			return writeJSON(w, c)
+		}
+		return c.WriteMarkdown(w)
+	})
+}
+
+// runCatalogDiff implements "researcher catalog-diff": it compares the
+// catalogs of two capture days (or ranges, or saved catalog JSON files).
+func runCatalogDiff(args []string) error {
+	fs := flag.NewFlagSet("catalog-diff", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "catalog-diff -base <day|from..to|catalog.json> -head <day|from..to|catalog.json> [flags]")
+	var sel captureFlags
+	sel.register(fs)
+	base := fs.String("base", "", "baseline: YYYY-MM-DD, FROM..TO or a catalog JSON file")
+	head := fs.String("head", "", "comparison: YYYY-MM-DD, FROM..TO or a catalog JSON file")
+	format := fs.String("format", "md", "output format: md or json")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	failOnChange := fs.Bool("exit-code", false, "exit with status 3 when the catalogs differ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *base == "" || *head == "" {
+		return fmt.Errorf("-base and -head are required")
+	}
+	if sel.from != "" || sel.to != "" {
+		return fmt.Errorf("use -base and -head instead of -from and -to")
+	}
+	if *format != "md" && *format != "json" {
+		return fmt.Errorf("-format must be md or json")
+	}
+	baseCat, err := loadCatalog(sel, *base)
+	if err != nil {
+		return fmt.Errorf("-base: %w", err)
+	}
+	headCat, err := loadCatalog(sel, *head)
+	if err != nil {
+		return fmt.Errorf("-head: %w", err)
+	}
+	d := catalog.Compare(baseCat, headCat)
+	err = writeOutput(*out, func(w io.Writer) error {
+		if *format == "json" {
+			return writeJSON(w, d)
+		}
+		return d.WriteMarkdown(w)
+	})
+	if err == nil && *failOnChange && !d.Empty() {
+		return exitCode(3)
+	}
+	return err
+}
+
+func buildCatalog(sel captureFlags) (*catalog.Catalog, error) {
+	s, err := sel.selection()
+	if err != nil {
+		return nil, err
+	}
+	b := catalog.NewBuilder()
+	if _, err := s.scan("", b); err != nil {
+		return nil, err
+	}
+	c := b.Catalog()
+	c.From, c.To = sel.from, sel.to
+	return c, nil
+}
+
+// loadCatalog reads a saved catalog if spec names a .json file, and
+// otherwise builds one for the day or FROM..TO range in spec.
+func loadCatalog(sel captureFlags, spec string) (*catalog.Catalog, error) {
+	if strings.HasSuffix(spec, ".json") {
+		data, err := os.ReadFile(spec)
+		if err != nil {
+			return nil, err
+		}
+		var c catalog.Catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", spec, err)
+		}
+		return &c, nil
+	}
+	sel.from, sel.to = spec, spec
+	if from, to, ok := strings.Cut(spec, ".."); ok {
+		sel.from, sel.to = from, to
+	}
+	return buildCatalog(sel)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeOutput calls write with stdout for "-" and otherwise with the
+// created file.
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
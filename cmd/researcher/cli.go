@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/config"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// captureFlags are the selection flags shared by the offline subcommands.
+type captureFlags struct {
+	dataDir string
+	from    string
+	to      string
+	path    string
+	tab     string
+}
+
+func (c *captureFlags) register(fs *flag.FlagSet) {
+	dataDir := "./research_data"
+	if cfg, err := config.Load(); err == nil {
+		dataDir = cfg.DataDir
+	}
+	fs.StringVar(&c.dataDir, "data-dir", dataDir, "capture directory (RESEARCHER_DATA_DIR)")
+	fs.StringVar(&c.from, "from", "", "first day or instant to include (YYYY-MM-DD or RFC3339, UTC)")
+	fs.StringVar(&c.to, "to", "", "last day or instant to include (YYYY-MM-DD or RFC3339, UTC)")
+	fs.StringVar(&c.path, "path", "", "only this tab path segment (e.g. chart)")
+	fs.StringVar(&c.tab, "tab", "", "only this tab (target ID or browser ID prefix)")
+}
+
+// captureSelection is the parsed form of captureFlags.
+type captureSelection struct {
+	dataDir  string
+	from, to time.Time // to is exclusive; zero means unbounded
+	path     string
+	tab      string
+}
+
+func (c *captureFlags) selection() (captureSelection, error) {
+	sel := captureSelection{dataDir: c.dataDir, path: c.path, tab: strings.ToUpper(c.tab)}
+	var err error
+	if c.from != "" {
+		if sel.from, _, err = parseDayOrTime(c.from); err != nil {
+			return sel, fmt.Errorf("-from: %w", err)
+		}
+	}
+	if c.to != "" {
+		var day bool
+		if sel.to, day, err = parseDayOrTime(c.to); err != nil {
+			return sel, fmt.Errorf("-to: %w", err)
+		}
+		if day {
+			sel.to = sel.to.AddDate(0, 0, 1)
+		}
+	}
+	if !sel.from.IsZero() && !sel.to.IsZero() && !sel.to.After(sel.from) {
+		return sel, fmt.Errorf("-to must be after -from")
+	}
+	return sel, nil
+}
+
+// parseDayOrTime parses YYYY-MM-DD (day is true) or RFC3339.
+func parseDayOrTime(s string) (t time.Time, day bool, err error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, false, fmt.Errorf("%q is not YYYY-MM-DD or RFC3339", s)
+	}
+	return t.UTC(), false, nil
+}
+
+// files lists the capture files of dataType ("" for all) that can hold
+// records in the selection.
+func (s captureSelection) files(dataType string) ([]storage.CaptureFile, error) {
+	f := storage.ScanFilter{From: s.from, PathSegment: s.path, DataType: dataType}
+	if !s.to.IsZero() {
+		f.To = s.to.Add(-time.Nanosecond)
+	}
+	return storage.ListCaptureFiles(s.dataDir, f)
+}
+
+// match reports whether a record's timestamp and tab are selected.
+func (s captureSelection) match(ts time.Time, tabID string) bool {
+	if !s.from.IsZero() && ts.Before(s.from) {
+		return false
+	}
+	if !s.to.IsZero() && !ts.Before(s.to) {
+		return false
+	}
+	return s.tab == "" || strings.HasPrefix(strings.ToUpper(tabID), s.tab)
+}
+
+// captureSink receives the decoded records of a scan.
+type captureSink interface {
+	AddHTTP(c *types.HTTPCapture)
+	AddWebSocket(c *types.WebSocketCapture)
+}
+
+// scan feeds every selected record of dataType ("" for all) to sink and
+// returns the number of files read. Capture gap markers and lines that do
+// not decode (e.g. a partial line from an unclean shutdown) are skipped.
+func (s captureSelection) scan(dataType string, sink captureSink) (int, error) {
+	files, err := s.files(dataType)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		err := storage.ReadJSONL(f.Path, func(line []byte) error {
+			if types.IsCaptureGap(line) {
+				return nil
+			}
+			switch f.DataType {
+			case "http":
+				var c types.HTTPCapture
+				if json.Unmarshal(line, &c) == nil && s.match(c.Timestamp, c.TabID) {
+					sink.AddHTTP(&c)
+				}
+			case "websocket":
+				var c types.WebSocketCapture
+				if json.Unmarshal(line, &c) == nil && s.match(c.Timestamp, c.TabID) {
+					sink.AddWebSocket(&c)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", f.Path, err)
+		}
+	}
+	return len(files), nil
+}
+
+func usageFor(fs *flag.FlagSet, synopsis string) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "usage: researcher %s\n\nflags:\n", synopsis)
+		fs.PrintDefaults()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/har"
+)
+
+// runExportHAR implements "researcher export-har": it converts the selected
+// HTTP and WebSocket captures into a HAR 1.2 archive.
+func runExportHAR(args []string) error {
+	fs := flag.NewFlagSet("export-har", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "export-har [flags]")
+	var sel captureFlags
+	sel.register(fs)
+	out := fs.String("o", "-", "output file (- for stdout)")
+	noWS := fs.Bool("no-websocket", false, "omit WebSocket connections and messages")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := sel.selection()
+	if err != nil {
+		return err
+	}
+
+	dataType := ""
+	if *noWS {
+		dataType = "http"
+	}
+	b := har.NewBuilder(version)
+	files, err := s.scan(dataType, b)
+	if err != nil {
+		return err
+	}
+
+	archive := b.Build()
+	if err := writeOutput(*out, func(w io.Writer) error { return writeJSON(w, archive) }); err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Fprintf(os.Stderr, "wrote %d entries from %d files to %s\n", len(archive.Log.Entries), files, *out)
+	}
+	return nil
+}
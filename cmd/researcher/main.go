@@ -2,22 +2,53 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/capture"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdp"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/config"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/redact"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/researchapi"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var version = "dev"
 
+// subcommands are the offline tools run as "researcher <command> [flags]".
+// Without a command the researcher captures traffic.
+var subcommands = map[string]func(args []string) error{
+	"export-har":   runExportHAR,
+	"query":        runQuery,
+	"catalog":      runCatalog,
+	"catalog-diff": runCatalogDiff,
+	"resources":    runResources,
+	"prune":        runPrune,
+	"replay":       runReplay,
+}
+
+// exitCode is returned by a subcommand to exit with a specific status
+// without printing an error.
+type exitCode int
+
+func (e exitCode) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runSubcommand(os.Args[1], os.Args[2:]))
+	}
+
 	if err := os.MkdirAll("logs", 0o755); err != nil {
 		slog.Debug("log directory creation failed", "error", err)
 	}
@@ -58,7 +89,25 @@ func main() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	redactCfg, err := redact.LoadConfig(cfg.RedactConfigPath)
+	if err != nil {
+		slog.Error("Failed to load redaction config; set RESEARCHER_REDACT_CONFIG= (empty) to run without redaction", "error", err)
+		os.Exit(1)
+	}
+	redactor, err := redact.New(redactCfg)
+	if err != nil {
+		slog.Error("Invalid redaction config", "error", err)
+		os.Exit(1)
+	}
+	defer redactor.Close()
+
 	writerRegistry := storage.NewWriterRegistry(cfg.DataDir, cfg.BufferSize, cfg.MaxFileSizeMB)
+	if redactor != nil {
+		writerRegistry.SetFilter(redactor.Apply)
+		slog.Info("Redaction enabled", "config", cfg.RedactConfigPath, "mode", redactor.Mode())
+	} else {
+		slog.Warn("Redaction disabled; captures contain raw cookies and tokens", "config", cfg.RedactConfigPath)
+	}
 	defer func() {
 		if err := writerRegistry.Close(); err != nil {
 			slog.Warn("Writer close failed", "error", err)
@@ -66,6 +115,10 @@ func main() {
 	}()
 
 	resourceWriter := storage.NewResourceWriter(cfg.DataDir)
+	if redactor != nil {
+		resourceWriter.SetFilter(redactor.Apply)
+	}
+	defer resourceWriter.Close()
 	tabRegistry := cdp.NewTabRegistry()
 
 	httpCapture := capture.NewHTTPCapture(writerRegistry, resourceWriter, tabRegistry,
@@ -74,6 +127,10 @@ func main() {
 
 	wsCapture := capture.NewWebSocketCapture(writerRegistry, tabRegistry, cfg.CaptureWS, cfg.WSMaxFrameBytes)
 
+	pauses := capture.NewPauses()
+	httpCapture.SetPauses(pauses)
+	wsCapture.SetPauses(pauses)
+
 	cdpClient := cdp.NewClient(cfg, httpCapture, wsCapture, tabRegistry)
 	if err := cdpClient.Connect(ctx); err != nil {
 		slog.Error("Failed to connect to browser", "error", err)
@@ -87,6 +144,35 @@ func main() {
 		}
 	}()
 
+	go cdpClient.Supervise(ctx)
+
+	if cfg.APIEnabled {
+		live := &liveResearcher{client: cdpClient, http: httpCapture, ws: wsCapture, writers: writerRegistry}
+		srv := &http.Server{Addr: cfg.APIAddr, Handler: researchapi.NewServer(live, pauses, cfg.DataDir, version)}
+		go func() {
+			slog.Info("Researcher API listening", "addr", cfg.APIAddr, "docs", "http://"+cfg.APIAddr+"/docs")
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Researcher API failed; capture continues without it", "error", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("Researcher API shutdown failed", "error", err)
+			}
+		}()
+	}
+
+	if cfg.RetentionIntervalMin > 0 {
+		policy := retentionPolicy(cfg.DiskQuotaMB, cfg.MaxAgeHTTPDays, cfg.MaxAgeWSDays, cfg.MaxAgeResourcesDays, cfg.Compression)
+		go storage.NewRetention(cfg.DataDir, policy).Run(ctx, time.Duration(cfg.RetentionIntervalMin)*time.Minute)
+		slog.Info("Retention enabled",
+			"interval_min", cfg.RetentionIntervalMin,
+			"quota_mb", cfg.DiskQuotaMB,
+			"compression", cfg.Compression)
+	}
+
 	slog.Info("Researcher running", "tabs", cdpClient.GetTabCount(), "output_dir", cfg.DataDir)
 	slog.Info("Press Ctrl+C to stop")
 
@@ -95,3 +181,28 @@ func main() {
 	cancel()
 	slog.Info("Researcher stopped")
 }
+
+func runSubcommand(name string, args []string) int {
+	run, ok := subcommands[name]
+	if !ok {
+		names := make([]string, 0, len(subcommands))
+		for n := range subcommands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "researcher: unknown command %q (commands: %s)\n", name, strings.Join(names, ", "))
+		return 2
+	}
+	if err := run(args); err != nil {
+		var code exitCode
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.As(err, &code):
+			return int(code)
+		}
+		fmt.Fprintf(os.Stderr, "researcher %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
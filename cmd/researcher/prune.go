@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/config"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+)
+
+// retentionPolicy builds a retention policy from the megabyte and day
+// settings; zero disables the quota or age limit.
+func retentionPolicy(quotaMB, httpDays, wsDays, resourceDays int, compression string) storage.RetentionPolicy {
+	day := 24 * time.Hour
+	return storage.RetentionPolicy{
+		MaxAge: map[string]time.Duration{
+			"http":                     time.Duration(httpDays) * day,
+			"websocket":                time.Duration(wsDays) * day,
+			storage.RetentionResources: time.Duration(resourceDays) * day,
+		},
+		QuotaBytes:  int64(quotaMB) * 1024 * 1024,
+		Compression: compression,
+	}
+}
+
+// runPrune implements "researcher prune": one retention sweep of the data
+// directory, using the RESEARCHER_* retention settings unless overridden.
+func runPrune(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "prune [flags]")
+	dataDir := fs.String("data-dir", cfg.DataDir, "capture directory (RESEARCHER_DATA_DIR)")
+	quotaMB := fs.Int("quota-mb", cfg.DiskQuotaMB, "remove the oldest closed days above this size, 0 for no quota (RESEARCHER_DISK_QUOTA_MB)")
+	httpDays := fs.Int("http-days", cfg.MaxAgeHTTPDays, "keep HTTP captures this many days, 0 for ever (RESEARCHER_MAX_AGE_HTTP_DAYS)")
+	wsDays := fs.Int("ws-days", cfg.MaxAgeWSDays, "keep WebSocket captures this many days, 0 for ever (RESEARCHER_MAX_AGE_WS_DAYS)")
+	resourceDays := fs.Int("resources-days", cfg.MaxAgeResourcesDays, "keep resource indexes this many days, 0 for ever (RESEARCHER_MAX_AGE_RESOURCES_DAYS)")
+	compression := fs.String("compression", cfg.Compression, "compress closed days with gzip, zstd or none (RESEARCHER_COMPRESSION)")
+	dryRun := fs.Bool("dry-run", false, "report what would be removed without changing anything")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !storage.ValidCompression(*compression) {
+		return fmt.Errorf("-compression must be gzip, zstd or none")
+	}
+
+	policy := retentionPolicy(*quotaMB, *httpDays, *wsDays, *resourceDays, *compression)
+	rep, err := storage.NewRetention(*dataDir, policy).Sweep(*dryRun)
+	if err != nil {
+		return err
+	}
+	return writeOutput("-", func(w io.Writer) error {
+		if *asJSON {
+			return writeJSON(w, rep)
+		}
+		verb := "removed"
+		if *dryRun {
+			verb = "would remove"
+		}
+		for _, p := range rep.Removed {
+			fmt.Fprintf(w, "%s %s\n", verb, p)
+		}
+		fmt.Fprintf(w, "%d removed, %d files compressed, %d blobs removed, %d bytes freed, %d bytes left\n",
+			len(rep.Removed), rep.Compressed, rep.BlobsRemoved, rep.FreedBytes, rep.TotalBytes)
+		if rep.OverQuota {
+			fmt.Fprintf(os.Stderr, "warning: still over the %d MB quota; only open days are left\n", *quotaMB)
+		}
+		return nil
+	})
+}
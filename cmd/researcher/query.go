@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/query"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+)
+
+// errStop ends a scan early once -limit records have been printed.
+var errStop = errors.New("stop")
+
+// runQuery implements "researcher query": it prints the captures matching
+// the filters as JSONL, or with -group a table of counts and bytes.
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "query [flags]")
+	var sel captureFlags
+	sel.register(fs)
+	kind := fs.String("kind", "", "only http or websocket records")
+	urlRe := fs.String("url", "", "URL regular expression")
+	methods := fs.String("method", "", "HTTP methods, comma-separated")
+	statuses := fs.String("status", "", "HTTP statuses or classes, comma-separated (e.g. 200,4xx)")
+	types := fs.String("type", "", "WebSocket message types (the \"m\" field), comma-separated; ~h~ for heartbeats")
+	direction := fs.String("direction", "", "WebSocket direction: incoming or outgoing")
+	group := fs.String("group", "", "aggregate by endpoint or type instead of printing records")
+	asJSON := fs.Bool("json", false, "print aggregates as JSON")
+	limit := fs.Int("limit", 0, "stop after this many records (0 for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := sel.selection()
+	if err != nil {
+		return err
+	}
+
+	f := query.Filter{
+		From:      s.from,
+		To:        s.to,
+		Methods:   query.SplitList(*methods),
+		Types:     query.SplitList(*types),
+		Direction: *direction,
+		Tab:       s.tab,
+	}
+	if *urlRe != "" {
+		if f.URL, err = regexp.Compile(*urlRe); err != nil {
+			return fmt.Errorf("-url: %w", err)
+		}
+	}
+	if f.Statuses, err = query.ParseStatuses(*statuses); err != nil {
+		return fmt.Errorf("-status: %w", err)
+	}
+	if *direction != "" && *direction != "incoming" && *direction != "outgoing" {
+		return fmt.Errorf("-direction must be incoming or outgoing")
+	}
+	dataType := f.DataType()
+	switch {
+	case *kind == "":
+	case *kind != "http" && *kind != "websocket":
+		return fmt.Errorf("-kind must be http or websocket")
+	case dataType != "" && dataType != *kind:
+		return fmt.Errorf("-kind %s conflicts with %s-only filters", *kind, dataType)
+	default:
+		dataType = *kind
+	}
+
+	var agg *query.Aggregator
+	if *group != "" {
+		if agg, err = query.NewAggregator(*group, f); err != nil {
+			return fmt.Errorf("-group: %w", err)
+		}
+	}
+	files, err := s.files(dataType)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	printed := 0
+	for _, file := range files {
+		err := storage.ReadJSONL(file.Path, func(line []byte) error {
+			r, err := query.Parse(file.DataType, line)
+			if err != nil || !f.Match(r) {
+				return nil
+			}
+			if agg != nil {
+				agg.Add(r)
+				return nil
+			}
+			out.Write(r.Raw)
+			out.WriteByte('\n')
+			printed++
+			if *limit > 0 && printed >= *limit {
+				return errStop
+			}
+			return nil
+		})
+		if errors.Is(err, errStop) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Path, err)
+		}
+	}
+	if agg == nil {
+		return nil
+	}
+
+	groups := agg.Groups()
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "COUNT\tBYTES\tFIRST\tLAST\t\tKEY")
+	for _, g := range groups {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t\t%s\n", g.Count, g.Bytes,
+			g.FirstSeen.Format(time.DateTime), g.LastSeen.Format(time.DateTime), g.Key)
+	}
+	return tw.Flush()
+}
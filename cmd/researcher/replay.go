@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/replay"
+)
+
+// runReplay implements "researcher replay": it serves the selected
+// WebSocket captures back as a mock TradingView data server until
+// interrupted.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "replay [flags]")
+	var sel captureFlags
+	sel.register(fs)
+	addr := fs.String("addr", "127.0.0.1:8190", "listen address")
+	speed := fs.Float64("speed", 1, "playback speed factor (2 = twice as fast, 0 = no delays)")
+	certFile := fs.String("tls-cert", "", "serve wss:// with this certificate (with -tls-key)")
+	keyFile := fs.String("tls-key", "", "private key for -tls-cert")
+	list := fs.Bool("list", false, "list the recorded sessions and the requests they answer, then exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *speed < 0 {
+		return fmt.Errorf("-speed must not be negative")
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		return fmt.Errorf("-tls-cert and -tls-key must be used together")
+	}
+	s, err := sel.selection()
+	if err != nil {
+		return err
+	}
+	b := replay.NewBuilder()
+	if _, err := s.scan("websocket", b); err != nil {
+		return err
+	}
+	sessions := b.Sessions()
+	if len(sessions) == 0 {
+		return fmt.Errorf("no WebSocket sessions with frames in %s for this selection", s.dataDir)
+	}
+
+	if *list {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SESSION\tSTART\tFRAMES\tURL\tTOPICS")
+		for _, sess := range sessions {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", sess.ID, sess.Start.Format(time.DateTime), sess.Frames(),
+				sess.URL, strings.Join(sess.Topics(), " "))
+		}
+		return tw.Flush()
+	}
+
+	scheme := "ws"
+	if *certFile != "" {
+		scheme = "wss"
+	}
+	srv := &http.Server{Addr: *addr, Handler: replay.NewServer(sessions, *speed)}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 1)
+	go func() {
+		if *certFile != "" {
+			errCh <- srv.ListenAndServeTLS(*certFile, *keyFile)
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+	slog.Info("Replay server listening", "url", scheme+"://"+*addr+"/socket.io/websocket",
+		"sessions", len(sessions), "speed", *speed)
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+)
+
+// resourceVersion is one distinct content seen at a URL.
+type resourceVersion struct {
+	URL       string    `json:"url"`
+	Type      string    `json:"type"`
+	SHA256    string    `json:"sha256"`
+	Size      int       `json:"size"`
+	Truncated bool      `json:"truncated,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Tabs      []string  `json:"tabs"`
+	Blob      string    `json:"blob"`
+}
+
+// runResources implements "researcher resources": it lists the versions of
+// the captured static resources, compares two days (-base/-head) or prints
+// a stored blob (-cat).
+func runResources(args []string) error {
+	fs := flag.NewFlagSet("resources", flag.ContinueOnError)
+	fs.Usage = usageFor(fs, "resources [flags] | resources -base <day|from..to> -head <day|from..to> | resources -cat <sha256>")
+	var sel captureFlags
+	sel.register(fs)
+	urlRe := fs.String("url", "", "URL regular expression")
+	kind := fs.String("type", "", "resource type (js, css, img, font, media, docs, manifest, other)")
+	base := fs.String("base", "", "compare: baseline YYYY-MM-DD or FROM..TO")
+	head := fs.String("head", "", "compare: comparison YYYY-MM-DD or FROM..TO")
+	cat := fs.String("cat", "", "write the blob with this SHA-256 (or unique prefix) to -o")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *cat != "" {
+		path, err := findBlob(sel.dataDir, *cat)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*out, func(w io.Writer) error {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		})
+	}
+
+	match := func(e storage.ResourceEntry) bool { return *kind == "" || e.Type == *kind }
+	if *urlRe != "" {
+		re, err := regexp.Compile(*urlRe)
+		if err != nil {
+			return fmt.Errorf("-url: %w", err)
+		}
+		byType := match
+		match = func(e storage.ResourceEntry) bool { return byType(e) && re.MatchString(e.URL) }
+	}
+
+	if *base != "" || *head != "" {
+		if *base == "" || *head == "" {
+			return fmt.Errorf("-base and -head must be used together")
+		}
+		if sel.from != "" || sel.to != "" {
+			return fmt.Errorf("use -base and -head instead of -from and -to")
+		}
+		baseEntries, err := loadResources(sel, *base, match)
+		if err != nil {
+			return fmt.Errorf("-base: %w", err)
+		}
+		headEntries, err := loadResources(sel, *head, match)
+		if err != nil {
+			return fmt.Errorf("-head: %w", err)
+		}
+		changes := storage.CompareResources(baseEntries, headEntries)
+		return writeOutput(*out, func(w io.Writer) error {
+			if *asJSON {
+				return writeJSON(w, changes)
+			}
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "STATUS\tBASE\tHEAD\tNAME")
+			for _, c := range changes {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Status, shortHashes(c.Base), shortHashes(c.Head), c.Name)
+			}
+			return tw.Flush()
+		})
+	}
+
+	entries, err := loadResources(sel, "", match)
+	if err != nil {
+		return err
+	}
+	versions := groupVersions(sel.dataDir, entries)
+	return writeOutput(*out, func(w io.Writer) error {
+		if *asJSON {
+			return writeJSON(w, versions)
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "FIRST\tLAST\tSIZE\tTABS\tSHA256\tURL")
+		for _, v := range versions {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\n", v.FirstSeen.Format(time.DateTime),
+				v.LastSeen.Format(time.DateTime), v.Size, len(v.Tabs), shortHashes([]string{v.SHA256}), v.URL)
+		}
+		return tw.Flush()
+	})
+}
+
+// loadResources reads the selected index entries. A non-empty spec (a day
+// or FROM..TO) replaces the -from/-to selection.
+func loadResources(sel captureFlags, spec string, match func(storage.ResourceEntry) bool) ([]storage.ResourceEntry, error) {
+	if spec != "" {
+		sel.from, sel.to = spec, spec
+		if from, to, ok := strings.Cut(spec, ".."); ok {
+			sel.from, sel.to = from, to
+		}
+	}
+	s, err := sel.selection()
+	if err != nil {
+		return nil, err
+	}
+	f := storage.ScanFilter{From: s.from, PathSegment: s.path}
+	if !s.to.IsZero() {
+		f.To = s.to.Add(-time.Nanosecond)
+	}
+	var entries []storage.ResourceEntry
+	err = storage.ReadResourceIndex(s.dataDir, f, func(e storage.ResourceEntry) error {
+		if s.match(e.Timestamp, e.TabID) && match(e) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// groupVersions merges entries by URL and content, sorted by URL then first
+// sighting.
+func groupVersions(dataDir string, entries []storage.ResourceEntry) []*resourceVersion {
+	byKey := make(map[string]*resourceVersion)
+	var out []*resourceVersion
+	for _, e := range entries {
+		key := e.URL + "|" + e.SHA256
+		v := byKey[key]
+		if v == nil {
+			v = &resourceVersion{URL: e.URL, Type: e.Type, SHA256: e.SHA256, Size: e.Size, Truncated: e.Truncated,
+				FirstSeen: e.Timestamp, LastSeen: e.Timestamp, Blob: storage.BlobPath(dataDir, e.SHA256)}
+			byKey[key] = v
+			out = append(out, v)
+		}
+		if e.Timestamp.Before(v.FirstSeen) {
+			v.FirstSeen = e.Timestamp
+		}
+		if e.Timestamp.After(v.LastSeen) {
+			v.LastSeen = e.Timestamp
+		}
+		if !slices.Contains(v.Tabs, e.TabID) {
+			v.Tabs = append(v.Tabs, e.TabID)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].URL != out[j].URL {
+			return out[i].URL < out[j].URL
+		}
+		return out[i].FirstSeen.Before(out[j].FirstSeen)
+	})
+	return out
+}
+
+// findBlob resolves a full or abbreviated SHA-256 to a blob path.
+func findBlob(dataDir, sha string) (string, error) {
+	sha = strings.ToLower(sha)
+	if len(sha) < 4 || strings.Trim(sha, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("-cat: need at least 4 hex digits")
+	}
+	candidates, err := filepath.Glob(storage.BlobPath(dataDir, sha) + "*")
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, c := range candidates {
+		if !strings.HasSuffix(c, ".tmp") {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("-cat: no blob %s", sha)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("-cat: %s is ambiguous (%d blobs)", sha, len(matches))
+	}
+}
+
+func shortHashes(shas []string) string {
+	if len(shas) == 0 {
+		return "-"
+	}
+	short := make([]string, len(shas))
+	for i, s := range shas {
+		short[i] = s[:min(12, len(s))]
+	}
+	return strings.Join(short, ",")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/capture"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdp"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// liveResearcher adapts the running capture to researchapi.Researcher.
+type liveResearcher struct {
+	client  *cdp.Client
+	http    *capture.HTTPCapture
+	ws      *capture.WebSocketCapture
+	writers *storage.WriterRegistry
+}
+
+func (l *liveResearcher) Connected() bool                    { return l.client.Connected() }
+func (l *liveResearcher) Tabs() []types.TabInfo              { return l.client.Tabs() }
+func (l *liveResearcher) WriterStats() []storage.WriterStats { return l.writers.Stats() }
+func (l *liveResearcher) ActiveWebSockets() int              { return l.ws.GetActiveConnections() }
+
+func (l *liveResearcher) WriteGap(tab types.TabInfo, dataType string, gap types.CaptureGap) {
+	switch dataType {
+	case capture.DataHTTP:
+		l.http.WriteGap(tab, gap)
+	case capture.DataWebSocket:
+		l.ws.WriteGap(tab, gap)
+	}
+}
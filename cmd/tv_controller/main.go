@@ -16,6 +16,7 @@ import (
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/config"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/controller"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/jobs"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/relay"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/snapshot"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -45,6 +46,7 @@ func main() {
 		"log_level", cfg.LogLevel,
 		"log_file", cfg.LogFile,
 		"snapshot_dir", cfg.SnapshotDir,
+		"jobs_dir", cfg.JobsDir,
 		"launch_browser", cfg.LaunchBrowser,
 	)
 
@@ -90,10 +92,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	svc := controller.NewService(cdpClient, snapStore)
+	jobStore, err := jobs.NewStore(cfg.JobsDir)
+	if err != nil {
+		slog.Error("failed to create job store", "dir", cfg.JobsDir, "error", err)
+		if launcher != nil && launcher.Running() {
+			launcher.Stop()
+		}
+		os.Exit(1)
+	}
+
+	svc := controller.NewService(cdpClient, snapStore, jobStore)
 
 	var serverOpts []api.ServerOption
 	var wsRelay *relay.Relay
+	var barTracker *relay.BarTracker
+	var quoteAgg *relay.QuoteAggregator
+	var alertTracker *relay.AlertTracker
+	var webhooks *relay.WebhookDispatcher
+	var recorder *relay.Recorder
 	if cfg.RelayEnabled {
 		relayCfg, err := relay.LoadConfig(cfg.RelayConfigPath)
 		if err != nil {
@@ -106,7 +122,43 @@ func main() {
 			slog.Error("failed to start relay", "error", err)
 			os.Exit(1)
 		}
-		serverOpts = append(serverOpts, api.WithRelayHandler(relay.SSEHandler(broker)))
+		svc.SetLoadWatcher(wsRelay)
+		barTracker = relay.NewBarTracker(broker, wsRelay.Series())
+		barTracker.Start()
+		quoteAgg = relay.NewQuoteAggregator(broker)
+		quoteAgg.Start()
+		alertTracker = relay.NewAlertTracker(broker)
+		alertTracker.Start()
+		if len(relayCfg.Webhooks) > 0 {
+			webhooks = relay.NewWebhookDispatcher(relayCfg.Webhooks, relayCfg.WebhookDeadLetter, broker)
+			webhooks.Start()
+		}
+		if rc := relayCfg.Recorder; rc.Dir != "" {
+			var bars, quotes *relay.Broker
+			if rc.Records("bars") {
+				bars = barTracker.Events()
+			}
+			if rc.Records("quotes") {
+				quotes = quoteAgg.Events()
+			}
+			recorder = relay.NewRecorder(rc.Dir, bars, quotes)
+			recorder.Start()
+			serverOpts = append(serverOpts, api.WithHistoryBarsHandler(relay.HistoryBarsHandler(recorder)))
+			slog.Info("relay recorder enabled", "dir", rc.Dir)
+		}
+		serverOpts = append(serverOpts,
+			api.WithRelayHandler(relay.SSEHandler(broker)),
+			api.WithRelayStatsHandler(relay.StatsHandler(broker)),
+			api.WithBarStreamHandler(relay.BarStreamHandler(barTracker)),
+			api.WithQuoteHandlers(relay.QuotesHandler(quoteAgg), relay.QuoteStreamHandler(quoteAgg)),
+			api.WithAlertEventsHandler(relay.AlertEventsHandler(alertTracker)),
+			api.WithRelayWebSocket(relay.WebSocketHandler(
+				[]*relay.Broker{broker, barTracker.Events(), quoteAgg.Events(), alertTracker.Events()},
+				wsRelay.Series(),
+				api.RelayOperations(svc),
+				relayCfg.WSAllowedOrigins,
+			)),
+		)
 		slog.Info("ws relay enabled", "config", cfg.RelayConfigPath, "feeds", len(relayCfg.Feeds))
 	}
 
@@ -139,10 +191,26 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		slog.Error("tv_controller shutdown failed", "error", err)
 	}
+	svc.Close()
 
 	if wsRelay != nil {
 		wsRelay.Stop()
 	}
+	if barTracker != nil {
+		barTracker.Stop()
+	}
+	if quoteAgg != nil {
+		quoteAgg.Stop()
+	}
+	if alertTracker != nil {
+		alertTracker.Stop()
+	}
+	if webhooks != nil {
+		webhooks.Stop()
+	}
+	if recorder != nil {
+		recorder.Stop()
+	}
 
 	if launcher != nil && launcher.Running() {
 		launcher.Stop()
@@ -275,6 +275,11 @@ const relayDocsHTML = `<!doctype html>
       <li><a href="#endpoint">Endpoint</a></li>
       <li><a href="#feeds">Available Feeds</a></li>
       <li><a href="#sse-format">SSE Event Format</a></li>
+      <li><a href="#bars">Bar Stream</a></li>
+      <li><a href="#quotes">Quotes</a></li>
+      <li><a href="#alerts">Alert Events</a></li>
+      <li><a href="#websocket">WebSocket</a></li>
+      <li><a href="#history">History</a></li>
       <li><a href="#examples">Examples</a></li>
       <li><a href="#config">Relay Config File</a></li>
       <li><a href="#notes">Notes</a></li>
@@ -335,6 +340,46 @@ CONTROLLER_RELAY_CONFIG=./config/relay.yaml</code></pre>
             all feeds. Example: <code>?feeds=private_feed,chart_data</code>
           </td>
         </tr>
+        <tr>
+          <td><code>decode</code></td>
+          <td>boolean</td>
+          <td>No</td>
+          <td>
+            When <code>true</code>, each event's data is a typed JSON object
+            (<code>{"feed","type","direction","session","data"}</code>) instead of the raw message.
+            See <a href="#sse-format">SSE Event Format</a>.
+          </td>
+        </tr>
+        <tr>
+          <td><code>directions</code></td>
+          <td>string</td>
+          <td>No</td>
+          <td>
+            Comma-separated frame directions to receive: <code>received</code>
+            (server to browser) and/or <code>sent</code> (browser to server). Omit to
+            receive every direction the feed relays.
+          </td>
+        </tr>
+        <tr>
+          <td><code>overflow</code></td>
+          <td>string</td>
+          <td>No</td>
+          <td>
+            What happens when this client falls more than 256 events behind:
+            <code>drop_newest</code> (default) discards new events,
+            <code>drop_oldest</code> discards the oldest queued events, and
+            <code>disconnect</code> ends the stream. See <a href="#overflow">Overflow</a>.
+          </td>
+        </tr>
+        <tr>
+          <td><code>last_event_id</code></td>
+          <td>integer</td>
+          <td>No</td>
+          <td>
+            Resume point for clients that cannot send the <code>Last-Event-ID</code>
+            header. See <a href="#sse-format">SSE Event Format</a>.
+          </td>
+        </tr>
       </tbody>
     </table>
 
@@ -365,13 +410,19 @@ CONTROLLER_RELAY_CONFIG=./config/relay.yaml</code></pre>
         &nbsp;·&nbsp;
         <span>Message types:</span>
         <span class="tag">alert_fired</span>
+        <span class="tag">event</span>
+        <span class="tag">fires_updated</span>
         <span class="tag">alerts_created</span>
         <span class="tag">alerts_updated</span>
-        <span class="tag">fires_updated</span>
+        <span class="tag">alert_running</span>
+        <span class="tag">alerts_deleted</span>
+        <span class="tag">alert_deleted</span>
       </div>
       <p>
         TradingView's authenticated private WebSocket. Carries real-time alert events
-        for the logged-in account.
+        for the logged-in account. Messages arrive wrapped in a
+        <code>{"id":N,"text":{"content":...}}</code> envelope whose content may itself be a
+        JSON string; the relay unwraps it to find the message type.
       </p>
     </div>
 
@@ -395,21 +446,28 @@ CONTROLLER_RELAY_CONFIG=./config/relay.yaml</code></pre>
         &nbsp;·&nbsp;
         <span>Message types:</span>
         <span class="tag">du</span>
+        <span class="tag">timescale_update</span>
         <span class="tag">qsd</span>
       </div>
       <p>
         TradingView's chart data socket (socket.io). Filtered to data-update
-        (<code>du</code>) and quote-series-data (<code>qsd</code>) messages.
+        (<code>du</code>), history-load (<code>timescale_update</code>) and
+        quote-series-data (<code>qsd</code>) messages.
       </p>
     </div>
 
     <!-- SSE FORMAT -->
     <h2 id="sse-format">SSE Event Format</h2>
-    <p>Each event follows the standard SSE format. The <code>event</code> field is the feed name.</p>
+    <p>
+      Each event follows the standard SSE format. The <code>event</code> field is the feed
+      name and <code>id</code> is a monotonically increasing event number.
+    </p>
     <div class="sse-block">
+      <span class="sse-key">id:</span> <span class="sse-value">1042</span><br>
       <span class="sse-key">event:</span> <span class="sse-value">private_feed</span><br>
       <span class="sse-key">data:</span> <span class="sse-value">{"m":"alert_fired","alert_id":3999574105,...}</span><br>
       <br>
+      <span class="sse-key">id:</span> <span class="sse-value">1043</span><br>
       <span class="sse-key">event:</span> <span class="sse-value">chart_data</span><br>
       <span class="sse-key">data:</span> <span class="sse-value">{"m":"du","p":["cs_...","sds_...",[...]]}</span><br>
       <br>
@@ -419,8 +477,223 @@ CONTROLLER_RELAY_CONFIG=./config/relay.yaml</code></pre>
       an <code>"m"</code> field identifying the message type, and a <code>"p"</code>
       array with parameters.
     </p>
+    <p>
+      The chart data socket packs several <code>~m~&lt;len&gt;~m~</code> framed messages
+      into a single WebSocket frame. The relay splits every frame and publishes each
+      message as its own SSE event, with the framing removed. Heartbeats
+      (<code>~h~</code>) are still relayed on feeds without message-type or payload
+      filters, so existing consumers keep seeing them.
+    </p>
+
+    <h3>Resuming after a disconnect</h3>
+    <p>
+      The broker keeps the last 1024 events of each feed. A client that reconnects with
+      the <code>Last-Event-ID</code> header (sent automatically by <code>EventSource</code>)
+      or <code>?last_event_id=</code> first receives the buffered events after that ID, then
+      the live stream. Events older than the buffer cannot be replayed: when some events
+      after the client's ID were already evicted, the replay is preceded by a synthetic
+      <code>gap</code> event, so the client knows to reload its state. The bar and alert
+      streams resume the same way; the quote stream resends its snapshot instead.
+    </p>
+    <div class="sse-block">
+      <span class="sse-key">event:</span> <span class="sse-value">gap</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"last_event_id":1042,"first_event_id":2301}</span><br>
+      <br>
+    </div>
+    <p>
+      Every stream sends a <code>: keepalive</code> comment every 15 seconds so proxies
+      do not close idle connections. SSE clients ignore comments.
+    </p>
+
+    <h3 id="overflow">Overflow</h3>
+    <p>
+      When events were dropped for a client, the next event is preceded by a synthetic
+      <code>overflow</code> event. A stream closed by the <code>disconnect</code> policy
+      ends with one. Reconnect with <code>last_event_id</code> as <code>Last-Event-ID</code>
+      to replay whatever is still buffered.
+    </p>
+    <div class="sse-block">
+      <span class="sse-key">event:</span> <span class="sse-value">overflow</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"dropped":12,"total_dropped":40,"last_event_id":1042}</span><br>
+      <br>
+    </div>
+
+    <h3 id="stats">Stats</h3>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/relay/stats</span>
+    </div>
+    <p>
+      JSON counters for the relay broker. Lists every subscriber, including the internal
+      bar, quote, alert and webhook consumers, with its overflow policy and delivered,
+      dropped and queued event counts.
+    </p>
+    <pre><code>{"clients":5,"published":184213,"subscribers":[
+  {"id":1,"label":"bar_tracker","policy":"drop_newest","connected_at":"2026-02-23T15:00:00Z","delivered":184213,"dropped":0,"queued":0},
+  {"id":6,"label":"/api/v1/relay/events 127.0.0.1:51422","policy":"drop_oldest","connected_at":"2026-02-23T15:02:11Z","delivered":90112,"dropped":40,"queued":12}]}</code></pre>
+
+    <h3>Typed events (<code>?decode=true</code>)</h3>
+    <p>
+      With <code>?decode=true</code> the relay decodes known message types into typed
+      objects. <code>du</code> and <code>timescale_update</code> become a list of series
+      with named OHLCV bars, <code>qsd</code> becomes a symbol quote with its sparse
+      values, and <code>symbol_resolved</code>, <code>series_*</code>,
+      <code>study_*</code> and <code>quote_completed</code> get named session and ID
+      fields. Other message types pass their raw <code>"p"</code> through as <code>data</code>.
+    </p>
+    <div class="sse-block">
+      <span class="sse-key">event:</span> <span class="sse-value">chart_data</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"feed":"chart_data","type":"du","direction":"received","session":"cs_abc123","data":{"session":"cs_abc123","series":[{"series_id":"sds_1","series_type":"s3","bars":[{"index":301,"time":1771699560,"open":68474.52,"high":68483.99,"low":68474.52,"close":68483.99,"volume":0.35798}],"bar_close_time":1771699620,"indexes":"nochange"}]}}</span><br>
+      <br>
+    </div>
+
+    <!-- BARS -->
+    <h2 id="bars">Bar Stream</h2>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/chart/{chart_id}/bars/stream</span>
+    </div>
+    <p>
+      Normalized real-time OHLCV bars for the chart's main series, built from relayed
+      <code>du</code> messages. Each SSE event is named <code>bar</code>. An update for the
+      forming bar has <code>closed: false</code>; the final state of a bar is sent again with
+      <code>closed: true</code> as soon as a newer bar starts or its
+      <code>lbs.bar_close_time</code> passes. The main series is the first one the chart
+      created (seen in its outgoing <code>create_series</code>), or <code>sds_1</code> if the
+      relay started after the chart loaded; compare series are never reported.
+    </p>
+    <div class="sse-block">
+      <span class="sse-key">event:</span> <span class="sse-value">bar</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"chart_id":"abc123","session":"cs_x","series_id":"sds_1","symbol":"COINBASE:BTCUSD","resolution":"1","time":1771699560,"open":68474.52,"high":68483.99,"low":68474.52,"close":68483.99,"volume":0.35798,"bar_index":301,"closed":false}</span><br>
+      <br>
+    </div>
+    <p>
+      The <code>chart_id</code> comes from the chart tab that opened the socket. A feed that
+      carries <code>du</code> messages (the default <code>chart_data</code> feed) must be configured.
+    </p>
+
+    <h2 id="quotes">Quotes</h2>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/quotes</span>
+    </div>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/quotes/stream</span>
+    </div>
+    <p>
+      TradingView's quote sessions (watchlist, details panel, ticker) send <code>qsd</code>
+      messages that only contain the fields that changed. The relay merges them into a full
+      current quote per symbol. <code>/api/v1/quotes</code> returns every known symbol as JSON;
+      <code>/api/v1/quotes/stream</code> first sends the current quote of each matching symbol,
+      then one <code>quote</code> event with the merged state after every update. Both accept
+      <code>?symbols=NASDAQ:AAPL,BINANCE:BTCUSDT</code>.
+    </p>
+    <div class="sse-block">
+      <span class="sse-key">event:</span> <span class="sse-value">quote</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"symbol":"NASDAQ:AAPL","status":"ok","lp":191.02,"ch":1.24,"chp":0.65,"bid":191.01,"ask":191.03,"volume":48211930,"updated_at":"2026-02-23T15:04:05Z","values":{...}}</span><br>
+      <br>
+    </div>
+    <p>
+      <code>values</code> holds every field received so far. Only symbols that an open quote
+      session is subscribed to appear, so add them to the visible watchlist to track them.
+    </p>
+
+    <h2 id="alerts">Alert Events</h2>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/alerts/events</span>
+    </div>
+    <p>
+      Structured alert events decoded from the <code>private_feed</code> feed. Envelope
+      variants, encoded <code>={"symbol":...}</code> symbols, ISO and unix timestamps and the
+      abbreviated field names of the compact <code>event</code> message are normalized.
+      Event names:
+    </p>
+    <ul>
+      <li><code>alert_fired</code> — once per fire, even though TradingView sends both
+        <code>alert_fired</code> and <code>event</code>. Condition and price are filled in from
+        the alert's lifecycle messages when seen.</li>
+      <li><code>fire_updated</code> — from <code>fires_updated</code>, with the webhook HTTP status.</li>
+      <li><code>alert_created</code>, <code>alert_updated</code>, <code>alert_running</code>,
+        <code>alert_deleted</code> — alert lifecycle.</li>
+    </ul>
+    <p>Filter with <code>?types=alert_fired</code> and <code>?symbols=COINBASE:BTCUSD</code>.</p>
+    <div class="sse-block">
+      <span class="sse-key">event:</span> <span class="sse-value">alert_fired</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"source":"alert_fired","fire_id":46723852620,"alert_id":4074311184,"symbol":"COINBASE:BTCUSD","resolution":"1","message":"BTCUSD Crossing 68,521.11","condition":"cross","price":68521.11,"fire_time":"2026-02-21T18:46:44Z","bar_time":"2026-02-21T18:46:00Z"}</span><br>
+      <br>
+      <span class="sse-key">event:</span> <span class="sse-value">alert_created</span><br>
+      <span class="sse-key">data:</span> <span class="sse-value">{"action":"created","alert_id":4074332684,"alert_type":"price","symbol":"COINBASE:BTCUSD","resolution":"1","message":"BTCUSD Crossing 68,580.27","condition":"cross","frequency":"on_first_fire","price":68580.27,"active":false,"time":"2026-02-21T18:54:24Z"}</span><br>
+      <br>
+    </div>
+
+    <h2 id="websocket">WebSocket</h2>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/relay/ws</span>
+    </div>
+    <p>
+      A bidirectional alternative to the SSE endpoints. One connection can change its
+      subscription at runtime and call controller operations. Every message is a JSON text
+      frame. Subscribable feeds are the relay feeds plus the derived <code>bars</code>,
+      <code>quotes</code> and <code>alerts</code> streams; <code>"*"</code> subscribes to all.
+      <code>types</code> and <code>symbols</code> narrow the match and default to everything.
+      Symbols match the derived streams, relayed <code>qsd</code> messages, and relayed
+      <code>du</code> and <code>timescale_update</code> messages whose series show that symbol.
+    </p>
+    <pre><code>→ {"id":"1","action":"subscribe","feeds":["chart_data","quotes"],"types":["qsd","quote"],"symbols":["NASDAQ:AAPL"],"decode":true}
+← {"type":"subscribed","id":"1","filter":{"feeds":["chart_data","quotes"],"types":["qsd","quote"],"symbols":["NASDAQ:AAPL"],"decode":true}}
+← {"type":"event","event_id":1042,"feed":"quotes","event_type":"quote","symbol":"NASDAQ:AAPL","data":{...}}
+
+→ {"id":"2","action":"call","op":"set_symbol","params":{"chart_id":"abc123","symbol":"NASDAQ:MSFT"}}
+← {"type":"result","id":"2","result":{"chart_id":"abc123","current_symbol":"NASDAQ:MSFT"}}
+
+→ {"id":"3","action":"call","op":"add_study","params":{"chart_id":"abc123","name":"Relative Strength Index"}}
+← {"type":"error","id":"3","error":{"code":"CHART_NOT_FOUND","message":"..."}}
+
+→ {"id":"4","action":"unsubscribe","symbols":["NASDAQ:AAPL"]}   // omit all lists to clear
+→ {"id":"5","action":"ping"}</code></pre>
+    <p>
+      Operations: <code>list_charts</code>, <code>get_symbol</code>, <code>set_symbol</code>,
+      <code>get_resolution</code>, <code>set_resolution</code>, <code>list_studies</code>,
+      <code>add_study</code> and <code>remove_study</code>. Params match the REST request
+      fields, with <code>chart_id</code> and optional <code>pane</code> in the params object.
+      Calls run concurrently, so use <code>id</code> to match results to requests. Dropped
+      events are reported with an <code>overflow</code> message.
+    </p>
+    <p>
+      Because calls drive the chart, a browser handshake whose <code>Origin</code> is not the
+      controller's own host is refused with <code>403</code>, so other pages open in the same
+      browser cannot use the socket. Allow a dashboard's origin with
+      <code>ws_allowed_origins</code> in <code>config/relay.yaml</code>
+      (<code>"*"</code> allows any). Clients that send no <code>Origin</code> are accepted.
+    </p>
 
     <!-- EXAMPLES -->
+    <h2 id="history">History</h2>
+    <div class="endpoint">
+      <span class="method">GET</span>
+      <span class="path">/api/v1/history/bars?symbol=&amp;resolution=&amp;from=&amp;to=</span>
+    </div>
+    <p>
+      With a <code>recorder</code> configured (see <a href="#recorder">Recorder</a>), closed bars
+      and quote updates are written to disk while the browser is open, whether or not any
+      client is connected. This endpoint returns the recorded bars of one symbol and
+      resolution, oldest first. <code>from</code> and <code>to</code> accept Unix seconds,
+      RFC 3339 or <code>YYYY-MM-DD</code>; <code>to</code> defaults to now and
+      <code>from</code> to 24 hours earlier.
+    </p>
+    <pre><code>GET /api/v1/history/bars?symbol=COINBASE:BTCUSD&amp;resolution=1&amp;from=2026-02-21
+
+{"symbol":"COINBASE:BTCUSD","resolution":"1","from":1771632000,"to":1771699620,
+ "bars":[{"time":1771699560,"open":68474.52,"high":68483.99,"low":68474.52,"close":68483.99,"volume":0.35798}]}</code></pre>
+    <p>
+      Only bars whose series could be mapped to a symbol and resolution are recorded, so
+      the chart's socket must be open when the series is created (reload the page after
+      starting the controller).
+    </p>
+
     <h2 id="examples">Examples</h2>
 
     <h3>Browser — EventSource</h3>
@@ -480,14 +753,56 @@ for event in sseclient.SSEClient(resp).events():
         </tr>
         <tr>
           <td><code>url_pattern</code></td>
-          <td>Yes</td>
-          <td>Substring matched against the WebSocket URL. The first matching feed wins.</td>
+          <td>No</td>
+          <td>
+            Substring matched against the WebSocket URL. A socket is relayed to every
+            feed that matches it. A message accepted by several feeds is still counted once
+            by the bar, quote and alert streams, the recorder and each webhook.
+          </td>
+        </tr>
+        <tr>
+          <td><code>url_regex</code></td>
+          <td>No</td>
+          <td>
+            Regular expression matched against the URL, instead of or in addition to
+            <code>url_pattern</code>. At least one of the two is required.
+          </td>
         </tr>
         <tr>
           <td><code>message_types</code></td>
           <td>No</td>
           <td>List of <code>"m"</code> field values to accept. Omit to forward all frames.</td>
         </tr>
+        <tr>
+          <td><code>directions</code></td>
+          <td>No</td>
+          <td>
+            Frame directions to relay: <code>received</code> and/or <code>sent</code>.
+            Defaults to <code>received</code>. Sent frames (<code>resolve_symbol</code>,
+            <code>create_series</code>, <code>create_study</code>, ...) are always used to map
+            <code>sds_*</code> series IDs to symbol and resolution, which labels bar events,
+            whether or not they are relayed; the mappings are dropped when the chart's sockets
+            close.
+          </td>
+        </tr>
+        <tr>
+          <td><code>sessions</code></td>
+          <td>No</td>
+          <td>
+            Session ID globs (e.g. <code>qs_multiplexer_watchlist_*</code>). Messages from
+            other sessions are dropped; messages without a session pass.
+          </td>
+        </tr>
+        <tr>
+          <td><code>filters</code></td>
+          <td>No</td>
+          <td>
+            Payload filters, all of which must accept a message. Each has a
+            <code>path</code> into the <code>{"m","p"}</code> message (<code>p[1].n</code>,
+            <code>p[*]</code>), <code>values</code> to match exactly and/or a
+            <code>match</code> regex, and optional <code>message_types</code> it applies to.
+          </td>
+        </tr>
       </tbody>
     </table>
 
@@ -495,21 +810,98 @@ for event in sseclient.SSEClient(resp).events():
 feeds:
   - name: private_feed
     url_pattern: "private_feed"
-    message_types: ["alert_fired", "alerts_created", "alerts_updated", "fires_updated"]
+    message_types: ["alert_fired", "event", "fires_updated", "alerts_created", "alerts_updated", "alert_running", "alerts_deleted", "alert_deleted"]
 
   - name: public
     url_pattern: "public"
 
   - name: chart_data
     url_pattern: "socket.io/websocket"
-    message_types: ["du", "qsd"]</code></pre>
+    message_types: ["du", "timescale_update", "qsd"]
+
+  # Opt in to the chart's own requests (direction "sent") on a separate feed.
+  - name: chart_requests
+    url_pattern: "socket.io/websocket"
+    directions: ["sent"]
+    message_types: ["resolve_symbol", "create_series", "modify_series", "create_study", "quote_add_symbols"]
+
+  # The same chart socket also feeds a watchlist-only quote stream.
+  - name: watchlist_quotes
+    url_regex: "^wss://(data|prodata)\\.tradingview\\.com/socket\\.io/websocket"
+    message_types: ["qsd"]
+    sessions: ["qs_multiplexer_watchlist_*"]
+    filters:
+      - path: "p[1].n"
+        values: ["NASDAQ:AAPL", "COINBASE:BTCUSD"]</code></pre>
+
+    <h3 id="webhooks">Webhooks</h3>
+    <p>
+      The optional <code>webhooks</code> section POSTs matching events to HTTP endpoints,
+      with no SSE client connected. Each URL has its own queue and worker.
+      Network errors, <code>429</code> and <code>5xx</code> responses are retried with
+      exponential backoff, or after the response's <code>Retry-After</code> (capped at one
+      minute). Other <code>4xx</code> responses, exhausted retries, queue overflow and
+      events still pending at shutdown are appended to the <code>webhook_dead_letter</code>
+      JSONL file.
+    </p>
+    <table>
+      <thead>
+        <tr><th>Field</th><th>Default</th><th>Description</th></tr>
+      </thead>
+      <tbody>
+        <tr><td><code>name</code></td><td><em>required</em></td><td>Webhook identifier, sent in the body.</td></tr>
+        <tr><td><code>urls</code></td><td><em>required</em></td><td>One or more <code>http(s)</code> URLs.</td></tr>
+        <tr><td><code>feeds</code></td><td>all</td><td>Feed names to forward.</td></tr>
+        <tr><td><code>message_types</code></td><td>all</td><td><code>"m"</code> values to forward.</td></tr>
+        <tr><td><code>decode</code></td><td><code>false</code></td><td>Send typed payloads, as with <code>?decode=true</code>.</td></tr>
+        <tr><td><code>secret</code></td><td>none</td><td>HMAC-SHA256 key; <code>${VAR}</code> is expanded from the environment.</td></tr>
+        <tr><td><code>max_retries</code></td><td><code>5</code></td><td>Retries after the first attempt.</td></tr>
+        <tr><td><code>backoff</code></td><td><code>1s</code></td><td>Initial retry delay, doubled per retry up to 1m.</td></tr>
+        <tr><td><code>timeout</code></td><td><code>10s</code></td><td>Per-request timeout.</td></tr>
+        <tr><td><code>queue_size</code></td><td><code>256</code></td><td>Pending events per URL.</td></tr>
+      </tbody>
+    </table>
+    <pre><code>webhook_dead_letter: "logs/relay_webhook_dead_letter.jsonl"
+webhooks:
+  - name: alert_fires
+    urls: ["https://hooks.example.internal/tradingview"]
+    feeds: ["private_feed"]
+    message_types: ["alert_fired"]
+    secret: "${RELAY_WEBHOOK_SECRET}"</code></pre>
+    <p>Request body and headers:</p>
+    <pre><code>POST /tradingview
+Content-Type: application/json
+X-Relay-Delivery: 1771699604123456789-42
+X-Relay-Feed: private_feed
+X-Relay-Event: alert_fired
+X-Relay-Signature: sha256=5d41402abc4b2a76b9719d911017c592...
+
+{"delivery_id":"1771699604123456789-42","webhook":"alert_fires","feed":"private_feed",
+ "type":"alert_fired","time":"2026-02-21T18:46:44Z","payload":{...}}</code></pre>
+    <p>
+      Verify the signature by computing HMAC-SHA256 of the raw request body with the
+      shared secret and comparing it to the hex digest after <code>sha256=</code>.
+    </p>
+
+    <h3 id="recorder">Recorder</h3>
+    <p>
+      The optional <code>recorder</code> section persists closed bars and quote updates to
+      date-partitioned JSONL files, queried with <a href="#history">History</a>.
+    </p>
+    <pre><code>recorder:
+  dir: "data/relay"
+  streams: ["bars", "quotes"]   # default both
+
+# data/relay/2026-02-21/bars/COINBASE:BTCUSD/1.jsonl
+# data/relay/2026-02-21/quotes/COINBASE:BTCUSD.jsonl</code></pre>
 
     <!-- NOTES -->
     <h2 id="notes">Notes</h2>
     <ul>
       <li>
         <strong>Buffer &amp; back-pressure:</strong> each subscriber has a 256-event in-memory buffer.
-        Slow clients will have events silently dropped — the broker is non-blocking.
+        The broker is non-blocking; a slow client is handled by its <code>?overflow=</code>
+        policy and told about drops with an <code>overflow</code> event.
       </li>
       <li>
         <strong>Tab attachment:</strong> the relay attaches to browser tabs matching
@@ -518,8 +910,9 @@ feeds:
       </li>
       <li>
         <strong>Reconnection:</strong> the browser's built-in <code>EventSource</code>
-        automatically reconnects on disconnect. For other clients, implement reconnect
-        with exponential backoff.
+        automatically reconnects on disconnect and resumes from the last event ID. For
+        other clients, implement reconnect with exponential backoff and send
+        <code>Last-Event-ID</code>.
       </li>
       <li>
         <strong>Authentication:</strong> the relay endpoint has no authentication. Bind
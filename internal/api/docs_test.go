@@ -4,10 +4,13 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/export"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/jobs"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/snapshot"
 )
 
@@ -492,6 +495,39 @@ func (s *stubService) ProbeDataWindow(ctx context.Context, chartID string, pane
 func (s *stubService) ExportChartData(ctx context.Context, chartID string, pane int) (cdpcontrol.ChartExportResult, error) {
 	return cdpcontrol.ChartExportResult{}, nil
 }
+func (s *stubService) StartBackfill(ctx context.Context, chartID string, pane int, from int64, maxBars int) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) ListBackfills(ctx context.Context, chartID string) ([]jobs.Job, error) {
+	return []jobs.Job{}, nil
+}
+func (s *stubService) GetBackfill(ctx context.Context, chartID, jobID string) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) CancelBackfill(ctx context.Context, chartID, jobID string) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) BackfillResult(ctx context.Context, chartID, jobID string) (cdpcontrol.ChartExportResult, error) {
+	return cdpcontrol.ChartExportResult{}, nil
+}
+func (s *stubService) StartBatchExport(ctx context.Context, req export.BatchRequest) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) ListBatchExports(ctx context.Context) ([]jobs.Job, error) {
+	return []jobs.Job{}, nil
+}
+func (s *stubService) GetBatchExport(ctx context.Context, jobID string) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) CancelBatchExport(ctx context.Context, jobID string) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) ResumeBatchExport(ctx context.Context, jobID string) (jobs.Job, error) {
+	return jobs.Job{}, nil
+}
+func (s *stubService) OpenBatchExportFile(ctx context.Context, jobID, name string) (*os.File, export.Format, error) {
+	return nil, "", nil
+}
 
 type studyPathInputRecording struct {
 	chartID string
@@ -616,3 +652,157 @@ func TestNewServerRegistersAllDomainRoutes(t *testing.T) {
 		}
 	}
 }
+
+type exportService struct {
+	*stubService
+	result cdpcontrol.ChartExportResult
+}
+
+func (s *exportService) ExportChartData(ctx context.Context, chartID string, pane int) (cdpcontrol.ChartExportResult, error) {
+	return s.result, nil
+}
+
+func TestExportChartDataNegotiatesFormat(t *testing.T) {
+	svc := &exportService{stubService: &stubService{}, result: cdpcontrol.ChartExportResult{
+		Symbol:     "NASDAQ:AAPL",
+		Resolution: "1D",
+		Columns: []cdpcontrol.ExportSchemaColumn{
+			{Type: "time"},
+			{Type: "value", SourceType: "series", PlotTitle: "Close"},
+			{Type: "value", SourceType: "study", SourceID: "st-ema", SourceTitle: "EMA", PlotTitle: "EMA"},
+		},
+		Studies: []cdpcontrol.ExportStudy{{SourceID: "st-ema", EntityID: "st-ema", Name: "Moving Average Exponential"}},
+		Bars:    [][]any{{1771632000.0, 191.02, nil}},
+	}}
+	h := NewServer(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export?tz=UTC", nil)
+	req.Header.Set("Accept", "text/csv")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK || resp.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("csv status = %d, content-type = %q", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	if want := "time,close,EMA\n2026-02-21T00:00:00Z,191.02,\n"; resp.Body.String() != want {
+		t.Fatalf("csv body = %q, want %q", resp.Body.String(), want)
+	}
+	if cd := resp.Header().Get("Content-Disposition"); !strings.Contains(cd, "NASDAQ_AAPL_1D.csv") {
+		t.Fatalf("content-disposition = %q", cd)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export", nil))
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"time_col_idx"`) {
+		t.Fatalf("default json = %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export?format=csv&studies=st-ema", nil))
+	if want := "time,close,EMA\n2026-02-21T00:00:00Z,191.02,\n"; resp.Code != http.StatusOK || resp.Body.String() != want {
+		t.Fatalf("studies=st-ema: %d %q", resp.Code, resp.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export", nil)
+	req.Header.Set("Accept", "text/csv;q=0, application/x-ndjson")
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK || resp.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("q=0 csv: status = %d, content-type = %q", resp.Code, resp.Header().Get("Content-Type"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export", nil)
+	req.Header.Set("Accept", "text/html")
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusNotAcceptable {
+		t.Fatalf("text/html: status = %d, want 406: %s", resp.Code, resp.Body.String())
+	}
+
+	for _, q := range []string{"format=xlsx", "tz=Mars/Olympus", "studies=MACD"} {
+		resp = httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export?"+q, nil))
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", q, resp.Code)
+		}
+	}
+}
+
+type backfillService struct {
+	*stubService
+	from    int64
+	maxBars int
+	pane    int
+}
+
+func (s *backfillService) StartBackfill(ctx context.Context, chartID string, pane int, from int64, maxBars int) (jobs.Job, error) {
+	s.from, s.maxBars, s.pane = from, maxBars, pane
+	return jobs.Job{ID: "job-1", Kind: "chart_backfill", Key: chartID, Status: jobs.StatusRunning}, nil
+}
+
+func (s *backfillService) BackfillResult(ctx context.Context, chartID, jobID string) (cdpcontrol.ChartExportResult, error) {
+	return cdpcontrol.ChartExportResult{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeConflict, Message: "backfill is still running"}
+}
+
+func TestStartBackfillAcceptsDates(t *testing.T) {
+	svc := &backfillService{stubService: &stubService{}}
+	h := NewServer(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chart/chart-1/export/backfill", strings.NewReader(`{"from":"2024-01-02","max_bars":5000,"pane":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusAccepted || !strings.Contains(resp.Body.String(), `"id":"job-1"`) {
+		t.Fatalf("start = %d %s", resp.Code, resp.Body.String())
+	}
+	if svc.from != 1704153600 || svc.maxBars != 5000 || svc.pane != 1 {
+		t.Fatalf("service got from=%d max_bars=%d pane=%d", svc.from, svc.maxBars, svc.pane)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/chart/chart-1/export/backfill", strings.NewReader(`{"from":"last week"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("bad from status = %d, want 400", resp.Code)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/chart/chart-1/export/backfill/job-1/data?format=csv", nil))
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("running data status = %d, want 409", resp.Code)
+	}
+}
+
+type batchExportService struct {
+	*stubService
+	req export.BatchRequest
+}
+
+func (s *batchExportService) StartBatchExport(ctx context.Context, req export.BatchRequest) (jobs.Job, error) {
+	s.req = req
+	return jobs.Job{ID: "job-2", Kind: "batch_export", Status: jobs.StatusRunning}, nil
+}
+
+func TestStartBatchExportDefaultsPane(t *testing.T) {
+	svc := &batchExportService{stubService: &stubService{}}
+	h := NewServer(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/export", strings.NewReader(`{"chart_id":"chart-1","symbols":["AAPL","MSFT"],"study_template":"Momentum","format":"parquet"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("start = %d %s", resp.Code, resp.Body.String())
+	}
+	if svc.req.Pane != -1 || len(svc.req.Symbols) != 2 || svc.req.Format != export.FormatParquet || svc.req.StudyTemplate != "Momentum" {
+		t.Fatalf("service got %+v", svc.req)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/jobs/export", strings.NewReader(`{"chart_id":"chart-1","symbols":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("empty symbols status = %d, want 422", resp.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/export"
+)
+
+// exportResponses documents the negotiated media types of the export
+// endpoint, which returns a StreamResponse and so has no inferred schema.
+func exportResponses(api huma.API) map[string]*huma.Response {
+	schema := api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf(cdpcontrol.ChartExportResult{}), true, "ChartExportResult")
+	return map[string]*huma.Response{
+		"200": {
+			Description: "Chart data in the negotiated format",
+			Content: map[string]*huma.MediaType{
+				"application/json":               {Schema: schema},
+				"text/csv":                       {Schema: &huma.Schema{Type: huma.TypeString}},
+				"application/x-ndjson":           {Schema: &huma.Schema{Type: huma.TypeString}},
+				"application/vnd.apache.parquet": {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+			},
+		},
+	}
+}
+
+// negotiateExport maps export.Negotiate errors to 406 for an Accept header
+// that rules out every format and 400 for a bad ?format=.
+func negotiateExport(format, accept string) (export.Format, error) {
+	f, err := export.Negotiate(format, accept)
+	if errors.Is(err, export.ErrNotAcceptable) {
+		return "", huma.Error406NotAcceptable(err.Error())
+	}
+	if err != nil {
+		return "", huma.Error400BadRequest(err.Error())
+	}
+	return f, nil
+}
+
+func loadExportLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	return loc, nil
+}
+
+// parseBackfillFrom parses a backfill target as Unix seconds, RFC 3339 or a
+// YYYY-MM-DD date (UTC). Empty means no target.
+func parseBackfillFrom(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t.Unix(), nil
+	}
+	return 0, fmt.Errorf("invalid from %q (want Unix seconds, RFC 3339 or YYYY-MM-DD)", s)
+}
+
+// streamChartExport writes result as JSON (the ChartExportResult as-is) or
+// row by row in a tabular format, flushing as it goes.
+func streamChartExport(result cdpcontrol.ChartExportResult, format export.Format, loc *time.Location) *huma.StreamResponse {
+	return &huma.StreamResponse{Body: func(ctx huma.Context) {
+		ctx.SetHeader("Content-Type", format.ContentType())
+		if format != export.FormatJSON {
+			ctx.SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName(result.Symbol, result.Resolution, format)))
+		}
+		if err := export.WriteChart(ctx.BodyWriter(), result, format, loc); err != nil {
+			slog.Debug("export response write failed", "error", err)
+		}
+	}}
+}
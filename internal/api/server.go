@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/export"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/jobs"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/snapshot"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -196,6 +199,17 @@ type Service interface {
 	GetOneHotlist(ctx context.Context, exchange, group string) (cdpcontrol.HotlistResult, error)
 	ProbeDataWindow(ctx context.Context, chartID string, pane int) (cdpcontrol.DataWindowProbe, error)
 	ExportChartData(ctx context.Context, chartID string, pane int) (cdpcontrol.ChartExportResult, error)
+	StartBackfill(ctx context.Context, chartID string, pane int, from int64, maxBars int) (jobs.Job, error)
+	ListBackfills(ctx context.Context, chartID string) ([]jobs.Job, error)
+	GetBackfill(ctx context.Context, chartID, jobID string) (jobs.Job, error)
+	CancelBackfill(ctx context.Context, chartID, jobID string) (jobs.Job, error)
+	BackfillResult(ctx context.Context, chartID, jobID string) (cdpcontrol.ChartExportResult, error)
+	StartBatchExport(ctx context.Context, req export.BatchRequest) (jobs.Job, error)
+	ListBatchExports(ctx context.Context) ([]jobs.Job, error)
+	GetBatchExport(ctx context.Context, jobID string) (jobs.Job, error)
+	CancelBatchExport(ctx context.Context, jobID string) (jobs.Job, error)
+	ResumeBatchExport(ctx context.Context, jobID string) (jobs.Job, error)
+	OpenBatchExportFile(ctx context.Context, jobID, name string) (*os.File, export.Format, error)
 }
 
 type chartIDInput struct {
@@ -224,6 +238,55 @@ func WithRelayHandler(h http.Handler) ServerOption {
 	}
 }
 
+// WithRelayWebSocket mounts the bidirectional relay WebSocket at
+// /api/v1/relay/ws.
+func WithRelayWebSocket(h http.Handler) ServerOption {
+	return func(r *chi.Mux) {
+		r.Get("/api/v1/relay/ws", h.ServeHTTP)
+	}
+}
+
+// WithRelayStatsHandler mounts the relay broker statistics at
+// /api/v1/relay/stats.
+func WithRelayStatsHandler(h http.Handler) ServerOption {
+	return func(r *chi.Mux) {
+		r.Get("/api/v1/relay/stats", h.ServeHTTP)
+	}
+}
+
+// WithBarStreamHandler mounts the normalized OHLCV bar SSE stream at
+// /api/v1/chart/{chart_id}/bars/stream.
+func WithBarStreamHandler(h http.Handler) ServerOption {
+	return func(r *chi.Mux) {
+		r.Get("/api/v1/chart/{chart_id}/bars/stream", h.ServeHTTP)
+	}
+}
+
+// WithQuoteHandlers mounts the merged quote snapshot at /api/v1/quotes and
+// its SSE stream at /api/v1/quotes/stream.
+func WithQuoteHandlers(snapshot, stream http.Handler) ServerOption {
+	return func(r *chi.Mux) {
+		r.Get("/api/v1/quotes", snapshot.ServeHTTP)
+		r.Get("/api/v1/quotes/stream", stream.ServeHTTP)
+	}
+}
+
+// WithAlertEventsHandler mounts the normalized alert SSE stream at
+// /api/v1/alerts/events.
+func WithAlertEventsHandler(h http.Handler) ServerOption {
+	return func(r *chi.Mux) {
+		r.Get("/api/v1/alerts/events", h.ServeHTTP)
+	}
+}
+
+// WithHistoryBarsHandler mounts the recorded bar query at
+// /api/v1/history/bars.
+func WithHistoryBarsHandler(h http.Handler) ServerOption {
+	return func(r *chi.Mux) {
+		r.Get("/api/v1/history/bars", h.ServeHTTP)
+	}
+}
+
 func NewServer(svc Service, opts ...ServerOption) http.Handler {
 	router := chi.NewMux()
 	router.Use(middleware.RequestID)
@@ -262,6 +325,7 @@ func NewServer(svc Service, opts ...ServerOption) http.Handler {
 	registerPineHandlers(api, svc)
 	registerLayoutHandlers(api, svc)
 	registerMiscHandlers(api, svc)
+	registerJobHandlers(api, svc)
 
 	return router
 }
@@ -275,8 +339,10 @@ func mapErr(err error) error {
 		switch coded.Code {
 		case cdpcontrol.CodeValidation:
 			return huma.Error400BadRequest(coded.Message)
-		case cdpcontrol.CodeChartNotFound, cdpcontrol.CodeSnapshotNotFound, cdpcontrol.CodeNoteNotFound:
+		case cdpcontrol.CodeChartNotFound, cdpcontrol.CodeSnapshotNotFound, cdpcontrol.CodeNoteNotFound, cdpcontrol.CodeJobNotFound:
 			return huma.Error404NotFound(coded.Message)
+		case cdpcontrol.CodeConflict:
+			return huma.Error409Conflict(coded.Message)
 		case cdpcontrol.CodeEvalTimeout:
 			return huma.Error504GatewayTimeout(coded.Message)
 		case cdpcontrol.CodeAPIUnavailable, cdpcontrol.CodeCDPUnavailable:
@@ -8,6 +8,8 @@ import (
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/export"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/jobs"
 )
 
 func registerChartHandlers(api huma.API, svc Service) {
@@ -570,11 +572,12 @@ func registerChartHandlers(api huma.API, svc Service) {
 		})
 
 	type exportInput struct {
-		ChartID string `path:"chart_id"`
-		Pane    int    `query:"pane" default:"-1" doc:"Target pane index (0-based). Omit to use active pane."`
-	}
-	type exportOutput struct {
-		Body cdpcontrol.ChartExportResult
+		ChartID string   `path:"chart_id"`
+		Pane    int      `query:"pane" default:"-1" doc:"Target pane index (0-based). Omit to use active pane."`
+		Format  string   `query:"format" doc:"json, csv, ndjson or parquet. Overrides the Accept header."`
+		TZ      string   `query:"tz" doc:"IANA timezone for RFC 3339 times in CSV and NDJSON (default UTC)."`
+		Studies []string `query:"studies" doc:"Only include these studies' columns (source ID, entity ID or name); OHLCV and time columns are always kept."`
+		Accept  string   `header:"Accept"`
 	}
 	huma.Register(api, huma.Operation{
 		OperationID: "export-chart-data",
@@ -582,13 +585,152 @@ func registerChartHandlers(api huma.API, svc Service) {
 		Path:        "/api/v1/chart/{chart_id}/export",
 		Summary:     "Export chart data (OHLCV + all studies)",
 		Description: "Returns all visible bars with OHLCV and every study plot column. " +
-			"Equivalent to TradingView's native Download chart data dialog.",
-		Tags: []string{"Data"},
-	}, func(ctx context.Context, input *exportInput) (*exportOutput, error) {
+			"`studies` maps each study source in the schema to its entity ID, pane index and current inputs; " +
+			"`?studies=` keeps only the listed studies' columns. " +
+			"Equivalent to TradingView's native Download chart data dialog. " +
+			"JSON by default; text/csv, application/x-ndjson and application/vnd.apache.parquet " +
+			"are selected via Accept (q-values honored, 406 if none is acceptable) or ?format= and streamed with named, typed columns.",
+		Tags:      []string{"Data"},
+		Responses: exportResponses(api),
+	}, func(ctx context.Context, input *exportInput) (*huma.StreamResponse, error) {
+		format, err := negotiateExport(input.Format, input.Accept)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := loadExportLocation(input.TZ)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
 		result, err := svc.ExportChartData(ctx, input.ChartID, input.Pane)
 		if err != nil {
 			return nil, mapErr(err)
 		}
-		return &exportOutput{Body: result}, nil
+		if len(input.Studies) > 0 {
+			if result, err = export.SelectStudies(result, input.Studies); err != nil {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+		}
+		return streamChartExport(result, format, loc), nil
+	})
+
+	type backfillJobOutput struct {
+		Body jobs.Job
+	}
+	type backfillJobInput struct {
+		ChartID string `path:"chart_id"`
+		JobID   string `path:"job_id"`
+	}
+	huma.Register(api, huma.Operation{
+		OperationID: "start-chart-backfill",
+		Method:      http.MethodPost,
+		Path:        "/api/v1/chart/{chart_id}/export/backfill",
+		Summary:     "Start a deep historical backfill",
+		Description: "Starts a background job that pages the chart backwards (go-to-date, then scroll left), " +
+			"waits for each page of history to finish loading and stitches the de-duplicated bars into one export. " +
+			"With the WebSocket relay enabled a page is exported as soon as its series completes loading; " +
+			"otherwise each page waits out a 15s settle timeout. " +
+			"Stops once bars back to `from` are loaded, `max_bars` are stitched or the history runs out. " +
+			"Responds 409 while another backfill or a batch export is running on the chart. " +
+			"Poll the returned job for progress and fetch the result from `.../backfill/{job_id}/data`; " +
+			"jobs and results are kept under `JOBS_DIR` across restarts.",
+		Tags:          []string{"Data"},
+		DefaultStatus: http.StatusAccepted,
+	}, func(ctx context.Context, input *struct {
+		ChartID string `path:"chart_id"`
+		Body    struct {
+			From    string `json:"from,omitempty" doc:"Oldest bar wanted: Unix seconds, RFC 3339 or YYYY-MM-DD (UTC)."`
+			MaxBars int    `json:"max_bars,omitempty" doc:"Maximum number of bars to stitch, keeping the newest."`
+			Pane    *int   `json:"pane,omitempty" doc:"Target pane index (0-based). Omit to use active pane."`
+		}
+	}) (*backfillJobOutput, error) {
+		from, err := parseBackfillFrom(input.Body.From)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		pane := -1
+		if input.Body.Pane != nil {
+			pane = *input.Body.Pane
+		}
+		job, err := svc.StartBackfill(ctx, input.ChartID, pane, from, input.Body.MaxBars)
+		if err != nil {
+			return nil, mapErr(err)
+		}
+		return &backfillJobOutput{Body: job}, nil
+	})
+
+	huma.Register(api, huma.Operation{OperationID: "list-chart-backfills", Method: http.MethodGet, Path: "/api/v1/chart/{chart_id}/export/backfill", Summary: "List backfill jobs for a chart", Tags: []string{"Data"}},
+		func(ctx context.Context, input *struct {
+			ChartID string `path:"chart_id"`
+		}) (*struct {
+			Body struct {
+				Jobs []jobs.Job `json:"jobs"`
+			}
+		}, error) {
+			list, err := svc.ListBackfills(ctx, input.ChartID)
+			if err != nil {
+				return nil, mapErr(err)
+			}
+			out := &struct {
+				Body struct {
+					Jobs []jobs.Job `json:"jobs"`
+				}
+			}{}
+			out.Body.Jobs = list
+			return out, nil
+		})
+
+	huma.Register(api, huma.Operation{OperationID: "get-chart-backfill", Method: http.MethodGet, Path: "/api/v1/chart/{chart_id}/export/backfill/{job_id}", Summary: "Get backfill job status and progress", Tags: []string{"Data"}},
+		func(ctx context.Context, input *backfillJobInput) (*backfillJobOutput, error) {
+			job, err := svc.GetBackfill(ctx, input.ChartID, input.JobID)
+			if err != nil {
+				return nil, mapErr(err)
+			}
+			return &backfillJobOutput{Body: job}, nil
+		})
+
+	huma.Register(api, huma.Operation{OperationID: "cancel-chart-backfill", Method: http.MethodDelete, Path: "/api/v1/chart/{chart_id}/export/backfill/{job_id}", Summary: "Cancel a backfill job", Description: "Bars stitched before cancellation remain available from the data endpoint.", Tags: []string{"Data"}},
+		func(ctx context.Context, input *backfillJobInput) (*backfillJobOutput, error) {
+			job, err := svc.CancelBackfill(ctx, input.ChartID, input.JobID)
+			if err != nil {
+				return nil, mapErr(err)
+			}
+			return &backfillJobOutput{Body: job}, nil
+		})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-chart-backfill-data",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/chart/{chart_id}/export/backfill/{job_id}/data",
+		Summary:     "Download the stitched backfill export",
+		Description: "Returns the bars of a finished (or canceled) backfill in the same shape and formats as the chart export. " +
+			"Responds 409 while the job is still running.",
+		Tags:      []string{"Data"},
+		Responses: exportResponses(api),
+	}, func(ctx context.Context, input *struct {
+		ChartID string   `path:"chart_id"`
+		JobID   string   `path:"job_id"`
+		Format  string   `query:"format" doc:"json, csv, ndjson or parquet. Overrides the Accept header."`
+		TZ      string   `query:"tz" doc:"IANA timezone for RFC 3339 times in CSV and NDJSON (default UTC)."`
+		Studies []string `query:"studies" doc:"Only include these studies' columns (source ID, entity ID or name); OHLCV and time columns are always kept."`
+		Accept  string   `header:"Accept"`
+	}) (*huma.StreamResponse, error) {
+		format, err := negotiateExport(input.Format, input.Accept)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := loadExportLocation(input.TZ)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		result, err := svc.BackfillResult(ctx, input.ChartID, input.JobID)
+		if err != nil {
+			return nil, mapErr(err)
+		}
+		if len(input.Studies) > 0 {
+			if result, err = export.SelectStudies(result, input.Studies); err != nil {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+		}
+		return streamChartExport(result, format, loc), nil
 	})
 }
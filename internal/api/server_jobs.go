@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/export"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/jobs"
+)
+
+func registerJobHandlers(api huma.API, svc Service) {
+	type jobOutput struct {
+		Body jobs.Job
+	}
+	type jobIDInput struct {
+		JobID string `path:"job_id"`
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "start-batch-export",
+		Method:      http.MethodPost,
+		Path:        "/api/v1/jobs/export",
+		Summary:     "Start a multi-symbol export job",
+		Description: "Exports the same chart setup for many symbols. Sets the resolution and applies the study template once, " +
+			"then for each symbol calls set-symbol, waits for its bars to load and writes one file into the job directory " +
+			"(`JOBS_DIR/<job_id>`). Per-symbol errors are recorded in the progress without stopping the job. " +
+			"Jobs are saved to disk and can be resumed after cancellation or a controller restart. " +
+			"Responds 409 while a backfill or another batch export is running on the chart.",
+		Tags:          []string{"Jobs"},
+		DefaultStatus: http.StatusAccepted,
+	}, func(ctx context.Context, input *struct {
+		Body struct {
+			ChartID       string   `json:"chart_id" required:"true"`
+			Pane          *int     `json:"pane,omitempty" doc:"Target pane index (0-based). Omit to use active pane."`
+			Symbols       []string `json:"symbols" required:"true" minItems:"1"`
+			Resolution    string   `json:"resolution,omitempty" doc:"Resolution to set before exporting, e.g. 1D or 60."`
+			StudyTemplate string   `json:"study_template,omitempty" doc:"Study template to apply once before exporting."`
+			Format        string   `json:"format,omitempty" doc:"csv (default), ndjson, parquet or json."`
+			TZ            string   `json:"tz,omitempty" doc:"IANA timezone for RFC 3339 times in CSV and NDJSON (default UTC)."`
+		}
+	}) (*jobOutput, error) {
+		req := export.BatchRequest{
+			ChartID:       input.Body.ChartID,
+			Pane:          -1,
+			Symbols:       input.Body.Symbols,
+			Resolution:    input.Body.Resolution,
+			StudyTemplate: input.Body.StudyTemplate,
+			Format:        export.Format(input.Body.Format),
+			TZ:            input.Body.TZ,
+		}
+		if input.Body.Pane != nil {
+			req.Pane = *input.Body.Pane
+		}
+		job, err := svc.StartBatchExport(ctx, req)
+		if err != nil {
+			return nil, mapErr(err)
+		}
+		return &jobOutput{Body: job}, nil
+	})
+
+	huma.Register(api, huma.Operation{OperationID: "list-batch-exports", Method: http.MethodGet, Path: "/api/v1/jobs/export", Summary: "List multi-symbol export jobs", Tags: []string{"Jobs"}},
+		func(ctx context.Context, input *struct{}) (*struct {
+			Body struct {
+				Jobs []jobs.Job `json:"jobs"`
+			}
+		}, error) {
+			list, err := svc.ListBatchExports(ctx)
+			if err != nil {
+				return nil, mapErr(err)
+			}
+			out := &struct {
+				Body struct {
+					Jobs []jobs.Job `json:"jobs"`
+				}
+			}{}
+			out.Body.Jobs = list
+			return out, nil
+		})
+
+	huma.Register(api, huma.Operation{OperationID: "get-batch-export", Method: http.MethodGet, Path: "/api/v1/jobs/export/{job_id}", Summary: "Get export job status and per-symbol progress", Tags: []string{"Jobs"}},
+		func(ctx context.Context, input *jobIDInput) (*jobOutput, error) {
+			job, err := svc.GetBatchExport(ctx, input.JobID)
+			if err != nil {
+				return nil, mapErr(err)
+			}
+			return &jobOutput{Body: job}, nil
+		})
+
+	huma.Register(api, huma.Operation{OperationID: "cancel-batch-export", Method: http.MethodDelete, Path: "/api/v1/jobs/export/{job_id}", Summary: "Cancel an export job", Description: "Files already written are kept; the job can be resumed.", Tags: []string{"Jobs"}},
+		func(ctx context.Context, input *jobIDInput) (*jobOutput, error) {
+			job, err := svc.CancelBatchExport(ctx, input.JobID)
+			if err != nil {
+				return nil, mapErr(err)
+			}
+			return &jobOutput{Body: job}, nil
+		})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "resume-batch-export",
+		Method:        http.MethodPost,
+		Path:          "/api/v1/jobs/export/{job_id}/resume",
+		Summary:       "Resume an export job",
+		Description:   "Re-runs a canceled, interrupted or finished job, skipping symbols already exported and retrying failed ones.",
+		Tags:          []string{"Jobs"},
+		DefaultStatus: http.StatusAccepted,
+	}, func(ctx context.Context, input *jobIDInput) (*jobOutput, error) {
+		job, err := svc.ResumeBatchExport(ctx, input.JobID)
+		if err != nil {
+			return nil, mapErr(err)
+		}
+		return &jobOutput{Body: job}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-batch-export-file",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/jobs/export/{job_id}/files/{file}",
+		Summary:     "Download one exported file",
+		Description: "`file` is a name from the job's `progress.symbols[].file`.",
+		Tags:        []string{"Jobs"},
+		Responses: map[string]*huma.Response{
+			"200": {Description: "The exported file", Content: map[string]*huma.MediaType{
+				"application/octet-stream": {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+			}},
+		},
+	}, func(ctx context.Context, input *struct {
+		JobID string `path:"job_id"`
+		File  string `path:"file"`
+	}) (*huma.StreamResponse, error) {
+		f, format, err := svc.OpenBatchExportFile(ctx, input.JobID, input.File)
+		if err != nil {
+			return nil, mapErr(err)
+		}
+		return &huma.StreamResponse{Body: func(ctx huma.Context) {
+			defer f.Close()
+			ctx.SetHeader("Content-Type", format.ContentType())
+			ctx.SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, input.File))
+			if _, err := io.Copy(ctx.BodyWriter(), f); err != nil {
+				slog.Debug("export file response write failed", "error", err)
+			}
+		}}, nil
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/relay"
+)
+
+// wsChartParams are the params shared by chart-scoped WebSocket operations.
+// Pane defaults to -1 (active pane), as in the REST API.
+type wsChartParams struct {
+	ChartID string `json:"chart_id"`
+	Pane    *int   `json:"pane,omitempty"`
+}
+
+func (p wsChartParams) pane() int {
+	if p.Pane == nil {
+		return -1
+	}
+	return *p.Pane
+}
+
+// RelayOperations exposes a subset of the controller as operations callable
+// over the relay WebSocket ({"action":"call","op":"set_symbol",...}).
+func RelayOperations(svc Service) map[string]relay.Operation {
+	return map[string]relay.Operation{
+		"list_charts": func(ctx context.Context, _ json.RawMessage) (any, error) {
+			charts, err := svc.ListCharts(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"charts": charts}, nil
+		},
+		"get_symbol": chartOp(func(ctx context.Context, p wsChartParams, _ json.RawMessage) (any, error) {
+			symbol, err := svc.GetSymbol(ctx, p.ChartID, p.pane())
+			return map[string]any{"chart_id": p.ChartID, "symbol": symbol}, err
+		}),
+		"set_symbol": chartOp(func(ctx context.Context, p wsChartParams, raw json.RawMessage) (any, error) {
+			var in struct {
+				Symbol string `json:"symbol"`
+			}
+			if err := decodeParams(raw, &in); err != nil {
+				return nil, err
+			}
+			symbol, err := svc.SetSymbol(ctx, p.ChartID, in.Symbol, p.pane())
+			return map[string]any{"chart_id": p.ChartID, "current_symbol": symbol}, err
+		}),
+		"get_resolution": chartOp(func(ctx context.Context, p wsChartParams, _ json.RawMessage) (any, error) {
+			res, err := svc.GetResolution(ctx, p.ChartID, p.pane())
+			return map[string]any{"chart_id": p.ChartID, "resolution": res}, err
+		}),
+		"set_resolution": chartOp(func(ctx context.Context, p wsChartParams, raw json.RawMessage) (any, error) {
+			var in struct {
+				Resolution string `json:"resolution"`
+			}
+			if err := decodeParams(raw, &in); err != nil {
+				return nil, err
+			}
+			res, err := svc.SetResolution(ctx, p.ChartID, in.Resolution, p.pane())
+			return map[string]any{"chart_id": p.ChartID, "current_resolution": res}, err
+		}),
+		"list_studies": chartOp(func(ctx context.Context, p wsChartParams, _ json.RawMessage) (any, error) {
+			studies, err := svc.ListStudies(ctx, p.ChartID, p.pane())
+			return map[string]any{"chart_id": p.ChartID, "studies": studies}, err
+		}),
+		"add_study": chartOp(func(ctx context.Context, p wsChartParams, raw json.RawMessage) (any, error) {
+			var in struct {
+				Name         string         `json:"name"`
+				Inputs       map[string]any `json:"inputs,omitempty"`
+				ForceOverlay bool           `json:"force_overlay,omitempty"`
+			}
+			if err := decodeParams(raw, &in); err != nil {
+				return nil, err
+			}
+			study, err := svc.AddStudy(ctx, p.ChartID, in.Name, in.Inputs, in.ForceOverlay, p.pane())
+			return map[string]any{"chart_id": p.ChartID, "study": study, "status": "added"}, err
+		}),
+		"remove_study": chartOp(func(ctx context.Context, p wsChartParams, raw json.RawMessage) (any, error) {
+			var in struct {
+				StudyID string `json:"study_id"`
+			}
+			if err := decodeParams(raw, &in); err != nil {
+				return nil, err
+			}
+			if err := svc.RemoveStudy(ctx, p.ChartID, in.StudyID, p.pane()); err != nil {
+				return nil, err
+			}
+			return map[string]any{"chart_id": p.ChartID, "study_id": in.StudyID, "status": "removed"}, nil
+		}),
+	}
+}
+
+// chartOp decodes the shared chart params before calling fn with the raw
+// params for op-specific fields.
+func chartOp(fn func(ctx context.Context, p wsChartParams, raw json.RawMessage) (any, error)) relay.Operation {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var p wsChartParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		out, err := fn(ctx, p, raw)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+}
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ type HTTPCapture struct {
 	captureStatic bool
 	maxBodyBytes  int
 	maxResBytes   int
+	pauses        *Pauses
 
 	pending   map[string]*types.PendingRequest
 	pendingMu sync.RWMutex
@@ -53,10 +54,27 @@ func NewHTTPCapture(
 	return h
 }
 
+// SetPauses makes the capture drop records of paused tabs and data types.
+// Call it before attaching to tabs.
+func (h *HTTPCapture) SetPauses(p *Pauses) {
+	h.pauses = p
+}
+
 func (h *HTTPCapture) Close() {
 	close(h.done)
 }
 
+// WriteGap writes a capture_gap marker to the tab's HTTP stream.
+func (h *HTTPCapture) WriteGap(tab types.TabInfo, gap types.CaptureGap) {
+	if !h.captureHTTP {
+		return
+	}
+	writer := h.registry.GetWriter(tab.PathSegment, "http", tab.BrowserID)
+	if err := writer.Write(&gap); err != nil {
+		slog.Error("Failed to write HTTP capture gap", "tab_id", tab.TargetID, "error", err)
+	}
+}
+
 func (h *HTTPCapture) OnRequestWillBeSent(tabID string, ev *network.EventRequestWillBeSent) {
 	var postData string
 	if ev.Request.HasPostData && len(ev.Request.PostDataEntries) > 0 {
@@ -134,6 +152,11 @@ func (h *HTTPCapture) OnLoadingFinished(tabID string, ev *network.EventLoadingFi
 	browserID := tabInfo.BrowserID
 	resourceDir := storage.MapResourceType(pending.ResourceType)
 	requestURL := pending.Capture.URL
+	wantStatic := h.captureStatic && resourceDir != "" && !h.pauses.Paused(tabID, DataStatic)
+	wantHTTP := h.captureHTTP && !h.pauses.Paused(tabID, DataHTTP)
+	if !wantStatic && !wantHTTP {
+		return
+	}
 
 	go func() {
 		var body []byte
@@ -146,17 +169,27 @@ func (h *HTTPCapture) OnLoadingFinished(tabID string, ev *network.EventLoadingFi
 			}
 		}
 
-		if h.captureStatic && resourceDir != "" && len(body) > 0 {
+		if wantStatic && len(body) > 0 {
 			resourceBody, truncated, originalSize, bodyHash := truncateBytes(body, h.maxResBytes)
-			filename := storage.FilenameFromURL(requestURL)
-			if err := h.resourceWriter.WriteRaw(pathSegment, resourceDir, filename, resourceBody); err != nil {
+			entry := storage.ResourceEntry{
+				URL:         requestURL,
+				TabID:       tabID,
+				PathSegment: pathSegment,
+				Type:        resourceDir,
+				Filename:    storage.FilenameFromURL(requestURL),
+				Truncated:   truncated,
+			}
+			if truncated {
+				entry.OriginalSize = originalSize
+			}
+			if _, err := h.resourceWriter.Write(entry, resourceBody); err != nil {
 				slog.Error("Failed to write resource file", "request_id", ev.RequestID, "error", err)
 			} else if truncated {
 				slog.Warn("Resource truncated due to max size", "request_id", ev.RequestID, "original_size", originalSize, "kept_size", len(resourceBody), "sha256", bodyHash)
 			}
 		}
 
-		if !h.captureHTTP {
+		if !wantHTTP {
 			return
 		}
 
@@ -0,0 +1,107 @@
+package capture
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Data types that can be paused.
+const (
+	DataHTTP      = "http"
+	DataWebSocket = "websocket"
+	DataStatic    = "static"
+)
+
+// DataTypes lists the pausable data types.
+var DataTypes = []string{DataHTTP, DataWebSocket, DataStatic}
+
+// Pauses records which tabs and data types are paused and since when. A
+// record is dropped while its tab or its data type is paused. The nil
+// *Pauses pauses nothing. It is safe for concurrent use.
+type Pauses struct {
+	mu    sync.RWMutex
+	tabs  map[string]time.Time
+	types map[string]time.Time
+}
+
+func NewPauses() *Pauses {
+	return &Pauses{tabs: make(map[string]time.Time), types: make(map[string]time.Time)}
+}
+
+// Paused reports whether records of dataType from tabID are dropped.
+func (p *Pauses) Paused(tabID, dataType string) bool {
+	_, paused := p.Since(tabID, dataType)
+	return paused
+}
+
+// Since returns the earliest start of the pauses affecting tabID and
+// dataType.
+func (p *Pauses) Since(tabID, dataType string) (time.Time, bool) {
+	if p == nil {
+		return time.Time{}, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	tab, tabPaused := p.tabs[tabID]
+	typ, typePaused := p.types[dataType]
+	switch {
+	case tabPaused && typePaused:
+		if typ.Before(tab) {
+			return typ, true
+		}
+		return tab, true
+	case tabPaused:
+		return tab, true
+	case typePaused:
+		return typ, true
+	}
+	return time.Time{}, false
+}
+
+// SetTab pauses or resumes a tab and reports whether that changed anything.
+func (p *Pauses) SetTab(tabID string, paused bool) bool {
+	return p.set(p.tabs, tabID, paused)
+}
+
+// SetType pauses or resumes a data type and reports whether that changed
+// anything.
+func (p *Pauses) SetType(dataType string, paused bool) bool {
+	return p.set(p.types, dataType, paused)
+}
+
+func (p *Pauses) set(m map[string]time.Time, key string, paused bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, was := m[key]
+	switch {
+	case paused && !was:
+		m[key] = time.Now().UTC()
+	case !paused && was:
+		delete(m, key)
+	default:
+		return false
+	}
+	return true
+}
+
+// Tabs returns the paused tab IDs, sorted.
+func (p *Pauses) Tabs() []string {
+	return p.keys(p.tabs)
+}
+
+// Types returns the paused data types, sorted.
+func (p *Pauses) Types() []string {
+	return p.keys(p.types)
+}
+
+func (p *Pauses) keys(m map[string]time.Time) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -23,6 +23,7 @@ type WebSocketCapture struct {
 	tabRegistry   types.TabInfoProvider
 	captureWS     bool
 	maxFrameBytes int
+	pauses        *Pauses
 
 	connections   map[string]*WebSocketConnectionInfo
 	connectionsMu sync.RWMutex
@@ -38,6 +39,13 @@ func NewWebSocketCapture(registry *storage.WriterRegistry, tabRegistry types.Tab
 	}
 }
 
+// SetPauses makes the capture drop events of paused tabs and data types.
+// Connections are still tracked while paused. Call it before attaching to
+// tabs.
+func (w *WebSocketCapture) SetPauses(p *Pauses) {
+	w.pauses = p
+}
+
 func (w *WebSocketCapture) OnWebSocketCreated(tabID string, ev *network.EventWebSocketCreated) {
 	if !w.captureWS {
 		return
@@ -65,6 +73,9 @@ func (w *WebSocketCapture) OnWebSocketCreated(tabID string, ev *network.EventWeb
 	w.connectionsMu.Lock()
 	w.connections[string(ev.RequestID)] = conn
 	w.connectionsMu.Unlock()
+	if w.pauses.Paused(tabID, DataWebSocket) {
+		return
+	}
 
 	capture := &types.WebSocketCapture{
 		Timestamp: time.Now().UTC(),
@@ -81,7 +92,7 @@ func (w *WebSocketCapture) OnWebSocketCreated(tabID string, ev *network.EventWeb
 }
 
 func (w *WebSocketCapture) OnWebSocketFrameReceived(tabID string, ev *network.EventWebSocketFrameReceived) {
-	if !w.captureWS {
+	if !w.captureWS || w.pauses.Paused(tabID, DataWebSocket) {
 		return
 	}
 
@@ -114,7 +125,7 @@ func (w *WebSocketCapture) OnWebSocketFrameReceived(tabID string, ev *network.Ev
 }
 
 func (w *WebSocketCapture) OnWebSocketFrameSent(tabID string, ev *network.EventWebSocketFrameSent) {
-	if !w.captureWS {
+	if !w.captureWS || w.pauses.Paused(tabID, DataWebSocket) {
 		return
 	}
 
@@ -157,7 +168,7 @@ func (w *WebSocketCapture) OnWebSocketClosed(tabID string, ev *network.EventWebS
 		delete(w.connections, string(ev.RequestID))
 	}
 	w.connectionsMu.Unlock()
-	if !ok {
+	if !ok || w.pauses.Paused(tabID, DataWebSocket) {
 		return
 	}
 
@@ -175,6 +186,29 @@ func (w *WebSocketCapture) OnWebSocketClosed(tabID string, ev *network.EventWebS
 	}
 }
 
+// WriteGap writes a capture_gap marker to the tab's WebSocket stream.
+func (w *WebSocketCapture) WriteGap(tab types.TabInfo, gap types.CaptureGap) {
+	if !w.captureWS {
+		return
+	}
+	writer := w.registry.GetWriter(tab.PathSegment, "websocket", tab.BrowserID)
+	if err := writer.Write(&gap); err != nil {
+		slog.Error("Failed to write WebSocket capture gap", "tab_id", tab.TargetID, "error", err)
+	}
+}
+
+// DropTab forgets the open connections of a tab that is gone, since no
+// close event will arrive for them.
+func (w *WebSocketCapture) DropTab(tabID string) {
+	w.connectionsMu.Lock()
+	defer w.connectionsMu.Unlock()
+	for id, conn := range w.connections {
+		if conn.TabID == tabID {
+			delete(w.connections, id)
+		}
+	}
+}
+
 func (w *WebSocketCapture) GetActiveConnections() int {
 	w.connectionsMu.RLock()
 	defer w.connectionsMu.RUnlock()
@@ -0,0 +1,279 @@
+// Package catalog builds an endpoint catalog from researcher captures:
+// HTTP requests grouped by templated path and WebSocket messages grouped by
+// "m" type, each with a JSON schema inferred from the bodies seen. Two
+// catalogs can be diffed to spot endpoints that appeared, disappeared or
+// changed shape after a TradingView deploy.
+package catalog
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// maxExamples is the number of distinct example URLs kept per endpoint.
+const maxExamples = 3
+
+// Catalog is the result of an analysis pass.
+type Catalog struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	From        string      `json:"from,omitempty"`
+	To          string      `json:"to,omitempty"`
+	Endpoints   []*Endpoint `json:"endpoints"`
+}
+
+// Endpoint is one HTTP endpoint or WebSocket message type.
+//
+// For HTTP, Request and Response describe the JSON request and response
+// bodies. For WebSocket messages they describe the "p" params of outgoing
+// and incoming messages respectively.
+type Endpoint struct {
+	Key          string    `json:"key"`
+	Kind         string    `json:"kind"` // "http" or "websocket"
+	Method       string    `json:"method,omitempty"`
+	Host         string    `json:"host,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	MessageType  string    `json:"message_type,omitempty"`
+	Count        int       `json:"count"`
+	Statuses     []int     `json:"statuses,omitempty"`
+	Directions   []string  `json:"directions,omitempty"`
+	QueryParams  []string  `json:"query_params,omitempty"`
+	ContentTypes []string  `json:"content_types,omitempty"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Examples     []string  `json:"examples,omitempty"`
+	Request      *Schema   `json:"request_schema,omitempty"`
+	Response     *Schema   `json:"response_schema,omitempty"`
+}
+
+// Builder accumulates captures into a Catalog.
+type Builder struct {
+	endpoints map[string]*Endpoint
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{endpoints: make(map[string]*Endpoint)}
+}
+
+// AddHTTP adds an HTTP capture to its templated-path group.
+func (b *Builder) AddHTTP(c *types.HTTPCapture) {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	path := TemplatePath(u.Path)
+	key := c.Method + " " + u.Host + path
+	e := b.endpoint(key, c.Timestamp, func() *Endpoint {
+		return &Endpoint{Kind: "http", Method: c.Method, Host: u.Host, Path: path}
+	})
+	e.addExample(c.URL)
+	for name := range u.Query() {
+		e.QueryParams = insertSorted(e.QueryParams, name)
+	}
+	if c.Request.PostData != "" {
+		if e.Request == nil {
+			e.Request = &Schema{}
+		}
+		e.Request.AddJSON([]byte(c.Request.PostData))
+	}
+	r := c.Response
+	if r == nil {
+		return
+	}
+	if !slices.Contains(e.Statuses, r.Status) {
+		e.Statuses = append(e.Statuses, r.Status)
+		sort.Ints(e.Statuses)
+	}
+	if ct := contentType(r.Headers); ct != "" {
+		e.ContentTypes = insertSorted(e.ContentTypes, ct)
+	}
+	body := []byte(r.Body)
+	if r.BodyBase64 != "" {
+		body, _ = base64.StdEncoding.DecodeString(r.BodyBase64)
+	}
+	if len(body) > 0 && !r.Truncated && looksLikeJSON(body) {
+		if e.Response == nil {
+			e.Response = &Schema{}
+		}
+		e.Response.AddJSON(body)
+	}
+}
+
+// AddWebSocket adds each message of a captured frame to its "m" type
+// group. Heartbeats, lifecycle events and messages without an "m" field
+// are skipped.
+func (b *Builder) AddWebSocket(c *types.WebSocketCapture) {
+	if c.EventType != "frame_sent" && c.EventType != "frame_received" {
+		return
+	}
+	for _, raw := range tvproto.SplitFrames(c.PayloadData) {
+		msg, ok := tvproto.ParseMessage(raw)
+		if !ok || msg.Type == "" {
+			continue
+		}
+		e := b.endpoint("WS "+msg.Type, c.Timestamp, func() *Endpoint {
+			return &Endpoint{Kind: "websocket", MessageType: msg.Type}
+		})
+		if u, err := url.Parse(c.URL); err == nil {
+			e.addExample(u.Scheme + "://" + u.Host + u.Path)
+		}
+		e.Directions = insertSorted(e.Directions, c.Direction)
+		if len(msg.Params) == 0 {
+			continue
+		}
+		schema := &e.Response
+		if c.Direction == "outgoing" {
+			schema = &e.Request
+		}
+		if *schema == nil {
+			*schema = &Schema{}
+		}
+		(*schema).AddJSON(msg.Params)
+	}
+}
+
+func (b *Builder) endpoint(key string, ts time.Time, create func() *Endpoint) *Endpoint {
+	e, ok := b.endpoints[key]
+	if !ok {
+		e = create()
+		e.Key, e.FirstSeen, e.LastSeen = key, ts, ts
+		b.endpoints[key] = e
+	}
+	e.Count++
+	if ts.Before(e.FirstSeen) {
+		e.FirstSeen = ts
+	}
+	if ts.After(e.LastSeen) {
+		e.LastSeen = ts
+	}
+	return e
+}
+
+func (e *Endpoint) addExample(u string) {
+	if len(e.Examples) < maxExamples && !slices.Contains(e.Examples, u) {
+		e.Examples = append(e.Examples, u)
+	}
+}
+
+// Catalog returns the endpoints sorted by kind and key.
+func (b *Builder) Catalog() *Catalog {
+	c := &Catalog{GeneratedAt: time.Now().UTC(), Endpoints: make([]*Endpoint, 0, len(b.endpoints))}
+	for _, e := range b.endpoints {
+		e.Request.finish()
+		e.Response.finish()
+		c.Endpoints = append(c.Endpoints, e)
+	}
+	sortEndpoints(c.Endpoints)
+	return c
+}
+
+func sortEndpoints(es []*Endpoint) {
+	sort.Slice(es, func(i, j int) bool {
+		if es[i].Kind != es[j].Kind {
+			return es[i].Kind < es[j].Kind
+		}
+		if es[i].Host != es[j].Host {
+			return es[i].Host < es[j].Host
+		}
+		if es[i].Path != es[j].Path {
+			return es[i].Path < es[j].Path
+		}
+		return es[i].Key < es[j].Key
+	})
+}
+
+var (
+	uuidRe   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexRe    = regexp.MustCompile(`^[0-9a-fA-F]{12,}$`)
+	numRe    = regexp.MustCompile(`^[0-9]+$`)
+	symbolRe = regexp.MustCompile(`^[A-Z0-9_.!]+:[A-Za-z0-9_.!/-]+$`)
+	idRe     = regexp.MustCompile(`^[A-Za-z0-9_-]{6,64}$`)
+)
+
+// TemplatePath replaces the variable segments of a URL path with
+// placeholders: "{symbol}" for EXCHANGE:TICKER segments and "{id}" for
+// numbers, UUIDs, long hex strings and short mixed letter/digit tokens such
+// as layout IDs ("/charts-storage/get/layout/kP2sHc5q/sources" becomes
+// "/charts-storage/get/layout/{id}/sources").
+func TemplatePath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if seg == "" {
+			continue
+		}
+		if s, err := url.PathUnescape(seg); err == nil {
+			seg = s
+		}
+		switch {
+		case symbolRe.MatchString(seg):
+			segs[i] = "{symbol}"
+		case numRe.MatchString(seg), uuidRe.MatchString(seg), hexRe.MatchString(seg), isToken(seg):
+			segs[i] = "{id}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// isToken reports whether seg looks generated rather than a word: at least
+// two digits mixed with letters. Names like "LineTool5PointsPattern" keep
+// their literal form.
+func isToken(seg string) bool {
+	if !idRe.MatchString(seg) {
+		return false
+	}
+	var digits, letters int
+	for _, r := range seg {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letters++
+		}
+	}
+	return digits >= 2 && letters > 0
+}
+
+func contentType(headers map[string]string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			if mt, _, err := mime.ParseMediaType(v); err == nil {
+				return mt
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+func looksLikeJSON(body []byte) bool {
+	for _, b := range body {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func insertSorted(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	i, found := slices.BinarySearch(list, s)
+	if found {
+		return list
+	}
+	return slices.Insert(list, i, s)
+}
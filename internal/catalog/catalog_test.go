@@ -0,0 +1,146 @@
+package catalog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+func TestTemplatePath(t *testing.T) {
+	cases := map[string]string{
+		"/charts-storage/get/layout/kP2sHc5q/sources": "/charts-storage/get/layout/{id}/sources",
+		"/api/v1/alerts/1234567":                      "/api/v1/alerts/{id}",
+		"/x/3f2a9c1e-1111-2222-3333-444455556666/y":   "/x/{id}/y",
+		"/symbols/NASDAQ:AAPL/info":                   "/symbols/{symbol}/info",
+		"/symbols/NASDAQ%3AAAPL/":                     "/symbols/{symbol}/",
+		"/drawing-templates/LineTool5PointsPattern/":  "/drawing-templates/LineTool5PointsPattern/",
+		"/public/news-flow/v2/news":                   "/public/news-flow/v2/news",
+		"/static/bundles/runtime.deadbeefcafe0123.js": "/static/bundles/runtime.deadbeefcafe0123.js",
+		"/static/bundles/deadbeefcafe0123":            "/static/bundles/{id}",
+	}
+	for in, want := range cases {
+		if got := TemplatePath(in); got != want {
+			t.Errorf("TemplatePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func httpCapture(ts time.Time, method, url string, status int, body string) *types.HTTPCapture {
+	return &types.HTTPCapture{
+		Timestamp: ts,
+		URL:       url,
+		Method:    method,
+		Response: &types.HTTPResponse{
+			Status:  status,
+			Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"},
+			Body:    body,
+		},
+	}
+}
+
+func wsFrame(ts time.Time, direction, payload string) *types.WebSocketCapture {
+	event := "frame_received"
+	if direction == "outgoing" {
+		event = "frame_sent"
+	}
+	return &types.WebSocketCapture{
+		Timestamp:   ts,
+		URL:         "wss://data.tradingview.com/socket.io/websocket?from=chart",
+		EventType:   event,
+		Direction:   direction,
+		PayloadData: payload,
+	}
+}
+
+func TestBuilderGroupsAndInfers(t *testing.T) {
+	ts := time.Date(2026, 2, 21, 0, 0, 0, 0, time.UTC)
+	b := NewBuilder()
+	b.AddHTTP(httpCapture(ts, "GET", "https://charts-storage.tradingview.com/charts-storage/get/layout/kP2sHc5q/sources?chart_id=1", 200, `{"sources":{}}`))
+	b.AddHTTP(httpCapture(ts.Add(time.Minute), "GET", "https://charts-storage.tradingview.com/charts-storage/get/layout/zZ9yXw8v/sources", 404, `{"error":"x"}`))
+	b.AddWebSocket(wsFrame(ts, "incoming", `~m~26~m~{"m":"du","p":["cs_1",{}]}~m~4~m~~h~1`))
+	b.AddWebSocket(wsFrame(ts, "outgoing", `{"m":"quote_add_symbols","p":["qs_1","NASDAQ:AAPL"]}`))
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: ts, EventType: "created", URL: "wss://x"})
+
+	c := b.Catalog()
+	if len(c.Endpoints) != 3 {
+		t.Fatalf("endpoints = %d, want 3", len(c.Endpoints))
+	}
+	e := c.Endpoints[0]
+	if e.Key != "GET charts-storage.tradingview.com/charts-storage/get/layout/{id}/sources" || e.Count != 2 {
+		t.Fatalf("http endpoint = %+v", e)
+	}
+	if len(e.Statuses) != 2 || e.Statuses[1] != 404 || len(e.QueryParams) != 1 || e.ContentTypes[0] != "application/json" {
+		t.Fatalf("http endpoint = %+v", e)
+	}
+	if got := e.Response.Flatten(); got[".sources"] != "object" || got[".error"] != "string" {
+		t.Fatalf("response schema = %v", got)
+	}
+	if len(e.Response.Required) != 0 {
+		t.Fatalf("required = %v, want none", e.Response.Required)
+	}
+
+	du, qs := c.Endpoints[1], c.Endpoints[2]
+	if du.Key != "WS du" || du.Response == nil || du.Request != nil {
+		t.Fatalf("du = %+v", du)
+	}
+	if qs.Key != "WS quote_add_symbols" || qs.Request == nil || qs.Directions[0] != "outgoing" {
+		t.Fatalf("quote_add_symbols = %+v", qs)
+	}
+
+	var md bytes.Buffer
+	if err := c.WriteMarkdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"### `charts-storage.tradingview.com`", "| GET | `/charts-storage/get/layout/{id}/sources` | 2 | 200, 404 |", "| `du` | incoming | 1 |"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ts := time.Date(2026, 2, 21, 0, 0, 0, 0, time.UTC)
+	base := NewBuilder()
+	base.AddHTTP(httpCapture(ts, "GET", "https://www.tradingview.com/api/a", 200, `{"id":1,"old":true}`))
+	base.AddHTTP(httpCapture(ts, "GET", "https://www.tradingview.com/api/gone", 200, `{}`))
+	head := NewBuilder()
+	head.AddHTTP(httpCapture(ts, "GET", "https://www.tradingview.com/api/a?v=2", 500, `{"id":"x","new":[1]}`))
+	head.AddHTTP(httpCapture(ts, "POST", "https://www.tradingview.com/api/new", 200, `{}`))
+	bc, hc := base.Catalog(), head.Catalog()
+	bc.From, bc.To, hc.From, hc.To = "2026-02-20", "2026-02-20", "2026-02-21", "2026-02-21"
+
+	d := Compare(bc, hc)
+	if len(d.Added) != 1 || d.Added[0].Key != "POST www.tradingview.com/api/new" {
+		t.Fatalf("added = %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Key != "GET www.tradingview.com/api/gone" {
+		t.Fatalf("removed = %+v", d.Removed)
+	}
+	if len(d.Changed) != 1 {
+		t.Fatalf("changed = %+v", d.Changed)
+	}
+	ch := d.Changed[0]
+	if len(ch.NewStatuses) != 1 || ch.NewStatuses[0] != 500 || len(ch.NewQueryParams) != 1 {
+		t.Fatalf("change = %+v", ch)
+	}
+	want := []FieldChange{
+		{Schema: "response", Path: ".id", Before: "integer", After: "string"},
+		{Schema: "response", Path: ".new", After: "array"},
+		{Schema: "response", Path: ".new[]", After: "integer"},
+		{Schema: "response", Path: ".old", Before: "boolean"},
+	}
+	if len(ch.Fields) != len(want) {
+		t.Fatalf("fields = %+v", ch.Fields)
+	}
+	for i := range want {
+		if ch.Fields[i] != want[i] {
+			t.Errorf("field %d = %+v, want %+v", i, ch.Fields[i], want[i])
+		}
+	}
+	if d.Base != "2026-02-20" || !Compare(hc, hc).Empty() {
+		t.Fatalf("base = %q or self-diff not empty", d.Base)
+	}
+}
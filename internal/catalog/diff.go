@@ -0,0 +1,110 @@
+package catalog
+
+import (
+	"slices"
+	"sort"
+)
+
+// Diff is the difference between a base and a head catalog.
+type Diff struct {
+	Base    string      `json:"base"`
+	Head    string      `json:"head"`
+	Added   []*Endpoint `json:"added"`
+	Removed []*Endpoint `json:"removed"`
+	Changed []Change    `json:"changed"`
+}
+
+// Change describes how an endpoint present in both catalogs differs.
+type Change struct {
+	Key            string        `json:"key"`
+	NewStatuses    []int         `json:"new_statuses,omitempty"`
+	NewQueryParams []string      `json:"new_query_params,omitempty"`
+	Fields         []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange is a schema path whose types differ. Before or After is
+// empty when the path exists on one side only.
+type FieldChange struct {
+	Schema string `json:"schema"` // "request" or "response"
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Empty reports whether the catalogs are equivalent.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Compare diffs head against base. Statuses and query parameters that
+// disappear are not reported, since a quiet day may simply not exercise
+// them; schema paths are compared both ways.
+func Compare(base, head *Catalog) *Diff {
+	d := &Diff{Base: label(base), Head: label(head), Added: []*Endpoint{}, Removed: []*Endpoint{}, Changed: []Change{}}
+	old := make(map[string]*Endpoint, len(base.Endpoints))
+	for _, e := range base.Endpoints {
+		old[e.Key] = e
+	}
+	seen := make(map[string]bool, len(head.Endpoints))
+	for _, e := range head.Endpoints {
+		seen[e.Key] = true
+		b, ok := old[e.Key]
+		if !ok {
+			d.Added = append(d.Added, e)
+			continue
+		}
+		if c, changed := compareEndpoint(b, e); changed {
+			d.Changed = append(d.Changed, c)
+		}
+	}
+	for _, e := range base.Endpoints {
+		if !seen[e.Key] {
+			d.Removed = append(d.Removed, e)
+		}
+	}
+	return d
+}
+
+func label(c *Catalog) string {
+	if c.From == c.To {
+		return c.From
+	}
+	return c.From + ".." + c.To
+}
+
+func compareEndpoint(base, head *Endpoint) (Change, bool) {
+	c := Change{Key: head.Key}
+	for _, s := range head.Statuses {
+		if !slices.Contains(base.Statuses, s) {
+			c.NewStatuses = append(c.NewStatuses, s)
+		}
+	}
+	for _, q := range head.QueryParams {
+		if !slices.Contains(base.QueryParams, q) {
+			c.NewQueryParams = append(c.NewQueryParams, q)
+		}
+	}
+	c.Fields = append(compareSchema("request", base.Request, head.Request), compareSchema("response", base.Response, head.Response)...)
+	return c, len(c.NewStatuses) > 0 || len(c.NewQueryParams) > 0 || len(c.Fields) > 0
+}
+
+func compareSchema(name string, base, head *Schema) []FieldChange {
+	// A side with no samples at all says nothing about the shape.
+	if base == nil || head == nil {
+		return nil
+	}
+	before, after := base.Flatten(), head.Flatten()
+	var out []FieldChange
+	for path, t := range after {
+		if before[path] != t {
+			out = append(out, FieldChange{Schema: name, Path: path, Before: before[path], After: t})
+		}
+	}
+	for path, t := range before {
+		if _, ok := after[path]; !ok {
+			out = append(out, FieldChange{Schema: name, Path: path, Before: t})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSummaryKeys is the number of property names shown in a schema summary.
+const maxSummaryKeys = 6
+
+// WriteMarkdown renders the catalog as tables in the style of the
+// "Deferred TradingView REST APIs" section of implementation-status.md:
+// one table per HTTP host, then one for WebSocket message types.
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Endpoint Catalog\n\n")
+	fmt.Fprintf(bw, "Captures %s, generated %s.\n", rangeText(c.From, c.To), c.GeneratedAt.Format("2006-01-02 15:04 UTC"))
+
+	host := ""
+	var ws []*Endpoint
+	for _, e := range c.Endpoints {
+		if e.Kind == "websocket" {
+			ws = append(ws, e)
+			continue
+		}
+		if e.Host != host {
+			host = e.Host
+			fmt.Fprintf(bw, "\n### `%s`\n\n", host)
+			fmt.Fprintln(bw, "| Method | Path | Count | Status | Query params | Request | Response |")
+			fmt.Fprintln(bw, "|--------|------|-------|--------|--------------|---------|----------|")
+		}
+		fmt.Fprintf(bw, "| %s | `%s` | %d | %s | %s | %s | %s |\n",
+			e.Method, e.Path, e.Count, ints(e.Statuses), codeList(e.QueryParams),
+			cell(Summary(e.Request)), cell(responseSummary(e)))
+	}
+	if len(ws) > 0 {
+		fmt.Fprintf(bw, "\n### WebSocket messages\n\n")
+		fmt.Fprintln(bw, "| Type | Direction | Count | Outgoing params | Incoming params |")
+		fmt.Fprintln(bw, "|------|-----------|-------|-----------------|-----------------|")
+		for _, e := range ws {
+			fmt.Fprintf(bw, "| `%s` | %s | %d | %s | %s |\n",
+				e.MessageType, strings.Join(e.Directions, ", "), e.Count, cell(Summary(e.Request)), cell(Summary(e.Response)))
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMarkdown renders the diff as added, removed and changed sections.
+func (d *Diff) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Catalog diff: %s → %s\n", d.Base, d.Head)
+	if d.Empty() {
+		fmt.Fprintf(bw, "\nNo changes.\n")
+		return bw.Flush()
+	}
+	for _, sec := range []struct {
+		title string
+		eps   []*Endpoint
+	}{{"Added", d.Added}, {"Removed", d.Removed}} {
+		if len(sec.eps) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n## %s (%d)\n\n", sec.title, len(sec.eps))
+		fmt.Fprintln(bw, "| Endpoint | Count | Example |")
+		fmt.Fprintln(bw, "|----------|-------|---------|")
+		for _, e := range sec.eps {
+			example := ""
+			if len(e.Examples) > 0 {
+				example = "`" + e.Examples[0] + "`"
+			}
+			fmt.Fprintf(bw, "| `%s` | %d | %s |\n", e.Key, e.Count, example)
+		}
+	}
+	if len(d.Changed) > 0 {
+		fmt.Fprintf(bw, "\n## Changed (%d)\n", len(d.Changed))
+		for _, c := range d.Changed {
+			fmt.Fprintf(bw, "\n### `%s`\n\n", c.Key)
+			if len(c.NewStatuses) > 0 {
+				fmt.Fprintf(bw, "- New statuses: %s\n", ints(c.NewStatuses))
+			}
+			if len(c.NewQueryParams) > 0 {
+				fmt.Fprintf(bw, "- New query params: %s\n", codeList(c.NewQueryParams))
+			}
+			for _, f := range c.Fields {
+				path := f.Schema + f.Path
+				switch {
+				case f.Before == "":
+					fmt.Fprintf(bw, "- Added `%s` (%s)\n", path, f.After)
+				case f.After == "":
+					fmt.Fprintf(bw, "- Removed `%s` (%s)\n", path, f.Before)
+				default:
+					fmt.Fprintf(bw, "- Changed `%s`: %s → %s\n", path, f.Before, f.After)
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Summary describes a schema in one short line, e.g.
+// "object {id, name, …}" or "array of object {symbol}".
+func Summary(s *Schema) string {
+	if s == nil || len(s.Type) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, t := range s.Type {
+		switch {
+		case t == "object" && len(s.Properties) > 0:
+			keys := make([]string, 0, len(s.Properties))
+			for k := range s.Properties {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			if len(keys) > maxSummaryKeys {
+				keys = append(keys[:maxSummaryKeys], "…")
+			}
+			parts = append(parts, "object {"+strings.Join(keys, ", ")+"}")
+		case t == "array" && s.Items != nil && len(s.Items.Type) > 0:
+			parts = append(parts, "array of "+Summary(s.Items))
+		default:
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// cell escapes the pipes in a table cell.
+func cell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func responseSummary(e *Endpoint) string {
+	if e.Response != nil {
+		return Summary(e.Response)
+	}
+	return strings.Join(e.ContentTypes, ", ")
+}
+
+func rangeText(from, to string) string {
+	switch {
+	case from == "" && to == "":
+		return "of all days"
+	case from == to:
+		return "of " + from
+	case from == "":
+		return "up to " + to
+	case to == "":
+		return "from " + from
+	}
+	return "from " + from + " to " + to
+}
+
+func ints(list []int) string {
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+func codeList(list []string) string {
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = "`" + v + "`"
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxProperties caps the properties tracked per object schema. Objects
+// keyed by IDs or symbols (e.g. series maps) would otherwise grow one
+// property per value seen.
+const maxProperties = 200
+
+// Schema is a JSON schema inferred from samples: the JSON Schema subset
+// type, properties, required and items. Type lists every JSON type seen;
+// integer is folded into number once both appear.
+type Schema struct {
+	Type       []string           `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	// Truncated is set when properties beyond maxProperties were dropped.
+	Truncated bool `json:"x-truncated,omitempty"`
+
+	objects int
+	keys    map[string]int
+}
+
+// AddJSON parses data and merges it into the schema. It reports false if
+// data is not valid JSON.
+func (s *Schema) AddJSON(data []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return false
+	}
+	s.Add(v)
+	return true
+}
+
+// Add merges a decoded JSON value (decoded with UseNumber) into the schema.
+func (s *Schema) Add(v any) {
+	switch v := v.(type) {
+	case nil:
+		s.addType("null")
+	case bool:
+		s.addType("boolean")
+	case string:
+		s.addType("string")
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			s.addType("number")
+		} else {
+			s.addType("integer")
+		}
+	case float64:
+		s.addType("number")
+	case []any:
+		s.addType("array")
+		if s.Items == nil {
+			s.Items = &Schema{}
+		}
+		for _, item := range v {
+			s.Items.Add(item)
+		}
+	case map[string]any:
+		s.addType("object")
+		s.objects++
+		if s.Properties == nil {
+			s.Properties = make(map[string]*Schema)
+			s.keys = make(map[string]int)
+		}
+		for k, val := range v {
+			p, ok := s.Properties[k]
+			if !ok {
+				if len(s.Properties) >= maxProperties {
+					s.Truncated = true
+					continue
+				}
+				p = &Schema{}
+				s.Properties[k] = p
+			}
+			s.keys[k]++
+			p.Add(val)
+		}
+	}
+}
+
+func (s *Schema) addType(t string) {
+	if slices.Contains(s.Type, t) {
+		return
+	}
+	switch {
+	case t == "integer" && slices.Contains(s.Type, "number"):
+		return
+	case t == "number":
+		s.Type = slices.DeleteFunc(s.Type, func(x string) bool { return x == "integer" })
+	}
+	s.Type = append(s.Type, t)
+	sort.Strings(s.Type)
+}
+
+// finish fills Required from the samples seen so far.
+func (s *Schema) finish() {
+	if s == nil {
+		return
+	}
+	if s.objects > 0 {
+		s.Required = s.required()
+	}
+	for _, p := range s.Properties {
+		p.finish()
+	}
+	s.Items.finish()
+}
+
+// required lists the properties present in every object sample.
+func (s *Schema) required() []string {
+	var out []string
+	for k, n := range s.keys {
+		if n == s.objects {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Flatten maps each path in the schema to its "|"-joined types, e.g.
+// {"": "object", ".data": "array", ".data[].id": "integer|string"}.
+func (s *Schema) Flatten() map[string]string {
+	out := make(map[string]string)
+	if s != nil {
+		s.flatten("", out)
+	}
+	return out
+}
+
+func (s *Schema) flatten(path string, out map[string]string) {
+	out[path] = strings.Join(s.Type, "|")
+	for k, p := range s.Properties {
+		p.flatten(path+"."+k, out)
+	}
+	if s.Items != nil && len(s.Items.Type) > 0 {
+		s.Items.flatten(path+"[]", out)
+	}
+}
@@ -0,0 +1,48 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaInference(t *testing.T) {
+	var s Schema
+	for _, doc := range []string{
+		`{"id": 1, "name": "a", "tags": ["x"], "price": 1}`,
+		`{"id": 2, "tags": [], "price": 1.5, "extra": null}`,
+	} {
+		if !s.AddJSON([]byte(doc)) {
+			t.Fatalf("AddJSON(%s) = false", doc)
+		}
+	}
+	if s.AddJSON([]byte(`{"a":1} trailing`)) {
+		t.Fatal("AddJSON accepted trailing data")
+	}
+	s.finish()
+
+	want := map[string]string{
+		"":        "object",
+		".id":     "integer",
+		".name":   "string",
+		".tags":   "array",
+		".tags[]": "string",
+		".price":  "number",
+		".extra":  "null",
+	}
+	if got := s.Flatten(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Flatten() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(s.Required, []string{"id", "price", "tags"}) {
+		t.Fatalf("Required = %v", s.Required)
+	}
+}
+
+func TestSchemaCapsProperties(t *testing.T) {
+	var s Schema
+	for i := 0; i < maxProperties+10; i++ {
+		s.Add(map[string]any{string(rune('a'+i%26)) + string(rune('0'+i/26)): true})
+	}
+	if len(s.Properties) != maxProperties || !s.Truncated {
+		t.Fatalf("properties = %d, truncated = %v", len(s.Properties), s.Truncated)
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/capture"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/config"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
 )
 
 // Client manages CDP connections to browser tabs.
@@ -22,11 +25,20 @@ type Client struct {
 	httpCapture *capture.HTTPCapture
 	wsCapture   *capture.WebSocketCapture
 	tabRegistry *TabRegistry
+
+	// connMu guards the connection contexts, which Connect replaces when
+	// the supervisor reconnects.
+	connMu      sync.Mutex
 	allocCtx    context.Context
 	allocCancel context.CancelFunc
-	tabs        map[target.ID]*TabContext
-	tabsMu      sync.RWMutex
-	done        chan struct{}
+	// browserCtx holds the browser-level connection used for target
+	// discovery; it is not attached to any tab.
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+
+	tabs   map[target.ID]*TabContext
+	tabsMu sync.RWMutex
+	done   chan struct{}
 }
 
 type TabContext struct {
@@ -47,23 +59,25 @@ func NewClient(cfg *config.Config, httpCapture *capture.HTTPCapture, wsCapture *
 	}
 }
 
+// Connect attaches to the open tabs matching RESEARCHER_TAB_URL_FILTER and
+// then follows the browser: tabs opened or navigated to a matching URL later
+// are attached, and closed tabs are detached.
 func (c *Client) Connect(ctx context.Context) error {
 	_ = ctx
 	cdpURL := c.cfg.GetCDPURL()
 	slog.Info("Connecting to Chromium", "url", cdpURL)
 
-	c.allocCtx, c.allocCancel = chromedp.NewRemoteAllocator(context.Background(), cdpURL)
-
-	tempCtx, tempCancel := chromedp.NewContext(c.allocCtx)
-	defer tempCancel()
-
-	if err := chromedp.Run(tempCtx); err != nil {
-		return fmt.Errorf("failed to connect to browser: %w", err)
-	}
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(context.Background(), cdpURL)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	c.connMu.Lock()
+	c.allocCtx, c.allocCancel = allocCtx, allocCancel
+	c.browserCtx, c.browserCancel = browserCtx, browserCancel
+	c.connMu.Unlock()
+	chromedp.ListenBrowser(browserCtx, c.onTargetEvent)
 
-	targets, err := chromedp.Targets(tempCtx)
+	targets, err := chromedp.Targets(browserCtx)
 	if err != nil {
-		return fmt.Errorf("failed to enumerate targets: %w", err)
+		return fmt.Errorf("failed to connect to browser: %w", err)
 	}
 
 	slog.Info("Found browser targets", "count", len(targets))
@@ -84,29 +98,121 @@ func (c *Client) Connect(ctx context.Context) error {
 		attachedCount++
 	}
 
+	browserExec := cdp.WithExecutor(browserCtx, chromedp.FromContext(browserCtx).Browser)
+	if err := target.SetDiscoverTargets(true).Do(browserExec); err != nil {
+		return fmt.Errorf("failed to enable target discovery: %w", err)
+	}
+
 	if attachedCount == 0 {
-		return fmt.Errorf("no tabs found matching RESEARCHER_TAB_URL_FILTER=%q", c.cfg.TabURLFilter)
+		slog.Warn("No tabs match yet; waiting for one to open", "tab_url_filter", c.cfg.TabURLFilter)
+		return nil
 	}
 
 	slog.Info("Attached to tabs", "count", attachedCount, "tab_url_filter", c.cfg.TabURLFilter)
 	return nil
 }
 
-func (c *Client) attachToTab(targetID target.ID, url string) error {
-	tabInfo, err := c.tabRegistry.Register(targetID, url)
-	if err != nil {
-		return fmt.Errorf("failed to register tab: %w", err)
+// onTargetEvent handles browser-level target discovery events. It runs on
+// chromedp's read loop, so attaching and detaching happen in goroutines.
+func (c *Client) onTargetEvent(ev interface{}) {
+	switch e := ev.(type) {
+	case *target.EventTargetCreated:
+		c.onTargetInfo(e.TargetInfo)
+	case *target.EventTargetInfoChanged:
+		c.onTargetInfo(e.TargetInfo)
+	case *target.EventTargetDestroyed:
+		go c.detachFromTab(e.TargetID, "closed")
+	case *target.EventTargetCrashed:
+		go c.detachFromTab(e.TargetID, "crashed")
+	}
+}
+
+func (c *Client) onTargetInfo(info *target.Info) {
+	if info.Type != "page" || c.closing() {
+		return
+	}
+	c.tabsMu.Lock()
+	tab, attached := c.tabs[info.TargetID]
+	if attached {
+		tab.URL = info.URL
+	}
+	c.tabsMu.Unlock()
+
+	if attached {
+		// Frame navigation events update the registry too; this also covers
+		// tabs whose page domain has not reported yet.
+		if cur, ok := c.tabRegistry.Get(info.TargetID); ok && cur.URL != info.URL {
+			if _, err := c.tabRegistry.Register(info.TargetID, info.URL); err != nil {
+				slog.Debug("Failed to update tab registry", "target_id", info.TargetID, "error", err)
+			}
+		}
+		return
+	}
+	// New tabs start at about:blank and report their real URL in a later
+	// TargetInfoChanged, so non-matching tabs are re-checked on every change.
+	if !c.matchesTabURL(info.URL) {
+		return
+	}
+	go func() {
+		if err := c.attachToTab(info.TargetID, info.URL); err != nil {
+			slog.Error("Failed to attach to new tab", "target_id", info.TargetID, "url", truncateURL(info.URL), "error", err)
+		}
+	}()
+}
+
+// detachFromTab stops capturing a tab that went away.
+func (c *Client) detachFromTab(targetID target.ID, reason string) {
+	c.tabsMu.Lock()
+	tab, ok := c.tabs[targetID]
+	delete(c.tabs, targetID)
+	c.tabsMu.Unlock()
+	if !ok {
+		return
 	}
+	c.tabRegistry.Remove(targetID)
+	c.wsCapture.DropTab(string(targetID))
+	tab.cancel()
+	slog.Info("Detached from tab", "target_id", targetID, "reason", reason, "url", truncateURL(tab.URL))
+}
+
+func (c *Client) closing() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
 
-	tabCtx, tabCancel := chromedp.NewContext(c.allocCtx, chromedp.WithTargetID(targetID))
+// attachToTab starts capturing a tab. It is a no-op if the tab is already
+// attached or being attached.
+func (c *Client) attachToTab(targetID target.ID, url string) error {
+	c.connMu.Lock()
+	allocCtx := c.allocCtx
+	c.connMu.Unlock()
+	if allocCtx == nil || allocCtx.Err() != nil {
+		return fmt.Errorf("not connected")
+	}
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx, chromedp.WithTargetID(targetID))
 	tab := &TabContext{ID: targetID, URL: url, ctx: tabCtx, cancel: tabCancel}
 
 	c.tabsMu.Lock()
+	if _, ok := c.tabs[targetID]; ok {
+		c.tabsMu.Unlock()
+		tabCancel()
+		return nil
+	}
 	c.tabs[targetID] = tab
 	c.tabsMu.Unlock()
 
+	tabInfo, err := c.tabRegistry.Register(targetID, url)
+	if err != nil {
+		c.dropTab(tab)
+		return fmt.Errorf("failed to register tab: %w", err)
+	}
+
 	if err := chromedp.Run(tabCtx, network.Enable(), network.SetCacheDisabled(true), page.Enable()); err != nil {
-		tabCancel()
+		c.dropTab(tab)
 		c.tabRegistry.Remove(targetID)
 		return fmt.Errorf("failed to enable network/page domains: %w", err)
 	}
@@ -127,6 +233,16 @@ func (c *Client) attachToTab(targetID target.ID, url string) error {
 	return nil
 }
 
+// dropTab forgets a tab whose attach failed, unless it was replaced.
+func (c *Client) dropTab(tab *TabContext) {
+	c.tabsMu.Lock()
+	if c.tabs[tab.ID] == tab {
+		delete(c.tabs, tab.ID)
+	}
+	c.tabsMu.Unlock()
+	tab.cancel()
+}
+
 func (c *Client) createEventHandler(tabID string) func(ev interface{}) {
 	return func(ev interface{}) {
 		switch e := ev.(type) {
@@ -182,6 +298,11 @@ func (c *Client) createEventHandler(tabID string) func(ev interface{}) {
 
 func (c *Client) Close() error {
 	close(c.done)
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.browserCancel != nil {
+		c.browserCancel()
+	}
 
 	c.tabsMu.Lock()
 	defer c.tabsMu.Unlock()
@@ -195,6 +316,33 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Connected reports whether the client holds a browser connection. It is
+// false while the supervisor is reconnecting.
+func (c *Client) Connected() bool {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.browserCtx != nil && c.browserCtx.Err() == nil
+}
+
+// Tabs returns the attached tabs ordered by target ID.
+func (c *Client) Tabs() []types.TabInfo {
+	c.tabsMu.RLock()
+	ids := make([]target.ID, 0, len(c.tabs))
+	for id := range c.tabs {
+		ids = append(ids, id)
+	}
+	c.tabsMu.RUnlock()
+
+	out := make([]types.TabInfo, 0, len(ids))
+	for _, id := range ids {
+		if info, ok := c.tabRegistry.Get(id); ok {
+			out = append(out, *info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TargetID < out[j].TargetID })
+	return out
+}
+
 func (c *Client) GetTabCount() int {
 	c.tabsMu.RLock()
 	defer c.tabsMu.RUnlock()
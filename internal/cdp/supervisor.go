@@ -0,0 +1,139 @@
+package cdp
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+const initialReconnectBackoff = time.Second
+
+// Supervise keeps the client connected until ctx is done or the client is
+// closed. When the browser connection drops (Chromium crashed or was
+// restarted) it forgets every tab, reconnects to the same CDP endpoint with
+// exponential backoff and re-attaches to the matching tabs, then writes a
+// capture_gap marker to the streams of each tab that was lost. Call it
+// after a successful Connect.
+func (c *Client) Supervise(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-c.lostConnection():
+		}
+		if c.closing() {
+			return
+		}
+
+		start := time.Now().UTC()
+		lost := c.disconnect()
+		slog.Warn("Browser connection lost; reconnecting", "url", c.cfg.GetCDPURL(), "tabs", len(lost))
+
+		attempts, ok := c.reconnect(ctx)
+		if !ok {
+			return
+		}
+		end := time.Now().UTC()
+		slog.Info("Reconnected to browser", "attempts", attempts, "gap", end.Sub(start).Round(time.Millisecond), "tabs", c.GetTabCount())
+
+		for _, tab := range lost {
+			gap := types.CaptureGap{
+				Timestamp: end,
+				EventType: types.EventCaptureGap,
+				TabID:     tab.TargetID,
+				URL:       tab.URL,
+				GapStart:  start,
+				GapEnd:    end,
+				Reason:    types.GapReasonDisconnected,
+				Attempts:  attempts,
+			}
+			c.httpCapture.WriteGap(tab, gap)
+			c.wsCapture.WriteGap(tab, gap)
+		}
+	}
+}
+
+// lostConnection returns a channel closed when the browser-level websocket
+// drops, or nil (blocking forever) when there is no connection.
+func (c *Client) lostConnection() <-chan struct{} {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.browserCtx == nil {
+		return nil
+	}
+	if cc := chromedp.FromContext(c.browserCtx); cc != nil && cc.Browser != nil {
+		return cc.Browser.LostConnection
+	}
+	return nil
+}
+
+// reconnect calls Connect until it succeeds, doubling the wait between
+// attempts up to RESEARCHER_RECONNECT_MAX_BACKOFF_MS. It returns the number
+// of attempts, and false if ctx ended or the client was closed first.
+func (c *Client) reconnect(ctx context.Context) (int, bool) {
+	backoff := initialReconnectBackoff
+	maxBackoff := time.Duration(c.cfg.ReconnectMaxBackoffMS) * time.Millisecond
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt - 1, false
+		case <-c.done:
+			timer.Stop()
+			return attempt - 1, false
+		case <-timer.C:
+		}
+		err := c.Connect(ctx)
+		if err == nil {
+			return attempt, true
+		}
+		c.disconnect()
+		backoff = min(backoff*2, maxBackoff)
+		slog.Warn("Reconnect failed", "attempt", attempt, "retry_in", backoff, "error", err)
+	}
+}
+
+// disconnect drops the browser connection and forgets every attached tab,
+// returning the tabs that were attached ordered by target ID.
+func (c *Client) disconnect() []types.TabInfo {
+	c.connMu.Lock()
+	browserCancel, allocCancel := c.browserCancel, c.allocCancel
+	c.allocCtx, c.allocCancel = nil, nil
+	c.browserCtx, c.browserCancel = nil, nil
+	c.connMu.Unlock()
+
+	c.tabsMu.Lock()
+	tabs := c.tabs
+	c.tabs = make(map[target.ID]*TabContext)
+	c.tabsMu.Unlock()
+
+	lost := make([]types.TabInfo, 0, len(tabs))
+	for id, tab := range tabs {
+		if info, ok := c.tabRegistry.Get(id); ok {
+			lost = append(lost, *info)
+		}
+		c.tabRegistry.Remove(id)
+		c.wsCapture.DropTab(string(id))
+		go tab.cancel()
+	}
+	sort.Slice(lost, func(i, j int) bool { return lost[i].TargetID < lost[j].TargetID })
+
+	if browserCancel != nil {
+		browserCancel()
+	}
+	if allocCancel != nil {
+		allocCancel()
+	}
+	return lost
+}
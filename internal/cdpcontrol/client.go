@@ -72,6 +72,12 @@ type Client struct {
 
 	chartLocksMu sync.Mutex
 	chartLocks   map[string]*sync.Mutex
+
+	// sessionCharts maps attached CDP session IDs to chart IDs. It has its own
+	// lock because it is read from CDP event handlers, which run on the read
+	// loop and must never wait on c.mu or a tabSession lock.
+	sessionChartsMu sync.RWMutex
+	sessionCharts   map[string]string
 }
 
 type evalEnvelope struct {
@@ -89,6 +95,7 @@ func NewClient(cdpURL, tabFilter string, evalTimeout time.Duration) *Client {
 		tabs:          make(map[target.ID]*tabSession),
 		chartToTarget: make(map[string]target.ID),
 		chartLocks:    make(map[string]*sync.Mutex),
+		sessionCharts: make(map[string]string),
 	}
 }
 
@@ -152,6 +159,10 @@ func (c *Client) cleanupLocked() {
 	}
 	c.tabs = make(map[target.ID]*tabSession)
 	c.chartToTarget = make(map[string]target.ID)
+
+	c.sessionChartsMu.Lock()
+	c.sessionCharts = make(map[string]string)
+	c.sessionChartsMu.Unlock()
 }
 
 func (c *Client) ListCharts(ctx context.Context) ([]ChartInfo, error) {
@@ -1874,6 +1885,9 @@ func (c *Client) ensureSession(ctx context.Context, cdp *rawCDP, session *tabSes
 		return "", newError(CodeCDPUnavailable, "attach to target failed", err)
 	}
 	session.sessionID = sid
+	c.sessionChartsMu.Lock()
+	c.sessionCharts[sid] = session.info.ChartID
+	c.sessionChartsMu.Unlock()
 	slog.Debug("cdpcontrol session attached", "target_id", targetID, "session_id", sid)
 	return sid, nil
 }
@@ -2551,19 +2565,75 @@ func (c *Client) ProbeDataWindow(ctx context.Context, chartID string) (DataWindo
 // --- Export methods ---
 
 func (c *Client) ExportChartData(ctx context.Context, chartID string) (ChartExportResult, error) {
-	var out ChartExportResult
-	if err := c.evalOnChart(ctx, chartID, jsExportChartData(), &out); err != nil {
+	var raw struct {
+		ChartExportResult
+		Entities []studyEntity `json:"entities"`
+	}
+	if err := c.evalOnChart(ctx, chartID, jsExportChartData(), &raw); err != nil {
 		return ChartExportResult{}, err
 	}
+	out := raw.ChartExportResult
+	linkStudyEntities(out.Studies, raw.Entities)
 	if out.Columns == nil {
 		out.Columns = []ExportSchemaColumn{}
 	}
+	if out.Studies == nil {
+		out.Studies = []ExportStudy{}
+	}
 	if out.Bars == nil {
 		out.Bars = [][]any{}
 	}
 	return out, nil
 }
 
+// studyEntity is a study as chart.getAllStudies() lists it, with the ID of
+// the data source behind it when the page exposes one.
+type studyEntity struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	SourceID string         `json:"source_id"`
+	Inputs   map[string]any `json:"inputs"`
+}
+
+// linkStudyEntities sets the entity ID and inputs of each exported study
+// from the entity wrapping its data source, else the entity with the same
+// ID, else the only unclaimed entity with the same name. Studies left
+// unmatched keep an empty entity ID.
+func linkStudyEntities(studies []ExportStudy, entities []studyEntity) {
+	claimed := make([]bool, len(entities))
+	for _, match := range []func(st ExportStudy, e studyEntity) bool{
+		func(st ExportStudy, e studyEntity) bool { return e.SourceID != "" && e.SourceID == st.SourceID },
+		func(st ExportStudy, e studyEntity) bool { return e.ID == st.SourceID },
+		func(st ExportStudy, e studyEntity) bool { return e.Name != "" && strings.EqualFold(e.Name, st.Name) },
+	} {
+		for i := range studies {
+			if studies[i].EntityID != "" {
+				continue
+			}
+			found := -1
+			for j, e := range entities {
+				if claimed[j] || !match(studies[i], e) {
+					continue
+				}
+				if found >= 0 {
+					found = -1 // ambiguous
+					break
+				}
+				found = j
+			}
+			if found >= 0 {
+				claimed[found] = true
+				studies[i].EntityID, studies[i].Inputs = entities[found].ID, entities[found].Inputs
+			}
+		}
+	}
+	for i := range studies {
+		if studies[i].Inputs == nil {
+			studies[i].Inputs = map[string]any{}
+		}
+	}
+}
+
 // EnableNetworkDomain enables the Network CDP domain on the first available
 // chart tab session so that Network.* events are emitted.
 func (c *Client) EnableNetworkDomain(ctx context.Context) error {
@@ -2575,6 +2645,14 @@ func (c *Client) EnableNetworkDomain(ctx context.Context) error {
 	return err
 }
 
+// ChartIDForSession returns the chart ID of the tab attached on the given CDP
+// session, or "" if the session is unknown. Safe to call from CDP event handlers.
+func (c *Client) ChartIDForSession(sessionID string) string {
+	c.sessionChartsMu.RLock()
+	defer c.sessionChartsMu.RUnlock()
+	return c.sessionCharts[sessionID]
+}
+
 // RegisterCDPEventHandler registers a handler for a CDP event method (e.g.
 // "Network.webSocketCreated"). Returns an unregister function.
 // The caller must have called Connect first.
@@ -0,0 +1,53 @@
+package cdpcontrol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLinkStudyEntities(t *testing.T) {
+	// Entity IDs from chart.getAllStudies() differ from the schema's data
+	// source IDs; only some entities expose the source they wrap.
+	var raw struct {
+		ChartExportResult
+		Entities []studyEntity `json:"entities"`
+	}
+	fixture := `{
+		"studies": [
+			{"source_id": "Xy12ab", "name": "Relative Strength Index", "pane_index": 1},
+			{"source_id": "Pq34cd", "name": "Moving Average Exponential", "pane_index": 0},
+			{"source_id": "Vv56ef", "name": "Moving Average", "pane_index": 0},
+			{"source_id": "Zz78gh", "name": "Bollinger Bands", "pane_index": 0}
+		],
+		"entities": [
+			{"id": "ent_EMA", "name": "Moving Average Exponential", "source_id": "", "inputs": {"length": 50}},
+			{"id": "ent_RSI", "name": "Relative Strength Index", "source_id": "Xy12ab", "inputs": {"length": 14}},
+			{"id": "ent_MA1", "name": "Moving Average", "source_id": "", "inputs": {}},
+			{"id": "ent_MA2", "name": "Moving Average", "source_id": "", "inputs": {}},
+			{"id": "Zz78gh", "name": "BB", "source_id": "", "inputs": {"mult": 2}}
+		]
+	}`
+	if err := json.Unmarshal([]byte(fixture), &raw); err != nil {
+		t.Fatal(err)
+	}
+	studies := raw.Studies
+	linkStudyEntities(studies, raw.Entities)
+
+	want := map[string]string{
+		"Xy12ab": "ent_RSI", // by wrapped source
+		"Pq34cd": "ent_EMA", // by name
+		"Vv56ef": "",        // two entities share the name
+		"Zz78gh": "Zz78gh",  // entity ID equals source ID
+	}
+	for _, st := range studies {
+		if st.EntityID != want[st.SourceID] {
+			t.Errorf("%s: entity_id = %q, want %q", st.SourceID, st.EntityID, want[st.SourceID])
+		}
+		if st.Inputs == nil {
+			t.Errorf("%s: nil inputs", st.SourceID)
+		}
+	}
+	if studies[0].Inputs["length"] != 14.0 || studies[1].Inputs["length"] != 50.0 {
+		t.Fatalf("inputs = %v, %v", studies[0].Inputs, studies[1].Inputs)
+	}
+}
@@ -89,12 +89,70 @@ try {
     resolution = String(props.childs().interval.value());
 } catch(_) {}
 
+// Describe each study source in the schema (name, pane) and list the chart's
+// study entities with the data source each wraps and its inputs; the Go side
+// links sources to entities, whose IDs need not equal the source IDs.
+var studies = [];
+var seenSources = {};
+var panes = [];
+try { panes = innerModel.panes ? innerModel.panes() : []; } catch(_) {}
+for (var i = 0; i < schema.length; i++) {
+  var sid = schema[i].source_id;
+  var stype = String(schema[i].source_type).toLowerCase();
+  if (!sid || seenSources[sid] || stype === "series" || stype === "mainseries") continue;
+  seenSources[sid] = true;
+  var entry = {source_id: sid, name: schema[i].source_title, pane_index: -1};
+  for (var p = 0; p < panes.length && entry.pane_index < 0; p++) {
+    var sources = [];
+    try { sources = panes[p].dataSources ? panes[p].dataSources() : []; } catch(_) {}
+    for (var k = 0; k < sources.length; k++) {
+      var src = sources[k];
+      var matched = false;
+      try {
+        if (src && typeof src.id === "function" && String(src.id()) === sid) {
+          matched = true;
+          entry.pane_index = p;
+          var mi = src.metaInfo ? src.metaInfo() : null;
+          if (mi) entry.name = String(mi.description || mi.shortDescription || entry.name);
+        }
+      } catch(_) {}
+      if (matched) break;
+    }
+  }
+  studies.push(entry);
+}
+
+var entities = [];
+var allStudies = [];
+try { allStudies = chart && typeof chart.getAllStudies === "function" ? (chart.getAllStudies() || []) : []; } catch(_) {}
+for (var i = 0; i < allStudies.length; i++) {
+  var it = allStudies[i] || {};
+  var ent = {id: String(it.id || it.entityId || ""), name: String(it.name || it.title || ""), source_id: "", inputs: {}};
+  if (!ent.id) continue;
+  var study = null;
+  try { study = typeof chart.getStudyById === "function" ? chart.getStudyById(ent.id) : null; } catch(_) {}
+  try {
+    var inner = study ? (study._study || study._source || study._dataSource || null) : null;
+    if (inner && typeof inner.id === "function") ent.source_id = String(inner.id());
+  } catch(_) {}
+  try {
+    var raw = study && typeof study.getInputValues === "function" ? (study.getInputValues() || []) : [];
+    for (var k = 0; k < raw.length; k++) {
+      var item = raw[k] || {};
+      ent.inputs[String(item.id || item.name || ("input_" + k))] = item.value !== undefined ? item.value : null;
+    }
+  } catch(_) {}
+  entities.push(ent);
+}
+
 return JSON.stringify({ok:true,data:{
   symbol: symbol,
   resolution: resolution,
   bar_count: bars.length,
   time_col_idx: timeColIdx,
   schema: schema,
+  studies: studies,
+  entities: entities,
   bars: bars
 }});
 `)
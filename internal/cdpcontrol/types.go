@@ -11,6 +11,8 @@ const (
 	CodeCDPUnavailable    = "CDP_UNAVAILABLE"
 	CodeSnapshotNotFound  = "SNAPSHOT_NOT_FOUND"
 	CodeNoteNotFound      = "NOTE_NOT_FOUND"
+	CodeJobNotFound       = "JOB_NOT_FOUND"
+	CodeConflict          = "CONFLICT"
 )
 
 // CodedError is a typed error used for stable API mapping.
@@ -710,9 +712,20 @@ type ChartExportResult struct {
 	BarCount   int                  `json:"bar_count"`
 	TimeColIdx int                  `json:"time_col_idx"`
 	Columns    []ExportSchemaColumn `json:"schema"`
+	Studies    []ExportStudy        `json:"studies"`
 	Bars       [][]any              `json:"bars"`
 }
 
+// ExportStudy links a study source in an export schema (source_id) to the
+// study entity, the chart pane it is drawn in and its current input values.
+type ExportStudy struct {
+	SourceID  string         `json:"source_id"`
+	EntityID  string         `json:"entity_id" doc:"Study entity ID as listed by GET /studies; empty if it could not be linked"`
+	Name      string         `json:"name"`
+	PaneIndex int            `json:"pane_index" doc:"Index of the pane within the chart (0 = main price pane); -1 if unknown"`
+	Inputs    map[string]any `json:"inputs"`
+}
+
 // DataWindowProbe describes what's discoverable about the data window / crosshair state.
 type DataWindowProbe struct {
 	PanelVisible     bool           `json:"panel_visible"`
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -33,6 +34,25 @@ type Config struct {
 	HTTPMaxBodyBytes int
 	WSMaxFrameBytes  int
 	ResourceMaxBytes int
+
+	// Redaction rules applied before captures are written
+	RedactConfigPath string
+
+	// Upper bound on the wait between reconnect attempts after the browser
+	// connection drops
+	ReconnectMaxBackoffMS int
+
+	// Local status and control API
+	APIEnabled bool
+	APIAddr    string
+
+	// Retention of the data directory; zero ages and quota keep everything
+	RetentionIntervalMin int
+	DiskQuotaMB          int
+	MaxAgeHTTPDays       int
+	MaxAgeWSDays         int
+	MaxAgeResourcesDays  int
+	Compression          string
 }
 
 // Load reads configuration from environment variables and optional .env file.
@@ -55,6 +75,29 @@ func Load() (*Config, error) {
 		HTTPMaxBodyBytes: getEnvIntOrDefault("RESEARCHER_HTTP_MAX_BODY_BYTES", 50*1024*1024),
 		WSMaxFrameBytes:  getEnvIntOrDefault("RESEARCHER_WS_MAX_FRAME_BYTES", 20*1024*1024),
 		ResourceMaxBytes: getEnvIntOrDefault("RESEARCHER_RESOURCE_MAX_BYTES", 100*1024*1024),
+		RedactConfigPath: getEnvOrDefault("RESEARCHER_REDACT_CONFIG", "./config/redact.yaml"),
+
+		ReconnectMaxBackoffMS: getEnvIntOrDefault("RESEARCHER_RECONNECT_MAX_BACKOFF_MS", 30000),
+
+		APIEnabled: getEnvBoolOrDefault("RESEARCHER_API_ENABLED", true),
+		APIAddr:    getEnvOrDefault("RESEARCHER_API_ADDR", "127.0.0.1:8189"),
+
+		RetentionIntervalMin: getEnvIntOrDefault("RESEARCHER_RETENTION_INTERVAL_MIN", 60),
+		DiskQuotaMB:          getEnvIntOrDefault("RESEARCHER_DISK_QUOTA_MB", 0),
+		MaxAgeHTTPDays:       getEnvIntOrDefault("RESEARCHER_MAX_AGE_HTTP_DAYS", 0),
+		MaxAgeWSDays:         getEnvIntOrDefault("RESEARCHER_MAX_AGE_WS_DAYS", 0),
+		MaxAgeResourcesDays:  getEnvIntOrDefault("RESEARCHER_MAX_AGE_RESOURCES_DAYS", 0),
+		Compression:          getEnvOrDefault("RESEARCHER_COMPRESSION", "none"),
+	}
+	// An explicitly empty RESEARCHER_REDACT_CONFIG turns redaction off.
+	if v, ok := os.LookupEnv("RESEARCHER_REDACT_CONFIG"); ok {
+		cfg.RedactConfigPath = strings.TrimSpace(v)
+	}
+
+	switch cfg.Compression {
+	case "gzip", "zstd", "none":
+	default:
+		return nil, fmt.Errorf("RESEARCHER_COMPRESSION must be gzip, zstd or none, got %q", cfg.Compression)
 	}
 
 	return cfg, nil
@@ -3,24 +3,62 @@ package controller
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/export"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/jobs"
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/snapshot"
 	"github.com/google/uuid"
 )
 
 // Service wraps active TradingView control operations.
 type Service struct {
-	cdp   *cdpcontrol.Client
-	snaps *snapshot.Store
+	cdp      *cdpcontrol.Client
+	snaps    *snapshot.Store
+	jobs     *jobs.Manager
+	jobStore *jobs.Store
+	loads    export.LoadWatcher
+}
+
+// NewService creates a Service. Batch export and backfill jobs are
+// persisted in jobStore, backfill results included, and those saved by a
+// previous run are restored (as interrupted if they were still running) so
+// they can be listed, downloaded and, for batches, resumed.
+func NewService(cdp *cdpcontrol.Client, snaps *snapshot.Store, jobStore *jobs.Store) *Service {
+	s := &Service{cdp: cdp, snaps: snaps, jobs: jobs.NewManager(), jobStore: jobStore}
+	if jobStore != nil {
+		s.restoreBatchExports()
+		s.restoreBackfills()
+		s.jobs.Observe(func(j jobs.Job) {
+			if j.Kind != batchExportJobKind && j.Kind != backfillJobKind {
+				return
+			}
+			if err := jobStore.Save(j); err != nil {
+				slog.Warn("failed to save job", "id", j.ID, "kind", j.Kind, "error", err)
+			}
+		})
+	}
+	return s
 }
 
-func NewService(cdp *cdpcontrol.Client, snaps *snapshot.Store) *Service {
-	return &Service{cdp: cdp, snaps: snaps}
+// SetLoadWatcher lets backfills page on the relay's series_completed
+// messages instead of waiting out each page's settle timeout. Call it
+// before serving requests.
+func (s *Service) SetLoadWatcher(w export.LoadWatcher) {
+	s.loads = w
+}
+
+// Close cancels background jobs and waits for them to stop.
+func (s *Service) Close() {
+	s.jobs.Close()
 }
 
 func (s *Service) requireNonEmpty(value, fieldName string) error {
@@ -1496,6 +1534,301 @@ func (s *Service) ExportChartData(ctx context.Context, chartID string, pane int)
 	return s.cdp.ExportChartData(ctx, strings.TrimSpace(chartID))
 }
 
+// backfillJobKind is the jobs.Manager kind of chart backfill jobs, which are
+// keyed by chart ID so only one job (backfill or batch export) drives a
+// chart at a time.
+const backfillJobKind = "chart_backfill"
+
+// StartBackfill starts a background job that pages the chart backwards until
+// bars back to from (Unix seconds) are loaded or maxBars are stitched.
+func (s *Service) StartBackfill(ctx context.Context, chartID string, pane int, from int64, maxBars int) (jobs.Job, error) {
+	chartID = strings.TrimSpace(chartID)
+	if from < 0 || maxBars < 0 {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "from and max_bars must not be negative"}
+	}
+	if from == 0 && maxBars == 0 {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "from or max_bars is required"}
+	}
+	if from > time.Now().Unix() {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "from must be in the past"}
+	}
+	opts := export.BackfillOptions{MaxBars: maxBars, Loads: s.loads}
+	if from > 0 {
+		opts.From = time.Unix(from, 0)
+	}
+	initial := export.BackfillProgress{From: from, MaxBars: maxBars}
+	job, err := s.jobs.Start(backfillJobKind, chartID, nil, initial, func(ctx context.Context, report func(any)) (any, error) {
+		// The pane switches only once the chart's lock is held, so a
+		// rejected backfill leaves the chart as it was.
+		if err := s.ensurePane(ctx, pane); err != nil {
+			return nil, err
+		}
+		res, err := export.Backfill(ctx, s.cdp, chartID, opts, func(p export.BackfillProgress) { report(p) })
+		if err != nil {
+			slog.Warn("chart backfill stopped", "chart_id", chartID, "bars", len(res.Bars), "error", err)
+		}
+		if s.jobStore == nil {
+			return res, err
+		}
+		// The stitched bars go to the job's directory rather than staying
+		// in memory for the life of the process.
+		if serr := s.jobStore.SaveResult(jobs.IDFromContext(ctx), res); serr != nil {
+			slog.Warn("failed to save backfill result; keeping it in memory", "chart_id", chartID, "error", serr)
+			return res, err
+		}
+		return nil, err
+	})
+	if errors.Is(err, jobs.ErrBusy) {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeConflict, Message: "a backfill or batch export is already running on chart " + chartID}
+	}
+	return job, err
+}
+
+// ListBackfills returns the chart's backfill jobs, newest first.
+func (s *Service) ListBackfills(ctx context.Context, chartID string) ([]jobs.Job, error) {
+	chartID = strings.TrimSpace(chartID)
+	out := []jobs.Job{}
+	for _, j := range s.jobs.List(backfillJobKind) {
+		if j.Key == chartID {
+			out = append(out, j)
+		}
+	}
+	return out, nil
+}
+
+func (s *Service) GetBackfill(ctx context.Context, chartID, jobID string) (jobs.Job, error) {
+	job, _, err := s.backfillJob(chartID, jobID)
+	return job, err
+}
+
+// CancelBackfill stops a running backfill; the bars stitched so far remain
+// available from BackfillResult.
+func (s *Service) CancelBackfill(ctx context.Context, chartID, jobID string) (jobs.Job, error) {
+	if _, _, err := s.backfillJob(chartID, jobID); err != nil {
+		return jobs.Job{}, err
+	}
+	return s.jobs.Cancel(jobID)
+}
+
+// BackfillResult returns the stitched export of a finished backfill.
+func (s *Service) BackfillResult(ctx context.Context, chartID, jobID string) (cdpcontrol.ChartExportResult, error) {
+	job, result, err := s.backfillJob(chartID, jobID)
+	if err != nil {
+		return cdpcontrol.ChartExportResult{}, err
+	}
+	if !job.Status.Done() {
+		return cdpcontrol.ChartExportResult{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeConflict, Message: "backfill is still running"}
+	}
+	res, ok := result.(cdpcontrol.ChartExportResult)
+	if !ok && s.jobStore != nil {
+		if err := s.jobStore.LoadResult(job.ID, &res); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return cdpcontrol.ChartExportResult{}, err
+		}
+	}
+	if res.Columns == nil {
+		res.Columns = []cdpcontrol.ExportSchemaColumn{}
+	}
+	if res.Studies == nil {
+		res.Studies = []cdpcontrol.ExportStudy{}
+	}
+	if res.Bars == nil {
+		res.Bars = [][]any{}
+	}
+	return res, nil
+}
+
+func (s *Service) backfillJob(chartID, jobID string) (jobs.Job, any, error) {
+	job, result, err := s.jobs.Result(strings.TrimSpace(jobID))
+	if err != nil || job.Kind != backfillJobKind || job.Key != strings.TrimSpace(chartID) {
+		return jobs.Job{}, nil, &cdpcontrol.CodedError{Code: cdpcontrol.CodeJobNotFound, Message: "backfill job not found: " + jobID}
+	}
+	return job, result, nil
+}
+
+// batchExportJobKind is the jobs.Manager kind of multi-symbol export jobs,
+// keyed by chart ID like backfills, which they would disturb by switching
+// the chart's symbol.
+const batchExportJobKind = "batch_export"
+
+// StartBatchExport validates req and starts a background job exporting each
+// symbol into the job's directory.
+func (s *Service) StartBatchExport(ctx context.Context, req export.BatchRequest) (jobs.Job, error) {
+	if s.jobStore == nil {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeAPIUnavailable, Message: "job store is not configured"}
+	}
+	req.ChartID = strings.TrimSpace(req.ChartID)
+	req.Resolution = strings.TrimSpace(req.Resolution)
+	req.StudyTemplate = strings.TrimSpace(req.StudyTemplate)
+	if len(req.Symbols) == 0 {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "symbols is required"}
+	}
+	seen := make(map[string]bool, len(req.Symbols))
+	for i, sym := range req.Symbols {
+		sym = strings.TrimSpace(sym)
+		if sym == "" {
+			return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "symbols must not be empty"}
+		}
+		if seen[strings.ToUpper(sym)] {
+			return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "duplicate symbol " + sym}
+		}
+		seen[strings.ToUpper(sym)] = true
+		req.Symbols[i] = sym
+	}
+	if req.Format == "" {
+		req.Format = export.FormatCSV
+	}
+	format, err := export.ParseFormat(string(req.Format))
+	if err != nil {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: err.Error()}
+	}
+	req.Format = format
+	if _, err := time.LoadLocation(req.TZ); err != nil {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: fmt.Sprintf("unknown timezone %q", req.TZ)}
+	}
+
+	prog := export.NewBatchProgress(req.Symbols)
+	job, err := s.jobs.Start(batchExportJobKind, req.ChartID, req, prog, s.runBatchExport(req, prog))
+	if errors.Is(err, jobs.ErrBusy) {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeConflict, Message: "a backfill or batch export is already running on chart " + req.ChartID}
+	}
+	return job, err
+}
+
+// ResumeBatchExport re-runs a finished, canceled or interrupted batch,
+// skipping symbols already exported and retrying failed ones.
+func (s *Service) ResumeBatchExport(ctx context.Context, jobID string) (jobs.Job, error) {
+	job, err := s.batchExportJob(jobID)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	req, _ := job.Params.(export.BatchRequest)
+	prog, _ := job.Progress.(export.BatchProgress)
+	job, err = s.jobs.Rerun(job.ID, s.runBatchExport(req, prog))
+	if errors.Is(err, jobs.ErrBusy) {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeConflict, Message: "batch export or another job on its chart is running"}
+	}
+	return job, err
+}
+
+func (s *Service) runBatchExport(req export.BatchRequest, prog export.BatchProgress) jobs.RunFunc {
+	return func(ctx context.Context, report func(any)) (any, error) {
+		dir, err := s.jobStore.Dir(jobs.IDFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.ensurePane(ctx, req.Pane); err != nil {
+			return nil, err
+		}
+		final, err := export.RunBatch(ctx, s.cdp, req, dir, prog, export.BatchOptions{}, func(p export.BatchProgress) { report(p) })
+		if err == nil && final.Failed > 0 {
+			slog.Warn("batch export finished with failures", "chart_id", req.ChartID, "failed", final.Failed, "total", final.Total)
+		}
+		return nil, err
+	}
+}
+
+// ListBatchExports returns batch export jobs, newest first.
+func (s *Service) ListBatchExports(ctx context.Context) ([]jobs.Job, error) {
+	return s.jobs.List(batchExportJobKind), nil
+}
+
+func (s *Service) GetBatchExport(ctx context.Context, jobID string) (jobs.Job, error) {
+	return s.batchExportJob(jobID)
+}
+
+// CancelBatchExport stops a running batch; files already written are kept
+// and the job can be resumed.
+func (s *Service) CancelBatchExport(ctx context.Context, jobID string) (jobs.Job, error) {
+	if _, err := s.batchExportJob(jobID); err != nil {
+		return jobs.Job{}, err
+	}
+	return s.jobs.Cancel(jobID)
+}
+
+// OpenBatchExportFile opens a file written by a batch export. name must be
+// one of the files listed in the job's progress.
+func (s *Service) OpenBatchExportFile(ctx context.Context, jobID, name string) (*os.File, export.Format, error) {
+	job, err := s.batchExportJob(jobID)
+	if err != nil {
+		return nil, "", err
+	}
+	req, _ := job.Params.(export.BatchRequest)
+	prog, _ := job.Progress.(export.BatchProgress)
+	for _, sym := range prog.Symbols {
+		if sym.File == "" || sym.File != name {
+			continue
+		}
+		dir, err := s.jobStore.Dir(job.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return nil, "", fmt.Errorf("open batch export file: %w", err)
+		}
+		return f, req.Format, nil
+	}
+	return nil, "", &cdpcontrol.CodedError{Code: cdpcontrol.CodeJobNotFound, Message: "batch export file not found: " + name}
+}
+
+func (s *Service) batchExportJob(jobID string) (jobs.Job, error) {
+	job, err := s.jobs.Get(strings.TrimSpace(jobID))
+	if err != nil || job.Kind != batchExportJobKind {
+		return jobs.Job{}, &cdpcontrol.CodedError{Code: cdpcontrol.CodeJobNotFound, Message: "batch export job not found: " + jobID}
+	}
+	return job, nil
+}
+
+// restoreBatchExports loads batch jobs saved by a previous run.
+func (s *Service) restoreBatchExports() {
+	saved, err := s.jobStore.Load(batchExportJobKind)
+	if err != nil {
+		slog.Warn("failed to load batch export jobs", "error", err)
+		return
+	}
+	for _, job := range saved {
+		var req export.BatchRequest
+		var prog export.BatchProgress
+		params, _ := job.Params.(json.RawMessage)
+		progress, _ := job.Progress.(json.RawMessage)
+		if err := json.Unmarshal(params, &req); err != nil {
+			slog.Warn("skipping batch export job with bad params", "id", job.ID, "error", err)
+			continue
+		}
+		if len(progress) == 0 || json.Unmarshal(progress, &prog) != nil || len(prog.Symbols) != len(req.Symbols) {
+			prog = export.NewBatchProgress(req.Symbols)
+		}
+		job.Params, job.Progress = req, prog
+		s.jobs.Restore(job)
+	}
+	if len(saved) > 0 {
+		slog.Info("restored batch export jobs", "count", len(saved))
+	}
+}
+
+// restoreBackfills loads backfill jobs saved by a previous run, whose
+// results stay on disk.
+func (s *Service) restoreBackfills() {
+	saved, err := s.jobStore.Load(backfillJobKind)
+	if err != nil {
+		slog.Warn("failed to load backfill jobs", "error", err)
+		return
+	}
+	for _, job := range saved {
+		var prog export.BackfillProgress
+		if progress, _ := job.Progress.(json.RawMessage); len(progress) > 0 && json.Unmarshal(progress, &prog) == nil {
+			job.Progress = prog
+		} else {
+			job.Progress = nil
+		}
+		job.Params = nil
+		s.jobs.Restore(job)
+	}
+	if len(saved) > 0 {
+		slog.Info("restored backfill jobs", "count", len(saved))
+	}
+}
+
 func decodeDataURL(dataURL string) ([]byte, error) {
 	parts := strings.SplitN(dataURL, ",", 2)
 	if len(parts) != 2 {
@@ -42,3 +42,24 @@ func TestSetSymbol_RequiresNonEmptySymbol(t *testing.T) {
 		t.Fatalf("SetSymbol() message = %q; want %q", got.Message, "symbol is required")
 	}
 }
+
+func TestStartBackfillConflictsWithBatchExport(t *testing.T) {
+	s := NewService(nil, nil, nil)
+	defer s.Close()
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := s.jobs.Start(batchExportJobKind, "chart-1", nil, nil, func(ctx context.Context, report func(any)) (any, error) {
+		<-release
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pane is given but the service has no browser: the conflict must be
+	// reported before the pane is touched.
+	_, err := s.StartBackfill(context.Background(), " chart-1 ", 1, 0, 100)
+	var got *cdpcontrol.CodedError
+	if !errors.As(err, &got) || got.Code != cdpcontrol.CodeConflict {
+		t.Fatalf("StartBackfill() err = %v; want %s", err, cdpcontrol.CodeConflict)
+	}
+}
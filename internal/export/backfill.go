@@ -0,0 +1,268 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+)
+
+// Pager is the chart control Backfill needs: exporting the loaded bars and
+// moving the viewport so TradingView requests older history.
+type Pager interface {
+	ExportChartData(ctx context.Context, chartID string) (cdpcontrol.ChartExportResult, error)
+	GoToDate(ctx context.Context, chartID string, timestamp int64) error
+	Scroll(ctx context.Context, chartID string, bars int) error
+}
+
+// LoadWatcher reports when a chart's series finishes loading data, as the
+// relay sees series_completed messages. Each receive on the channel is one
+// completion; stop ends the watch.
+type LoadWatcher interface {
+	WatchSeriesCompleted(chartID string) (completed <-chan struct{}, stop func())
+}
+
+// BackfillOptions bounds a Backfill. At least one of From and MaxBars
+// should be set, or paging stops only when history runs out.
+type BackfillOptions struct {
+	// From is the oldest bar time wanted; paging stops once it is loaded.
+	From time.Time
+	// MaxBars caps the number of stitched bars, keeping the newest.
+	MaxBars int
+	// PageBars is how far left to scroll per page (default 500).
+	PageBars int
+	// Loads signals when a page has loaded. Without it each page waits
+	// the full SettleTimeout before exporting.
+	Loads LoadWatcher
+	// SettleTimeout is how long to wait for older bars after a scroll
+	// before treating the history as exhausted (default 15s).
+	SettleTimeout time.Duration
+}
+
+// Backfill stop reasons.
+const (
+	StopReachedFrom      = "reached_from"
+	StopMaxBars          = "max_bars"
+	StopHistoryExhausted = "history_exhausted"
+)
+
+// BackfillProgress reports how far a Backfill has paged.
+type BackfillProgress struct {
+	Pages      int     `json:"pages"`
+	Bars       int     `json:"bars"`
+	Oldest     int64   `json:"oldest,omitempty" doc:"Unix seconds of the oldest stitched bar"`
+	Newest     int64   `json:"newest,omitempty" doc:"Unix seconds of the newest stitched bar"`
+	From       int64   `json:"from,omitempty"`
+	MaxBars    int     `json:"max_bars,omitempty"`
+	Percent    float64 `json:"percent"`
+	StopReason string  `json:"stop_reason,omitempty"`
+}
+
+// Backfill pages the chart backwards and stitches every loaded batch into
+// one export. Each page moves the viewport to the oldest loaded bar
+// (GoToDate), scrolls further left, waits for the series to finish loading
+// (or SettleTimeout) and exports once. Bars are de-duplicated by
+// time, sorted ascending and trimmed to From and MaxBars. On cancellation
+// or error the bars stitched so far are returned with the error.
+func Backfill(ctx context.Context, p Pager, chartID string, opts BackfillOptions, report func(BackfillProgress)) (cdpcontrol.ChartExportResult, error) {
+	if opts.PageBars <= 0 {
+		opts.PageBars = 500
+	}
+	if opts.SettleTimeout <= 0 {
+		opts.SettleTimeout = 15 * time.Second
+	}
+	if report == nil {
+		report = func(BackfillProgress) {}
+	}
+
+	first, err := p.ExportChartData(ctx, chartID)
+	if err != nil {
+		return cdpcontrol.ChartExportResult{}, err
+	}
+	s, err := newStitcher(first)
+	if err != nil {
+		return first, err
+	}
+	prog := BackfillProgress{Pages: 1, MaxBars: opts.MaxBars}
+	if !opts.From.IsZero() {
+		prog.From = opts.From.Unix()
+	}
+
+	for {
+		prog.Bars, prog.Oldest, prog.Newest = len(s.bars), s.oldest, s.newest
+		prog.Percent = backfillPercent(prog)
+		switch {
+		case len(s.bars) > 0 && prog.From > 0 && s.oldest <= prog.From:
+			prog.StopReason = StopReachedFrom
+		case opts.MaxBars > 0 && len(s.bars) >= opts.MaxBars:
+			prog.StopReason = StopMaxBars
+		}
+		if prog.StopReason != "" {
+			prog.Percent = 100
+			report(prog)
+			return s.result(prog.From, opts.MaxBars), nil
+		}
+		report(prog)
+
+		res, ok, err := loadOlder(ctx, p, chartID, s.oldest, opts)
+		if err != nil {
+			return s.result(prog.From, opts.MaxBars), err
+		}
+		if !ok {
+			prog.StopReason = StopHistoryExhausted
+			report(prog)
+			return s.result(prog.From, opts.MaxBars), nil
+		}
+		if err := s.add(res); err != nil {
+			return s.result(prog.From, opts.MaxBars), err
+		}
+		prog.Pages++
+	}
+}
+
+// loadOlder scrolls past oldest and exports once the series has finished
+// loading older bars. A completion that brought no older bars (another
+// series, or the page not yet applied) keeps it waiting; at SettleTimeout
+// it exports a last time. ok is false when no older bars arrived.
+func loadOlder(ctx context.Context, p Pager, chartID string, oldest int64, opts BackfillOptions) (res cdpcontrol.ChartExportResult, ok bool, err error) {
+	var completed <-chan struct{}
+	if opts.Loads != nil {
+		ch, stop := opts.Loads.WatchSeriesCompleted(chartID)
+		defer stop()
+		completed = ch
+	}
+	if oldest > 0 {
+		if err := p.GoToDate(ctx, chartID, oldest); err != nil {
+			return res, false, err
+		}
+	}
+	if err := p.Scroll(ctx, chartID, -opts.PageBars); err != nil {
+		return res, false, err
+	}
+
+	settle := time.NewTimer(opts.SettleTimeout)
+	defer settle.Stop()
+	for {
+		settled := false
+		select {
+		case <-ctx.Done():
+			return res, false, ctx.Err()
+		case <-completed:
+		case <-settle.C:
+			settled = true
+		}
+		cur, err := p.ExportChartData(ctx, chartID)
+		if err != nil {
+			return res, false, err
+		}
+		if t, found := oldestTime(cur); found && t < oldest {
+			return cur, true, nil
+		}
+		if settled {
+			return res, false, nil
+		}
+	}
+}
+
+func backfillPercent(p BackfillProgress) float64 {
+	var pct float64
+	if p.From > 0 && p.Newest > p.From {
+		pct = float64(p.Newest-p.Oldest) / float64(p.Newest-p.From) * 100
+	}
+	if p.MaxBars > 0 {
+		pct = max(pct, float64(p.Bars)/float64(p.MaxBars)*100)
+	}
+	return min(pct, 100)
+}
+
+func oldestTime(r cdpcontrol.ChartExportResult) (int64, bool) {
+	var oldest int64
+	found := false
+	for _, bar := range r.Bars {
+		if t, ok := barTime(bar, r.TimeColIdx); ok && (!found || t < oldest) {
+			oldest, found = t, true
+		}
+	}
+	return oldest, found
+}
+
+func barTime(bar []any, idx int) (int64, bool) {
+	if idx < 0 || idx >= len(bar) {
+		return 0, false
+	}
+	f, ok := bar[idx].(float64)
+	return int64(f), ok
+}
+
+// stitcher merges export batches keyed by bar time; later batches win.
+type stitcher struct {
+	base           cdpcontrol.ChartExportResult
+	bars           map[int64][]any
+	oldest, newest int64
+}
+
+func newStitcher(first cdpcontrol.ChartExportResult) (*stitcher, error) {
+	s := &stitcher{base: first, bars: make(map[int64][]any, len(first.Bars))}
+	s.base.Bars = nil
+	return s, s.add(first)
+}
+
+func (s *stitcher) add(r cdpcontrol.ChartExportResult) error {
+	if !sameSchema(s.base.Columns, r.Columns) || r.TimeColIdx != s.base.TimeColIdx {
+		return errors.New("chart columns changed during backfill")
+	}
+	if r.Symbol != s.base.Symbol || r.Resolution != s.base.Resolution {
+		return fmt.Errorf("chart changed during backfill (%s %s -> %s %s)", s.base.Symbol, s.base.Resolution, r.Symbol, r.Resolution)
+	}
+	for _, bar := range r.Bars {
+		t, ok := barTime(bar, r.TimeColIdx)
+		if !ok {
+			continue
+		}
+		if len(s.bars) == 0 || t < s.oldest {
+			s.oldest = t
+		}
+		if len(s.bars) == 0 || t > s.newest {
+			s.newest = t
+		}
+		s.bars[t] = bar
+	}
+	return nil
+}
+
+// result returns the stitched bars in time order, dropping bars before
+// from (Unix seconds, 0 for none) and keeping at most the newest maxBars.
+func (s *stitcher) result(from int64, maxBars int) cdpcontrol.ChartExportResult {
+	times := make([]int64, 0, len(s.bars))
+	for t := range s.bars {
+		if t >= from {
+			times = append(times, t)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	if maxBars > 0 && len(times) > maxBars {
+		times = times[len(times)-maxBars:]
+	}
+	out := s.base
+	out.Bars = make([][]any, len(times))
+	for i, t := range times {
+		out.Bars[i] = s.bars[t]
+	}
+	out.BarCount = len(out.Bars)
+	return out
+}
+
+func sameSchema(a, b []cdpcontrol.ExportSchemaColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package export
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+)
+
+// fakePager serves a minute-bar history where each left scroll loads one
+// more page of older bars and, while watched, signals a completed load.
+type fakePager struct {
+	mu        sync.Mutex
+	newest    int64
+	total     int // bars available in the full history
+	loaded    int
+	page      int
+	gotoTS    []int64
+	scrolls   int
+	exports   int
+	completed chan struct{}
+}
+
+func (f *fakePager) WatchSeriesCompleted(chartID string) (<-chan struct{}, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completed = make(chan struct{}, 1)
+	return f.completed, func() {
+		f.mu.Lock()
+		f.completed = nil
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakePager) ExportChartData(ctx context.Context, chartID string) (cdpcontrol.ChartExportResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exports++
+	res := cdpcontrol.ChartExportResult{
+		Symbol:     "BINANCE:BTCUSDT",
+		Resolution: "1",
+		Columns:    []cdpcontrol.ExportSchemaColumn{{Type: "time"}, {Type: "value", SourceType: "series", PlotTitle: "close"}},
+	}
+	for i := f.loaded - 1; i >= 0; i-- {
+		res.Bars = append(res.Bars, []any{float64(f.newest - int64(i)*60), float64(i)})
+	}
+	res.BarCount = len(res.Bars)
+	return res, nil
+}
+
+func (f *fakePager) GoToDate(ctx context.Context, chartID string, ts int64) error {
+	f.mu.Lock()
+	f.gotoTS = append(f.gotoTS, ts)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakePager) Scroll(ctx context.Context, chartID string, bars int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scrolls++
+	f.loaded = min(f.loaded+f.page, f.total)
+	if f.completed != nil {
+		f.completed <- struct{}{}
+	}
+	return nil
+}
+
+var fastBackfill = BackfillOptions{SettleTimeout: 20 * time.Millisecond}
+
+func TestBackfillStopsAtFrom(t *testing.T) {
+	p := &fakePager{newest: 1_700_000_000, total: 10_000, loaded: 100, page: 100}
+	opts := fastBackfill
+	opts.From = time.Unix(p.newest-349*60, 0)
+
+	var last BackfillProgress
+	res, err := Backfill(context.Background(), p, "chart-1", opts, func(bp BackfillProgress) { last = bp })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Bars) != 350 || res.BarCount != 350 {
+		t.Fatalf("bars = %d (bar_count %d), want 350", len(res.Bars), res.BarCount)
+	}
+	if first := res.Bars[0][0].(float64); int64(first) != opts.From.Unix() {
+		t.Fatalf("first bar = %v, want %d", first, opts.From.Unix())
+	}
+	for i := 1; i < len(res.Bars); i++ {
+		if res.Bars[i][0].(float64) <= res.Bars[i-1][0].(float64) {
+			t.Fatalf("bars not strictly ascending at %d", i)
+		}
+	}
+	if last.StopReason != StopReachedFrom || last.Percent != 100 || last.Pages != 4 {
+		t.Fatalf("final progress = %+v", last)
+	}
+	if len(p.gotoTS) != 3 || p.gotoTS[0] != p.newest-99*60 {
+		t.Fatalf("go-to-date calls = %v", p.gotoTS)
+	}
+}
+
+func TestBackfillExportsOncePerLoadedPage(t *testing.T) {
+	p := &fakePager{newest: 1_700_000_000, total: 10_000, loaded: 100, page: 100}
+	// Pages must be driven by completions: the settle timeout never fires.
+	opts := BackfillOptions{Loads: p, SettleTimeout: time.Minute, From: time.Unix(p.newest-349*60, 0)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := Backfill(ctx, p, "chart-1", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Bars) != 350 || p.exports != 4 {
+		t.Fatalf("bars = %d, exports = %d; want 350 bars from 4 exports", len(res.Bars), p.exports)
+	}
+}
+
+func TestBackfillMaxBarsAndExhaustion(t *testing.T) {
+	p := &fakePager{newest: 1_700_000_000, total: 250, loaded: 100, page: 100}
+	opts := fastBackfill
+	opts.MaxBars = 220
+
+	var last BackfillProgress
+	res, err := Backfill(context.Background(), p, "chart-1", opts, func(bp BackfillProgress) { last = bp })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Bars) != 220 || last.StopReason != StopMaxBars {
+		t.Fatalf("bars = %d, stop = %q", len(res.Bars), last.StopReason)
+	}
+	if newest := int64(res.Bars[len(res.Bars)-1][0].(float64)); newest != p.newest {
+		t.Fatalf("newest bar = %d, want %d", newest, p.newest)
+	}
+
+	p = &fakePager{newest: 1_700_000_000, total: 250, loaded: 100, page: 100}
+	opts.MaxBars = 0
+	opts.From = time.Unix(1, 0)
+	opts.Loads = p // the last completion brings nothing older; settle ends it
+	res, err = Backfill(context.Background(), p, "chart-1", opts, func(bp BackfillProgress) { last = bp })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Bars) != 250 || last.StopReason != StopHistoryExhausted {
+		t.Fatalf("bars = %d, stop = %q", len(res.Bars), last.StopReason)
+	}
+}
+
+func TestBackfillCanceledKeepsPartialResult(t *testing.T) {
+	p := &fakePager{newest: 1_700_000_000, total: 10_000, loaded: 100, page: 100}
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := fastBackfill
+	opts.From = time.Unix(1, 0)
+	res, err := Backfill(ctx, p, "chart-1", opts, func(bp BackfillProgress) {
+		if bp.Pages == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(res.Bars) != 200 {
+		t.Fatalf("partial bars = %d, want 200", len(res.Bars))
+	}
+}
@@ -0,0 +1,233 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+)
+
+// BatchChart is the chart control RunBatch needs.
+type BatchChart interface {
+	SetSymbol(ctx context.Context, chartID, symbol string) (string, error)
+	SetResolution(ctx context.Context, chartID, resolution string) (string, error)
+	ApplyStudyTemplate(ctx context.Context, chartID, name string) (cdpcontrol.StudyTemplateApplyResult, error)
+	ExportChartData(ctx context.Context, chartID string) (cdpcontrol.ChartExportResult, error)
+}
+
+// BatchRequest describes a multi-symbol export. It is persisted with the
+// job so an interrupted batch can be resumed.
+type BatchRequest struct {
+	ChartID       string   `json:"chart_id"`
+	Pane          int      `json:"pane"`
+	Symbols       []string `json:"symbols"`
+	Resolution    string   `json:"resolution,omitempty"`
+	StudyTemplate string   `json:"study_template,omitempty"`
+	Format        Format   `json:"format"`
+	TZ            string   `json:"tz,omitempty"`
+}
+
+// Per-symbol batch states.
+const (
+	SymbolPending = "pending"
+	SymbolDone    = "done"
+	SymbolFailed  = "failed"
+)
+
+// BatchSymbol is the outcome of one symbol in a batch.
+type BatchSymbol struct {
+	Symbol string `json:"symbol"`
+	Status string `json:"status"`
+	File   string `json:"file,omitempty"`
+	Bars   int    `json:"bars,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchProgress reports a batch's per-symbol state.
+type BatchProgress struct {
+	Total   int           `json:"total"`
+	Done    int           `json:"done"`
+	Failed  int           `json:"failed"`
+	Current string        `json:"current,omitempty"`
+	Percent float64       `json:"percent"`
+	Symbols []BatchSymbol `json:"symbols"`
+}
+
+// NewBatchProgress returns progress with every symbol pending.
+func NewBatchProgress(symbols []string) BatchProgress {
+	p := BatchProgress{Total: len(symbols), Symbols: make([]BatchSymbol, len(symbols))}
+	for i, sym := range symbols {
+		p.Symbols[i] = BatchSymbol{Symbol: sym, Status: SymbolPending}
+	}
+	return p
+}
+
+func (p *BatchProgress) recount() {
+	p.Done, p.Failed = 0, 0
+	for _, s := range p.Symbols {
+		switch s.Status {
+		case SymbolDone:
+			p.Done++
+		case SymbolFailed:
+			p.Failed++
+		}
+	}
+	if p.Total > 0 {
+		p.Percent = float64(p.Done+p.Failed) / float64(p.Total) * 100
+	}
+}
+
+// BatchOptions tunes how RunBatch waits for each symbol to load.
+type BatchOptions struct {
+	// PollInterval is the delay between exports while a symbol loads
+	// (default 500ms).
+	PollInterval time.Duration
+	// LoadTimeout bounds the wait for a symbol's bars (default 30s).
+	LoadTimeout time.Duration
+}
+
+// RunBatch exports each symbol of req into dir, one file per symbol. It
+// sets the resolution and applies the study template once, then for each
+// symbol not yet done calls SetSymbol, waits until the export reports the
+// new symbol with a stable bar count, and writes the file. Symbol errors are
+// recorded in the progress and do not stop the batch; symbols that failed
+// in an earlier run are retried. It returns the final progress, and
+// ctx.Err() if canceled (the current symbol stays pending).
+func RunBatch(ctx context.Context, c BatchChart, req BatchRequest, dir string, prog BatchProgress, opts BatchOptions, report func(BatchProgress)) (BatchProgress, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 500 * time.Millisecond
+	}
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = 30 * time.Second
+	}
+	if report == nil {
+		report = func(BatchProgress) {}
+	}
+	loc, err := time.LoadLocation(req.TZ)
+	if err != nil {
+		return prog, fmt.Errorf("unknown timezone %q", req.TZ)
+	}
+	prog.Current = ""
+	prog.recount()
+	report(prog)
+
+	if req.Resolution != "" {
+		if _, err := c.SetResolution(ctx, req.ChartID, req.Resolution); err != nil {
+			return prog, fmt.Errorf("set resolution %s: %w", req.Resolution, err)
+		}
+	}
+	if req.StudyTemplate != "" {
+		if _, err := c.ApplyStudyTemplate(ctx, req.ChartID, req.StudyTemplate); err != nil {
+			return prog, fmt.Errorf("apply study template %q: %w", req.StudyTemplate, err)
+		}
+	}
+
+	for i := range prog.Symbols {
+		sym := &prog.Symbols[i]
+		if sym.Status == SymbolDone {
+			continue
+		}
+		*sym = BatchSymbol{Symbol: sym.Symbol, Status: SymbolPending}
+		prog.Current = sym.Symbol
+		report(prog)
+
+		file, bars, err := exportSymbol(ctx, c, req, sym.Symbol, dir, loc, opts)
+		if ctx.Err() != nil {
+			prog.Current = ""
+			return prog, ctx.Err()
+		}
+		if err != nil {
+			sym.Status, sym.Error = SymbolFailed, err.Error()
+		} else {
+			sym.Status, sym.File, sym.Bars = SymbolDone, file, bars
+		}
+		prog.recount()
+	}
+	prog.Current = ""
+	report(prog)
+	return prog, nil
+}
+
+func exportSymbol(ctx context.Context, c BatchChart, req BatchRequest, symbol, dir string, loc *time.Location, opts BatchOptions) (string, int, error) {
+	if _, err := c.SetSymbol(ctx, req.ChartID, symbol); err != nil {
+		return "", 0, err
+	}
+	res, err := waitForSymbol(ctx, c, req.ChartID, symbol, opts)
+	if err != nil {
+		return "", 0, err
+	}
+
+	name := FileName(symbol, "", req.Format)
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := WriteChart(tmp, res, req.Format, loc); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return "", 0, err
+	}
+	return name, len(res.Bars), nil
+}
+
+// waitForSymbol polls ExportChartData until it reports the requested
+// symbol and two consecutive exports have the same non-zero bar count.
+// SetSymbol's own result is not trusted: it is read right after the switch
+// starts and usually still names the previous symbol.
+func waitForSymbol(ctx context.Context, c BatchChart, chartID, requested string, opts BatchOptions) (cdpcontrol.ChartExportResult, error) {
+	deadline := time.Now().Add(opts.LoadTimeout)
+	lastCount := -1
+	var lastSymbol string
+	for {
+		if err := sleepCtx(ctx, opts.PollInterval); err != nil {
+			return cdpcontrol.ChartExportResult{}, err
+		}
+		res, err := c.ExportChartData(ctx, chartID)
+		if err != nil {
+			return res, err
+		}
+		if symbolMatches(res.Symbol, requested) {
+			if len(res.Bars) > 0 && len(res.Bars) == lastCount {
+				return res, nil
+			}
+			lastCount = len(res.Bars)
+		} else {
+			lastCount = -1
+		}
+		lastSymbol = res.Symbol
+		if time.Now().After(deadline) {
+			return res, fmt.Errorf("symbol did not load within %s (chart shows %q)", opts.LoadTimeout, lastSymbol)
+		}
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// symbolMatches reports whether the chart's symbol is want, allowing want
+// to omit the exchange prefix ("AAPL" matches "NASDAQ:AAPL").
+func symbolMatches(actual, want string) bool {
+	if actual == "" || want == "" {
+		return false
+	}
+	actual, want = strings.ToUpper(actual), strings.ToUpper(strings.TrimSpace(want))
+	return actual == want || (!strings.Contains(want, ":") && strings.HasSuffix(actual, ":"+want))
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+)
+
+// fakeBatchChart switches symbol after one poll; symbols in bad never load.
+// Like the real chart, SetSymbol reports the symbol shown before the switch.
+type fakeBatchChart struct {
+	mu        sync.Mutex
+	symbol    string
+	polls     int
+	bad       map[string]bool
+	templates []string
+	set       []string
+	onSet     func(symbol string)
+}
+
+func (f *fakeBatchChart) SetSymbol(ctx context.Context, chartID, symbol string) (string, error) {
+	f.mu.Lock()
+	f.set = append(f.set, symbol)
+	f.polls = 0
+	before := f.symbol
+	if !f.bad[symbol] {
+		f.symbol = "NASDAQ:" + symbol
+	}
+	onSet := f.onSet
+	f.mu.Unlock()
+	if onSet != nil {
+		onSet(symbol)
+	}
+	return before, nil
+}
+
+func (f *fakeBatchChart) SetResolution(ctx context.Context, chartID, resolution string) (string, error) {
+	return resolution, nil
+}
+
+func (f *fakeBatchChart) ApplyStudyTemplate(ctx context.Context, chartID, name string) (cdpcontrol.StudyTemplateApplyResult, error) {
+	f.templates = append(f.templates, name)
+	return cdpcontrol.StudyTemplateApplyResult{}, nil
+}
+
+func (f *fakeBatchChart) ExportChartData(ctx context.Context, chartID string) (cdpcontrol.ChartExportResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls++
+	res := cdpcontrol.ChartExportResult{
+		Symbol:     f.symbol,
+		Resolution: "1D",
+		Columns:    []cdpcontrol.ExportSchemaColumn{{Type: "time"}, {Type: "value", SourceType: "series", PlotTitle: "close"}},
+	}
+	if f.polls > 1 {
+		res.Bars = [][]any{{1771632000.0, 1.0}, {1771718400.0, 2.0}}
+	}
+	return res, nil
+}
+
+func TestRunBatchWritesFilesAndRecordsFailures(t *testing.T) {
+	dir := t.TempDir()
+	chart := &fakeBatchChart{bad: map[string]bool{"NOPE": true}}
+	req := BatchRequest{ChartID: "chart-1", Symbols: []string{"AAPL", "NOPE", "MSFT"}, StudyTemplate: "Momentum", Format: FormatCSV}
+	opts := BatchOptions{PollInterval: time.Millisecond, LoadTimeout: 20 * time.Millisecond}
+
+	prog, err := RunBatch(context.Background(), chart, req, dir, NewBatchProgress(req.Symbols), opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.Done != 2 || prog.Failed != 1 || prog.Percent != 100 {
+		t.Fatalf("progress = %+v", prog)
+	}
+	if s := prog.Symbols[1]; s.Status != SymbolFailed || !strings.Contains(s.Error, "did not load") {
+		t.Fatalf("NOPE = %+v", s)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, prog.Symbols[0].File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.Symbols[0].File != "AAPL.csv" || !strings.HasPrefix(string(data), "time,close\n2026-02-21T00:00:00Z,1\n") {
+		t.Fatalf("AAPL file %q = %q", prog.Symbols[0].File, data)
+	}
+	if len(chart.templates) != 1 {
+		t.Fatalf("template applied %d times, want once", len(chart.templates))
+	}
+
+	// A resumed run retries the failure and skips finished symbols.
+	chart.bad = nil
+	chart.set = nil
+	prog, err = RunBatch(context.Background(), chart, req, dir, prog, opts, nil)
+	if err != nil || prog.Done != 3 || len(chart.set) != 1 || chart.set[0] != "NOPE" {
+		t.Fatalf("resume: progress = %+v, set = %v, err = %v", prog, chart.set, err)
+	}
+}
+
+func TestRunBatchCancelLeavesSymbolPending(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chart := &fakeBatchChart{onSet: func(symbol string) {
+		if symbol == "MSFT" {
+			cancel()
+		}
+	}}
+	req := BatchRequest{ChartID: "chart-1", Symbols: []string{"AAPL", "MSFT"}, Format: FormatNDJSON}
+	opts := BatchOptions{PollInterval: time.Millisecond, LoadTimeout: time.Second}
+
+	prog, err := RunBatch(ctx, chart, req, t.TempDir(), NewBatchProgress(req.Symbols), opts, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if prog.Symbols[0].Status != SymbolDone || prog.Symbols[1].Status != SymbolPending {
+		t.Fatalf("symbols = %+v", prog.Symbols)
+	}
+}
+
+func TestSymbolMatches(t *testing.T) {
+	cases := []struct {
+		actual, want string
+		ok           bool
+	}{
+		{"NASDAQ:AAPL", "AAPL", true},
+		{"NASDAQ:AAPL", "nasdaq:aapl", true},
+		{"NASDAQ:AAPL", "NYSE:AAPL", false},
+		{"NASDAQ:AAPLX", "AAPL", false},
+		{"", "AAPL", false},
+	}
+	for _, c := range cases {
+		if got := symbolMatches(c.actual, c.want); got != c.ok {
+			t.Errorf("symbolMatches(%q, %q) = %v", c.actual, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+)
+
+// ChartColumns derives named, typed columns from an ExportChartData schema.
+// Time columns are "time" (and "user_time"), main-series plots use their
+// lower-cased plot title ("open", "close", ...) and study plots are named
+// "<source_title>: <plot_title>", or just the title when the two agree.
+// Duplicate names get a " (2)", " (3)", ... suffix.
+func ChartColumns(schema []cdpcontrol.ExportSchemaColumn) []Column {
+	cols := make([]Column, len(schema))
+	seen := make(map[string]int, len(schema))
+	for i, sc := range schema {
+		c := Column{Name: columnName(sc), Kind: KindFloat}
+		if sc.Type == "time" || sc.Type == "userTime" {
+			c.Kind = KindTime
+		}
+		if n := seen[c.Name]; n > 0 {
+			seen[c.Name] = n + 1
+			c.Name += " (" + strconv.Itoa(n+1) + ")"
+		} else {
+			seen[c.Name] = 1
+		}
+		cols[i] = c
+	}
+	return cols
+}
+
+func columnName(sc cdpcontrol.ExportSchemaColumn) string {
+	switch sc.Type {
+	case "time":
+		return "time"
+	case "userTime":
+		return "user_time"
+	}
+	src, plot := strings.TrimSpace(sc.SourceTitle), strings.TrimSpace(sc.PlotTitle)
+	if isMainSeries(sc) {
+		if plot != "" {
+			return strings.ToLower(plot)
+		}
+	}
+	switch {
+	case src == "" && plot == "":
+		return "value"
+	case src == "" || strings.EqualFold(src, plot):
+		return plot
+	case plot == "":
+		return src
+	}
+	return src + ": " + plot
+}
+
+// ChartRow converts one row of ChartExportResult.Bars into the value types
+// of cols: Unix-second times become time.Time, numbers stay float64 and
+// anything else (null gaps) becomes nil. dst is reused when large enough.
+func ChartRow(cols []Column, bar []any, dst []any) []any {
+	if cap(dst) < len(cols) {
+		dst = make([]any, len(cols))
+	}
+	dst = dst[:len(cols)]
+	for i, c := range cols {
+		dst[i] = nil
+		if i >= len(bar) {
+			continue
+		}
+		f, ok := bar[i].(float64)
+		if !ok {
+			continue
+		}
+		if c.Kind == KindTime {
+			dst[i] = time.Unix(int64(f), 0).UTC()
+		} else {
+			dst[i] = f
+		}
+	}
+	return dst
+}
+
+// SelectStudies keeps the time and main-series columns of result plus the
+// columns of the studies matched by selectors, each a source ID, entity ID
+// or case-insensitive study name (as listed in result.Studies). Bars are
+// copied with only the kept columns. An unmatched selector is an error.
+func SelectStudies(result cdpcontrol.ChartExportResult, selectors []string) (cdpcontrol.ChartExportResult, error) {
+	keep := make(map[string]bool)
+	for _, sel := range selectors {
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			continue
+		}
+		found := false
+		for _, st := range result.Studies {
+			if sel == st.SourceID || sel == st.EntityID || strings.EqualFold(sel, st.Name) {
+				keep[st.SourceID] = true
+				found = true
+			}
+		}
+		if !found {
+			return result, fmt.Errorf("study %q is not on the chart", sel)
+		}
+	}
+
+	var idx []int
+	out := result
+	out.Columns = nil
+	out.TimeColIdx = -1
+	for i, c := range result.Columns {
+		if c.Type == "time" || c.Type == "userTime" || isMainSeries(c) || keep[c.SourceID] {
+			if i == result.TimeColIdx {
+				out.TimeColIdx = len(idx)
+			}
+			idx = append(idx, i)
+			out.Columns = append(out.Columns, c)
+		}
+	}
+	out.Studies = nil
+	for _, st := range result.Studies {
+		if keep[st.SourceID] {
+			out.Studies = append(out.Studies, st)
+		}
+	}
+	if out.Studies == nil {
+		out.Studies = []cdpcontrol.ExportStudy{}
+	}
+	out.Bars = make([][]any, len(result.Bars))
+	for r, bar := range result.Bars {
+		row := make([]any, len(idx))
+		for j, i := range idx {
+			if i < len(bar) {
+				row[j] = bar[i]
+			}
+		}
+		out.Bars[r] = row
+	}
+	return out, nil
+}
+
+func isMainSeries(c cdpcontrol.ExportSchemaColumn) bool {
+	return strings.EqualFold(c.SourceType, "series") || strings.EqualFold(c.SourceType, "mainSeries")
+}
+
+// chartFlushRows is how many rows WriteChart writes between flushes.
+const chartFlushRows = 1000
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileName returns a filesystem-safe "<symbol>_<resolution>.<ext>" name for
+// an export in format f.
+func FileName(symbol, resolution string, f Format) string {
+	name := symbol
+	if resolution != "" {
+		name += "_" + resolution
+	}
+	return unsafeFileChars.ReplaceAllString(name, "_") + "." + string(f)
+}
+
+// WriteChart writes an export to w: JSON writes the ChartExportResult as-is,
+// the row formats write one row per bar with ChartColumns. If w has a
+// Flush method (such as http.Flusher) it is called every 1000 rows.
+func WriteChart(w io.Writer, result cdpcontrol.ChartExportResult, f Format, loc *time.Location) error {
+	if f == FormatJSON {
+		return json.NewEncoder(w).Encode(result)
+	}
+	cols := ChartColumns(result.Columns)
+	rw, err := NewWriter(w, f, cols, loc)
+	if err != nil {
+		return err
+	}
+	flusher, _ := w.(interface{ Flush() })
+	var row []any
+	for i, bar := range result.Bars {
+		row = ChartRow(cols, bar, row)
+		if err := rw.WriteRow(row); err != nil {
+			return err
+		}
+		if flusher != nil && (i+1)%chartFlushRows == 0 {
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+	return rw.Close()
+}
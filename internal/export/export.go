@@ -0,0 +1,283 @@
+// Package export renders tabular chart data as CSV, NDJSON or Apache
+// Parquet, one row at a time so large exports can be streamed, and stitches
+// deep history exports by paging the chart backwards (Backfill).
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the value type of a column. Every column is nullable.
+type Kind int
+
+const (
+	// KindTime values are time.Time.
+	KindTime Kind = iota
+	// KindFloat values are float64.
+	KindFloat
+	// KindString values are string.
+	KindString
+)
+
+// Column describes one output column.
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Format is an export file format.
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+var contentTypes = map[Format]string{
+	FormatJSON:    "application/json",
+	FormatCSV:     "text/csv",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// ContentType returns the MIME type of f.
+func (f Format) ContentType() string { return contentTypes[f] }
+
+// ParseFormat validates a ?format= value.
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := contentTypes[f]; !ok {
+		return "", fmt.Errorf("unknown format %q (want json, csv, ndjson or parquet)", s)
+	}
+	return f, nil
+}
+
+// ErrNotAcceptable is returned by Negotiate when the Accept header rules out
+// every supported format.
+var ErrNotAcceptable = errors.New("no acceptable export format")
+
+// acceptTypes lists the media types each format answers to, in the order
+// formats are preferred when the Accept header ranks them equally.
+var acceptTypes = []struct {
+	format Format
+	types  []string
+}{
+	{FormatJSON, []string{"application/json"}},
+	{FormatCSV, []string{"text/csv"}},
+	{FormatNDJSON, []string{"application/x-ndjson", "application/ndjson", "application/jsonl"}},
+	{FormatParquet, []string{"application/vnd.apache.parquet", "application/x-parquet"}},
+}
+
+// Negotiate picks the format from an explicit ?format= value, else from the
+// Accept header: the format with the highest q-value wins, a format named
+// outright beats one matched by a wildcard, and JSON is preferred on ties.
+// An empty Accept selects JSON; one that excludes every format (q=0 or no
+// match) returns ErrNotAcceptable.
+func Negotiate(format, accept string) (Format, error) {
+	if format != "" {
+		return ParseFormat(format)
+	}
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, nil
+	}
+	ranges := parseAccept(accept)
+	var best Format
+	bestQ, bestSpec := 0.0, -1
+	for _, a := range acceptTypes {
+		for _, t := range a.types {
+			q, spec := acceptQuality(ranges, t)
+			if q > bestQ || (q == bestQ && q > 0 && spec > bestSpec) {
+				best, bestQ, bestSpec = a.format, q, spec
+			}
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: Accept %q (want application/json, text/csv, application/x-ndjson or application/vnd.apache.parquet)",
+			ErrNotAcceptable, accept)
+	}
+	return best, nil
+}
+
+type mediaRange struct {
+	typ string // "text/csv", "text/*" or "*/*"
+	q   float64
+}
+
+// parseAccept parses an Accept header, skipping malformed ranges.
+func parseAccept(accept string) []mediaRange {
+	var out []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		out = append(out, mediaRange{typ: mt, q: q})
+	}
+	return out
+}
+
+// acceptQuality returns the q-value the most specific matching range gives
+// mediaType and that range's specificity (2 exact, 1 type/*, 0 */*), or
+// 0, -1 if no range matches.
+func acceptQuality(ranges []mediaRange, mediaType string) (float64, int) {
+	q, spec := 0.0, -1
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, r := range ranges {
+		s := -1
+		switch r.typ {
+		case mediaType:
+			s = 2
+		case major + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > spec {
+			q, spec = r.q, s
+		}
+	}
+	return q, spec
+}
+
+// RowWriter writes rows in column order. Values must match the column's
+// Kind or be nil. Flush pushes buffered rows to the underlying writer where
+// the format allows it (Parquet writes whole row groups). Close flushes and,
+// for Parquet, writes the footer; it does not close the underlying writer.
+type RowWriter interface {
+	WriteRow(row []any) error
+	Flush() error
+	Close() error
+}
+
+// NewWriter returns a RowWriter for a row format (CSV, NDJSON or Parquet).
+// Times are rendered as RFC 3339 in loc by the text formats; Parquet stores
+// them as UTC timestamps.
+func NewWriter(w io.Writer, f Format, cols []Column, loc *time.Location) (RowWriter, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w, cols, loc)
+	case FormatNDJSON:
+		return &ndjsonWriter{w: w, cols: cols, loc: loc}, nil
+	case FormatParquet:
+		return newParquetWriter(w, cols), nil
+	}
+	return nil, fmt.Errorf("format %q is not a row format", f)
+}
+
+type csvWriter struct {
+	w    *csv.Writer
+	cols []Column
+	loc  *time.Location
+	rec  []string
+}
+
+func newCSVWriter(w io.Writer, cols []Column, loc *time.Location) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), cols: cols, loc: loc, rec: make([]string, len(cols))}
+	for i, c := range cols {
+		cw.rec[i] = c.Name
+	}
+	return cw, cw.w.Write(cw.rec)
+}
+
+func (c *csvWriter) WriteRow(row []any) error {
+	for i := range c.cols {
+		c.rec[i] = formatText(row[i], c.loc)
+	}
+	return c.w.Write(c.rec)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error { return c.Flush() }
+
+type ndjsonWriter struct {
+	w    io.Writer
+	cols []Column
+	loc  *time.Location
+	buf  []byte
+}
+
+func (n *ndjsonWriter) WriteRow(row []any) error {
+	b := append(n.buf[:0], '{')
+	for i, c := range n.cols {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, c.Name)
+		b = append(b, ':')
+		switch v := row[i].(type) {
+		case nil:
+			b = append(b, "null"...)
+		case time.Time:
+			b = appendJSONString(b, v.In(n.loc).Format(time.RFC3339))
+		case float64:
+			b = appendJSONFloat(b, v)
+		default:
+			enc, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b = append(b, enc...)
+		}
+	}
+	b = append(b, '}', '\n')
+	n.buf = b
+	_, err := n.w.Write(b)
+	return err
+}
+
+func (n *ndjsonWriter) Flush() error { return nil }
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+// appendJSONFloat appends v as a JSON number, or null for NaN and ±Inf,
+// which JSON cannot represent.
+func appendJSONFloat(b []byte, v float64) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return append(b, "null"...)
+	}
+	return strconv.AppendFloat(b, v, 'f', -1, 64)
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	enc, _ := json.Marshal(s)
+	return append(b, enc...)
+}
+
+func formatText(v any, loc *time.Location) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.In(loc).Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
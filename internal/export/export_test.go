@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		format, accept string
+		want           Format
+	}{
+		{"", "", FormatJSON},
+		{"", "text/csv;q=0.9, application/json", FormatJSON},
+		{"", "text/csv, application/json;q=0.9", FormatCSV},
+		{"", "text/csv;q=0, application/x-ndjson", FormatNDJSON},
+		{"", "text/csv, */*", FormatCSV},
+		{"", "application/*;q=0.5, application/jsonl", FormatNDJSON},
+		{"", "text/html,application/xhtml+xml,*/*;q=0.8", FormatJSON},
+		{"", "application/x-ndjson", FormatNDJSON},
+		{"", "application/vnd.apache.parquet", FormatParquet},
+		{"", "text/html, */*", FormatJSON},
+		{"CSV", "application/json", FormatCSV},
+	}
+	for _, c := range cases {
+		got, err := Negotiate(c.format, c.accept)
+		if err != nil || got != c.want {
+			t.Errorf("Negotiate(%q, %q) = %q, %v; want %q", c.format, c.accept, got, err, c.want)
+		}
+	}
+	if _, err := Negotiate("xlsx", ""); err == nil || errors.Is(err, ErrNotAcceptable) {
+		t.Errorf("unknown format: %v", err)
+	}
+	for _, accept := range []string{"text/html", "text/csv;q=0", "*/*;q=0", "application/json;q=0, */*;q=0"} {
+		if f, err := Negotiate("", accept); !errors.Is(err, ErrNotAcceptable) {
+			t.Errorf("Negotiate(%q) = %q, %v; want ErrNotAcceptable", accept, f, err)
+		}
+	}
+}
+
+func TestTextWriters(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata unavailable:", err)
+	}
+	cols := []Column{{Name: "time", Kind: KindTime}, {Name: "close", Kind: KindFloat}, {Name: "EMA: Plot", Kind: KindFloat}}
+	row := []any{time.Unix(1771699560, 0), 68483.99, nil}
+
+	var csvBuf bytes.Buffer
+	w, _ := NewWriter(&csvBuf, FormatCSV, cols, ny)
+	w.WriteRow(row)
+	w.Close()
+	if want := "time,close,EMA: Plot\n2026-02-21T13:46:00-05:00,68483.99,\n"; csvBuf.String() != want {
+		t.Fatalf("csv = %q, want %q", csvBuf.String(), want)
+	}
+
+	var ndBuf bytes.Buffer
+	w, _ = NewWriter(&ndBuf, FormatNDJSON, cols, nil)
+	w.WriteRow(row)
+	w.Close()
+	if want := `{"time":"2026-02-21T18:46:00Z","close":68483.99,"EMA: Plot":null}` + "\n"; ndBuf.String() != want {
+		t.Fatalf("ndjson = %q, want %q", ndBuf.String(), want)
+	}
+}
+
+func TestSelectStudies(t *testing.T) {
+	res := cdpcontrol.ChartExportResult{
+		TimeColIdx: 0,
+		Columns: []cdpcontrol.ExportSchemaColumn{
+			{Type: "time"},
+			{Type: "value", SourceType: "series", PlotTitle: "close"},
+			{Type: "value", SourceType: "study", SourceID: "st1", SourceTitle: "EMA", PlotTitle: "EMA"},
+			{Type: "value", SourceType: "study", SourceID: "st2", SourceTitle: "RSI", PlotTitle: "Plot"},
+		},
+		Studies: []cdpcontrol.ExportStudy{
+			{SourceID: "st1", EntityID: "st1", Name: "Moving Average Exponential", PaneIndex: 0, Inputs: map[string]any{"length": 9.0}},
+			{SourceID: "st2", EntityID: "st2", Name: "Relative Strength Index", PaneIndex: 1, Inputs: map[string]any{"length": 14.0}},
+		},
+		Bars: [][]any{{1771632000.0, 191.0, 190.5, 55.2}},
+	}
+
+	got, err := SelectStudies(res, []string{"relative strength index"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Columns) != 3 || got.Columns[2].SourceID != "st2" || got.TimeColIdx != 0 {
+		t.Fatalf("columns = %+v", got.Columns)
+	}
+	if len(got.Studies) != 1 || got.Studies[0].PaneIndex != 1 {
+		t.Fatalf("studies = %+v", got.Studies)
+	}
+	if row := got.Bars[0]; len(row) != 3 || row[2] != 55.2 {
+		t.Fatalf("row = %v", row)
+	}
+	if len(res.Bars[0]) != 4 {
+		t.Fatal("SelectStudies modified the input bars")
+	}
+
+	if _, err := SelectStudies(res, []string{"MACD"}); err == nil {
+		t.Fatal("expected error for a study not on the chart")
+	}
+}
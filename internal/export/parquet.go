@@ -0,0 +1,258 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// parquetRowGroupSize is the number of rows buffered before a row group is
+// written, bounding memory for large exports.
+const parquetRowGroupSize = 64 * 1024
+
+// Parquet physical types, encodings and other enum values used below (see
+// parquet.thrift).
+const (
+	pqInt64      = 2
+	pqDouble     = 5
+	pqByteArray  = 6
+	pqOptional   = 1
+	pqPlain      = 0
+	pqRLE        = 3
+	pqDataPage   = 0
+	pqUTF8       = 0 // ConvertedType
+	pqTSMillis   = 9 // ConvertedType
+	pqVersion    = 1
+	pqCreatedBy  = "MaudeViewTVCore export"
+	parquetMagic = "PAR1"
+)
+
+// parquetWriter writes an uncompressed, PLAIN-encoded Parquet file with one
+// optional column per Column: timestamps as INT64 TIMESTAMP(MILLIS, UTC),
+// floats as DOUBLE and strings as UTF-8 BYTE_ARRAY.
+type parquetWriter struct {
+	w    io.Writer
+	cols []Column
+	err  error
+
+	offset    int64
+	rows      int
+	values    []bytes.Buffer // PLAIN values per column for the current row group
+	defined   [][]bool       // definition level per row per column
+	rowGroups []pqRowGroup
+	totalRows int64
+}
+
+type pqRowGroup struct {
+	rows   int64
+	chunks []pqChunk
+}
+
+type pqChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+func newParquetWriter(w io.Writer, cols []Column) *parquetWriter {
+	p := &parquetWriter{
+		w:       w,
+		cols:    cols,
+		values:  make([]bytes.Buffer, len(cols)),
+		defined: make([][]bool, len(cols)),
+	}
+	p.write([]byte(parquetMagic))
+	return p
+}
+
+func (p *parquetWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	p.err = err
+}
+
+func (p *parquetWriter) WriteRow(row []any) error {
+	if p.err != nil {
+		return p.err
+	}
+	var b [8]byte
+	for i, c := range p.cols {
+		v := row[i]
+		p.defined[i] = append(p.defined[i], v != nil)
+		if v == nil {
+			continue
+		}
+		buf := &p.values[i]
+		switch c.Kind {
+		case KindTime:
+			binary.LittleEndian.PutUint64(b[:], uint64(v.(time.Time).UnixMilli()))
+			buf.Write(b[:])
+		case KindFloat:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.(float64)))
+			buf.Write(b[:])
+		case KindString:
+			s := v.(string)
+			binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+			buf.Write(b[:4])
+			buf.WriteString(s)
+		}
+	}
+	p.rows++
+	if p.rows >= parquetRowGroupSize {
+		p.flushRowGroup()
+	}
+	return p.err
+}
+
+// flushRowGroup writes one data page per column for the buffered rows.
+func (p *parquetWriter) flushRowGroup() {
+	if p.rows == 0 {
+		return
+	}
+	rg := pqRowGroup{rows: int64(p.rows)}
+	for i := range p.cols {
+		var page bytes.Buffer
+		levels := encodeDefinitionLevels(p.defined[i])
+		var n [4]byte
+		binary.LittleEndian.PutUint32(n[:], uint32(len(levels)))
+		page.Write(n[:])
+		page.Write(levels)
+		page.Write(p.values[i].Bytes())
+
+		var hdr thriftWriter
+		hdr.beginStruct()
+		hdr.i32(1, pqDataPage)
+		hdr.i32(2, int32(page.Len()))
+		hdr.i32(3, int32(page.Len()))
+		hdr.structField(5)
+		hdr.i32(1, int32(p.rows))
+		hdr.i32(2, pqPlain)
+		hdr.i32(3, pqRLE)
+		hdr.i32(4, pqRLE)
+		hdr.endStruct()
+		hdr.endStruct()
+
+		start := p.offset
+		p.write(hdr.buf.Bytes())
+		p.write(page.Bytes())
+		rg.chunks = append(rg.chunks, pqChunk{offset: start, size: p.offset - start, numValues: int64(p.rows)})
+
+		p.values[i].Reset()
+		p.defined[i] = p.defined[i][:0]
+	}
+	p.rowGroups = append(p.rowGroups, rg)
+	p.totalRows += int64(p.rows)
+	p.rows = 0
+}
+
+// encodeDefinitionLevels encodes 0/1 definition levels with the RLE/bit-packing
+// hybrid at bit width 1, as a single bit-packed run.
+func encodeDefinitionLevels(defined []bool) []byte {
+	groups := (len(defined) + 7) / 8
+	out := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, d := range defined {
+		if d {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(out, packed...)
+}
+
+// Flush is a no-op: rows are written a row group at a time.
+func (p *parquetWriter) Flush() error { return p.err }
+
+func (p *parquetWriter) Close() error {
+	p.flushRowGroup()
+	if p.err != nil {
+		return p.err
+	}
+
+	var t thriftWriter
+	t.beginStruct()
+	t.i32(1, pqVersion)
+	t.listField(2, tStruct, len(p.cols)+1)
+	t.beginStruct()
+	t.string(4, "schema")
+	t.i32(5, int32(len(p.cols)))
+	t.endStruct()
+	for _, c := range p.cols {
+		t.beginStruct()
+		t.i32(1, physicalType(c.Kind))
+		t.i32(3, pqOptional)
+		t.string(4, c.Name)
+		switch c.Kind {
+		case KindTime:
+			t.i32(6, pqTSMillis)
+			t.structField(10) // LogicalType
+			t.structField(8)  // TIMESTAMP
+			t.bool(1, true)   // isAdjustedToUTC
+			t.structField(2)  // unit
+			t.structField(1)  // MILLIS
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+		case KindString:
+			t.i32(6, pqUTF8)
+			t.structField(10) // LogicalType
+			t.structField(1)  // STRING
+			t.endStruct()
+			t.endStruct()
+		}
+		t.endStruct()
+	}
+	t.i64(3, p.totalRows)
+	t.listField(4, tStruct, len(p.rowGroups))
+	for _, rg := range p.rowGroups {
+		t.beginStruct()
+		t.listField(1, tStruct, len(rg.chunks))
+		var total int64
+		for i, ch := range rg.chunks {
+			total += ch.size
+			t.beginStruct()
+			t.i64(2, ch.offset)
+			t.structField(3) // ColumnMetaData
+			t.i32(1, physicalType(p.cols[i].Kind))
+			t.i32List(2, []int32{pqPlain, pqRLE})
+			t.listField(3, tBinary, 1)
+			t.rawString(p.cols[i].Name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, ch.numValues)
+			t.i64(6, ch.size)
+			t.i64(7, ch.size)
+			t.i64(9, ch.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, total)
+		t.i64(3, rg.rows)
+		t.endStruct()
+	}
+	t.string(6, pqCreatedBy)
+	t.endStruct()
+
+	footer := t.buf.Bytes()
+	p.write(footer)
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(footer)))
+	p.write(n[:])
+	p.write([]byte(parquetMagic))
+	return p.err
+}
+
+func physicalType(k Kind) int32 {
+	switch k {
+	case KindFloat:
+		return pqDouble
+	case KindString:
+		return pqByteArray
+	default:
+		return pqInt64
+	}
+}
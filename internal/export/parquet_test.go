@@ -0,0 +1,269 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes compact-protocol structs into field ID → value maps
+// (lists as []any, nested structs as map[int16]any) to check the footer.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) int() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case tBoolTrue:
+		return true
+	case tBoolFalse:
+		return false
+	case tI32, tI64:
+		return r.int()
+	case tBinary:
+		n := int(r.uvarint())
+		s := string(r.b[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case tList:
+		h := r.b[r.pos]
+		r.pos++
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		out := make([]any, n)
+		for i := range out {
+			if elem == tBoolTrue || elem == tBoolFalse {
+				// Bool list elements are a byte each.
+				out[i] = r.b[r.pos] == 1
+				r.pos++
+				continue
+			}
+			out[i] = r.value(elem)
+		}
+		return out
+	case tStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	out := map[int16]any{}
+	var last int16
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return out
+		}
+		typ := h & 0x0f
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.int())
+		}
+		last = id
+		out[id] = r.value(typ)
+	}
+}
+
+var parquetTestCols = []Column{{Name: "time", Kind: KindTime}, {Name: "close", Kind: KindFloat}, {Name: "symbol", Kind: KindString}}
+
+func writeTestParquet(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	pw, err := NewWriter(&buf, FormatParquet, parquetTestCols, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Unix(1771699560, 0)
+	rows := [][]any{
+		{t0, 1.5, "NASDAQ:AAPL"},
+		{t0.Add(time.Minute), nil, "NASDAQ:AAPL"},
+		{t0.Add(2 * time.Minute), 2.25, nil},
+	}
+	for _, r := range rows {
+		if err := pw.WriteRow(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParquetWriterFooterAndPages(t *testing.T) {
+	b := writeTestParquet(t)
+	if string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatal("missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	r := &thriftReader{b: b[len(b)-8-footerLen : len(b)-8]}
+	meta := r.readStruct()
+	if meta[3] != int64(3) {
+		t.Fatalf("num_rows = %v", meta[3])
+	}
+	schema := meta[2].([]any)
+	if len(schema) != 4 || schema[1].(map[int16]any)[4] != "time" || schema[3].(map[int16]any)[1] != int64(pqByteArray) {
+		t.Fatalf("schema = %v", schema)
+	}
+	ts := schema[1].(map[int16]any)[10].(map[int16]any)[8].(map[int16]any)
+	if ts[1] != true {
+		t.Fatalf("timestamp logical type = %v", ts)
+	}
+
+	// Read the close column's page: header, definition levels, PLAIN doubles.
+	rg := meta[4].([]any)[0].(map[int16]any)
+	chunk := rg[1].([]any)[1].(map[int16]any)[3].(map[int16]any)
+	pr := &thriftReader{b: b, pos: int(chunk[9].(int64))}
+	hdr := pr.readStruct()
+	if hdr[5].(map[int16]any)[1] != int64(3) {
+		t.Fatalf("page num_values = %v", hdr)
+	}
+	levelsLen := int(binary.LittleEndian.Uint32(b[pr.pos:]))
+	levels := b[pr.pos+4 : pr.pos+4+levelsLen]
+	if levels[0] != 3 || levels[1] != 0b101 {
+		t.Fatalf("definition levels = %08b", levels)
+	}
+	vals := b[pr.pos+4+levelsLen:]
+	if math.Float64frombits(binary.LittleEndian.Uint64(vals)) != 1.5 || math.Float64frombits(binary.LittleEndian.Uint64(vals[8:])) != 2.25 {
+		t.Fatal("unexpected PLAIN values")
+	}
+}
+
+// readParquet decodes a flat file of optional columns with PLAIN data pages
+// into its footer and per-column values (nil where undefined).
+func readParquet(t *testing.T, b []byte) (map[int16]any, [][]any) {
+	t.Helper()
+	if string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatal("missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	meta := (&thriftReader{b: b[len(b)-8-footerLen : len(b)-8]}).readStruct()
+	cols := make([][]any, len(meta[2].([]any))-1)
+	for _, rg := range meta[4].([]any) {
+		for i, ch := range rg.(map[int16]any)[1].([]any) {
+			cm := ch.(map[int16]any)[3].(map[int16]any)
+			if cm[4] != int64(0) {
+				t.Fatalf("column %d codec = %v, want UNCOMPRESSED", i, cm[4])
+			}
+			pos, want := int(cm[9].(int64)), len(cols[i])+int(cm[5].(int64))
+			for len(cols[i]) < want {
+				pr := &thriftReader{b: b, pos: pos}
+				hdr := pr.readStruct()
+				if hdr[1] != int64(pqDataPage) {
+					t.Fatalf("column %d page type = %v", i, hdr[1])
+				}
+				page := b[pr.pos : pr.pos+int(hdr[3].(int64))]
+				pos = pr.pos + len(page)
+				n := int(hdr[5].(map[int16]any)[1].(int64))
+				levelsLen := int(binary.LittleEndian.Uint32(page))
+				vals := page[4+levelsLen:]
+				for _, defined := range decodeDefinitionLevels(page[4:4+levelsLen], n) {
+					if !defined {
+						cols[i] = append(cols[i], nil)
+						continue
+					}
+					switch cm[1] {
+					case int64(pqInt64):
+						cols[i] = append(cols[i], int64(binary.LittleEndian.Uint64(vals)))
+						vals = vals[8:]
+					case int64(pqDouble):
+						cols[i] = append(cols[i], math.Float64frombits(binary.LittleEndian.Uint64(vals)))
+						vals = vals[8:]
+					case int64(pqByteArray):
+						l := int(binary.LittleEndian.Uint32(vals))
+						cols[i] = append(cols[i], string(vals[4:4+l]))
+						vals = vals[4+l:]
+					default:
+						t.Fatalf("column %d physical type = %v", i, cm[1])
+					}
+				}
+			}
+		}
+	}
+	return meta, cols
+}
+
+// decodeDefinitionLevels decodes n bit-width-1 levels in the RLE/bit-packing
+// hybrid encoding.
+func decodeDefinitionLevels(b []byte, n int) []bool {
+	var out []bool
+	for len(out) < n {
+		h, k := binary.Uvarint(b)
+		b = b[k:]
+		if h&1 == 1 {
+			groups := int(h >> 1)
+			for i := 0; i < groups*8; i++ {
+				out = append(out, b[i/8]>>(i%8)&1 == 1)
+			}
+			b = b[groups:]
+			continue
+		}
+		for i := 0; i < int(h>>1); i++ {
+			out = append(out, b[0] == 1)
+		}
+		b = b[1:]
+	}
+	return out[:n]
+}
+
+// testdata/parquet-go.parquet holds the rows of writeTestParquet written by
+// github.com/xitongsys/parquet-go v1.6.2 (uncompressed, optional INT64
+// TIMESTAMP_MILLIS, DOUBLE and UTF8 columns). Our file must declare the
+// same column types and decode to the same values as the reference one.
+func TestParquetMatchesReferenceFile(t *testing.T) {
+	golden, err := os.ReadFile("testdata/parquet-go.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refMeta, refCols := readParquet(t, golden)
+	meta, cols := readParquet(t, writeTestParquet(t))
+
+	ms := int64(1771699560000)
+	want := [][]any{
+		{ms, ms + 60000, ms + 120000},
+		{1.5, nil, 2.25},
+		{"NASDAQ:AAPL", "NASDAQ:AAPL", nil},
+	}
+	if !reflect.DeepEqual(refCols, want) {
+		t.Fatalf("reference columns = %v", refCols)
+	}
+	if !reflect.DeepEqual(cols, want) {
+		t.Fatalf("columns = %v, want %v", cols, want)
+	}
+	if meta[3] != refMeta[3] {
+		t.Fatalf("num_rows = %v, reference %v", meta[3], refMeta[3])
+	}
+	refSchema, schema := refMeta[2].([]any), meta[2].([]any)
+	if len(schema) != len(refSchema) {
+		t.Fatalf("schema has %d elements, reference %d", len(schema), len(refSchema))
+	}
+	// type, repetition_type, name, converted_type, logicalType
+	for i := 1; i < len(schema); i++ {
+		for _, f := range []int16{1, 3, 4, 6, 10} {
+			got, ref := schema[i].(map[int16]any)[f], refSchema[i].(map[int16]any)[f]
+			if !reflect.DeepEqual(got, ref) {
+				t.Errorf("schema[%d] field %d = %v, reference %v", i, f, got, ref)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type IDs.
+const (
+	tBoolTrue  = 1
+	tBoolFalse = 2
+	tI32       = 5
+	tI64       = 6
+	tBinary    = 8
+	tList      = 9
+	tStruct    = 12
+)
+
+// thriftWriter encodes the Thrift compact protocol, which Parquet uses for
+// page headers and the file footer. Only the subset Parquet needs is
+// implemented.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastIDs []int16 // last field ID per open struct
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := t.lastIDs[len(t.lastIDs)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.lastIDs[len(t.lastIDs)-1] = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+// beginStruct starts a top-level struct or a list element.
+func (t *thriftWriter) beginStruct() { t.lastIDs = append(t.lastIDs, 0) }
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, tStruct)
+	t.beginStruct()
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, tI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, tI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.fieldHeader(id, tBoolTrue)
+	} else {
+		t.fieldHeader(id, tBoolFalse)
+	}
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.fieldHeader(id, tBinary)
+	t.rawString(v)
+}
+
+func (t *thriftWriter) rawString(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// listField writes a list field header; the caller then writes n elements.
+func (t *thriftWriter) listField(id int16, elem byte, n int) {
+	t.fieldHeader(id, tList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.varint(uint64(n))
+	}
+}
+
+func (t *thriftWriter) i32List(id int16, vals []int32) {
+	t.listField(id, tI32, len(vals))
+	for _, v := range vals {
+		t.varint(zigzag(int64(v)))
+	}
+}
@@ -0,0 +1,359 @@
+// Package har converts researcher captures into HTTP Archive (HAR 1.2)
+// files that Chrome DevTools and other HAR tooling can open.
+package har
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// HAR is the top-level archive.
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log holds the archive's entries.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Pages   []Page  `json:"pages"`
+	Entries []Entry `json:"entries"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// Creator names the tool that wrote the archive.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Page groups entries by page load. The researcher does not track page
+// loads, so Log.Pages is always empty.
+type Page struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	ID              string      `json:"id"`
+	Title           string      `json:"title"`
+	PageTimings     PageTimings `json:"pageTimings"`
+}
+
+// PageTimings is part of Page.
+type PageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// Entry is one request/response pair. WebSocket connections carry their
+// frames in the Chrome-compatible _webSocketMessages extension.
+type Entry struct {
+	StartedDateTime   time.Time          `json:"startedDateTime"`
+	Time              float64            `json:"time"`
+	Request           Request            `json:"request"`
+	Response          Response           `json:"response"`
+	Cache             struct{}           `json:"cache"`
+	Timings           Timings            `json:"timings"`
+	ResourceType      string             `json:"_resourceType,omitempty"`
+	RequestID         string             `json:"_requestId,omitempty"`
+	TabID             string             `json:"_tabId,omitempty"`
+	WebSocketMessages []WebSocketMessage `json:"_webSocketMessages,omitempty"`
+}
+
+// Request is the request half of an entry.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Response is the response half of an entry.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+	Comment     string      `json:"comment,omitempty"`
+}
+
+// NameValue is a header, cookie or query parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is a request body.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Content is a response body.
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Timings are unknown for captures, so all phases are reported as -1
+// except the required send, wait and receive.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// WebSocketMessage is one frame in the _webSocketMessages extension. Time
+// is Unix seconds with fractional milliseconds, as Chrome writes it.
+type WebSocketMessage struct {
+	Type   string  `json:"type"`
+	Time   float64 `json:"time"`
+	Opcode int     `json:"opcode"`
+	Data   string  `json:"data"`
+}
+
+var unknownTimings = Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+
+// Builder accumulates captures and builds a HAR. WebSocket events are
+// matched to their connection by request ID; connections without an HTTP
+// capture of the handshake get a synthesized 101 entry.
+type Builder struct {
+	creator Creator
+	http    []*types.HTTPCapture
+	ws      map[string]*wsConn
+	wsOrder []string
+}
+
+type wsConn struct {
+	first    time.Time
+	url      string
+	tabID    string
+	messages []WebSocketMessage
+	closed   *types.WebSocketCapture
+}
+
+// NewBuilder returns an empty Builder that records version as the
+// creator version.
+func NewBuilder(version string) *Builder {
+	return &Builder{
+		creator: Creator{Name: "MaudeViewTVCore researcher", Version: version},
+		ws:      make(map[string]*wsConn),
+	}
+}
+
+// AddHTTP adds an HTTP capture.
+func (b *Builder) AddHTTP(c *types.HTTPCapture) {
+	b.http = append(b.http, c)
+}
+
+// AddWebSocket adds a WebSocket event (created, frame_sent,
+// frame_received or closed).
+func (b *Builder) AddWebSocket(c *types.WebSocketCapture) {
+	conn, ok := b.ws[c.RequestID]
+	if !ok {
+		conn = &wsConn{first: c.Timestamp, url: c.URL, tabID: c.TabID}
+		b.ws[c.RequestID] = conn
+		b.wsOrder = append(b.wsOrder, c.RequestID)
+	}
+	if c.Timestamp.Before(conn.first) {
+		conn.first = c.Timestamp
+	}
+	switch c.EventType {
+	case "closed":
+		conn.closed = c
+	case "frame_sent", "frame_received":
+		typ := "receive"
+		if c.EventType == "frame_sent" {
+			typ = "send"
+		}
+		conn.messages = append(conn.messages, WebSocketMessage{
+			Type:   typ,
+			Time:   float64(c.Timestamp.UnixMicro()) / 1e6,
+			Opcode: c.Opcode,
+			Data:   c.PayloadData,
+		})
+	}
+}
+
+// Build returns the archive with entries ordered by start time.
+func (b *Builder) Build() HAR {
+	entries := make([]Entry, 0, len(b.http)+len(b.ws))
+	handshakes := make(map[string]bool)
+	for _, c := range b.http {
+		e := httpEntry(c)
+		if conn, ok := b.ws[c.RequestID]; ok {
+			e.ResourceType = "websocket"
+			e.WebSocketMessages = conn.sortedMessages()
+			handshakes[c.RequestID] = true
+		}
+		entries = append(entries, e)
+	}
+	for _, id := range b.wsOrder {
+		if !handshakes[id] {
+			entries = append(entries, b.ws[id].entry(id))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+	return HAR{Log: Log{
+		Version: "1.2",
+		Creator: b.creator,
+		Pages:   []Page{},
+		Entries: entries,
+	}}
+}
+
+func httpEntry(c *types.HTTPCapture) Entry {
+	e := Entry{
+		StartedDateTime: c.Timestamp,
+		Request:         request(c.Method, c.URL, c.Request.Headers),
+		// Replaced below when the capture has a response.
+		Response:  Response{Cookies: []NameValue{}, Headers: []NameValue{}, HeadersSize: -1, BodySize: -1},
+		Timings:   unknownTimings,
+		RequestID: c.RequestID,
+		TabID:     c.TabID,
+	}
+	if c.Request.PostData != "" {
+		e.Request.PostData = &PostData{MimeType: headerValue(c.Request.Headers, "Content-Type"), Text: c.Request.PostData}
+		e.Request.BodySize = len(c.Request.PostData)
+	}
+	if r := c.Response; r != nil {
+		e.Response = Response{
+			Status:      r.Status,
+			StatusText:  r.StatusText,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []NameValue{},
+			Headers:     nameValues(r.Headers),
+			RedirectURL: headerValue(r.Headers, "Location"),
+			HeadersSize: -1,
+			BodySize:    -1,
+			Content:     content(r),
+		}
+	}
+	return e
+}
+
+func content(r *types.HTTPResponse) Content {
+	c := Content{MimeType: headerValue(r.Headers, "Content-Type")}
+	switch {
+	case r.BodyBase64 != "":
+		c.Text, c.Encoding = r.BodyBase64, "base64"
+		if raw, err := base64.StdEncoding.DecodeString(r.BodyBase64); err == nil {
+			c.Size = len(raw)
+		}
+	default:
+		c.Text, c.Size = r.Body, len(r.Body)
+	}
+	if r.Truncated {
+		c.Size = r.OriginalSize
+		c.Comment = "body truncated at capture; sha256 of original: " + r.SHA256
+	}
+	return c
+}
+
+func (w *wsConn) entry(id string) Entry {
+	e := Entry{
+		StartedDateTime:   w.first,
+		Request:           request("GET", w.url, nil),
+		Timings:           unknownTimings,
+		ResourceType:      "websocket",
+		RequestID:         id,
+		TabID:             w.tabID,
+		WebSocketMessages: w.sortedMessages(),
+		Response: Response{
+			Status:      101,
+			StatusText:  "Switching Protocols",
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []NameValue{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    0,
+			Comment:     "handshake not captured; synthesized from WebSocket events",
+		},
+	}
+	if w.closed != nil {
+		e.Time = float64(w.closed.Timestamp.Sub(w.first).Microseconds()) / 1e3
+	}
+	return e
+}
+
+func (w *wsConn) sortedMessages() []WebSocketMessage {
+	msgs := append([]WebSocketMessage(nil), w.messages...)
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Time < msgs[j].Time })
+	return msgs
+}
+
+func request(method, rawURL string, headers map[string]string) Request {
+	r := Request{
+		Method:      method,
+		URL:         rawURL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []NameValue{},
+		Headers:     nameValues(headers),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		for _, kv := range strings.Split(u.RawQuery, "&") {
+			if kv == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(kv, "=")
+			if n, err := url.QueryUnescape(name); err == nil {
+				name = n
+			}
+			if v, err := url.QueryUnescape(value); err == nil {
+				value = v
+			}
+			r.QueryString = append(r.QueryString, NameValue{Name: name, Value: value})
+		}
+	}
+	if cookie := headerValue(headers, "Cookie"); cookie != "" {
+		for _, part := range strings.Split(cookie, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			r.Cookies = append(r.Cookies, NameValue{Name: name, Value: value})
+		}
+	}
+	return r
+}
+
+// nameValues converts a header map to a name-sorted list so output is
+// stable.
+func nameValues(m map[string]string) []NameValue {
+	out := make([]NameValue, 0, len(m))
+	for k, v := range m {
+		out = append(out, NameValue{Name: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func headerValue(m map[string]string, name string) string {
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package har
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+func TestBuildHTTPEntry(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := NewBuilder("test")
+	b.AddHTTP(&types.HTTPCapture{
+		Timestamp: ts,
+		RequestID: "1.1",
+		TabID:     "AAAA0000FFFF",
+		URL:       "https://www.tradingview.com/api/v1/symbols?q=AAPL&exchange=NASDAQ%3A",
+		Method:    "POST",
+		Request: types.HTTPRequest{
+			Headers:  map[string]string{"Content-Type": "application/json", "Cookie": "sessionid=abc; device=x"},
+			PostData: `{"a":1}`,
+		},
+		Response: &types.HTTPResponse{
+			Status:     200,
+			StatusText: "OK",
+			Headers:    map[string]string{"content-type": "application/octet-stream"},
+			BodyBase64: "AAEC",
+		},
+	})
+	h := b.Build()
+	if h.Log.Version != "1.2" || len(h.Log.Entries) != 1 {
+		t.Fatalf("log = %+v", h.Log)
+	}
+	e := h.Log.Entries[0]
+	if e.Request.Method != "POST" || e.Request.PostData == nil || e.Request.PostData.MimeType != "application/json" {
+		t.Fatalf("request = %+v", e.Request)
+	}
+	if len(e.Request.QueryString) != 2 || e.Request.QueryString[1] != (NameValue{"exchange", "NASDAQ:"}) {
+		t.Fatalf("query = %+v", e.Request.QueryString)
+	}
+	if len(e.Request.Cookies) != 2 || e.Request.Cookies[0] != (NameValue{"sessionid", "abc"}) {
+		t.Fatalf("cookies = %+v", e.Request.Cookies)
+	}
+	c := e.Response.Content
+	if e.Response.Status != 200 || c.Encoding != "base64" || c.Size != 3 || c.MimeType != "application/octet-stream" {
+		t.Fatalf("response = %+v", e.Response)
+	}
+	if e.ResourceType != "" || e.WebSocketMessages != nil {
+		t.Fatalf("unexpected websocket data on http entry: %+v", e)
+	}
+}
+
+func TestBuildWebSocketEntry(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := NewBuilder("test")
+	url := "wss://data.tradingview.com/socket.io/websocket"
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: ts, RequestID: "9.1", TabID: "T", URL: url, EventType: "created"})
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: ts.Add(2 * time.Second), RequestID: "9.1", URL: url, EventType: "frame_received", Direction: "incoming", Opcode: 1, PayloadData: "~m~4~m~~h~1"})
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: ts.Add(time.Second), RequestID: "9.1", URL: url, EventType: "frame_sent", Direction: "outgoing", Opcode: 1, PayloadData: "hello"})
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: ts.Add(3 * time.Second), RequestID: "9.1", URL: url, EventType: "closed", CloseCode: 1000})
+	b.AddHTTP(&types.HTTPCapture{Timestamp: ts.Add(-time.Second), RequestID: "1.1", URL: "https://www.tradingview.com/", Method: "GET"})
+
+	h := b.Build()
+	if len(h.Log.Entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(h.Log.Entries))
+	}
+	e := h.Log.Entries[1]
+	if e.ResourceType != "websocket" || e.Response.Status != 101 || e.Request.URL != url {
+		t.Fatalf("ws entry = %+v", e)
+	}
+	if e.Time != 3000 {
+		t.Fatalf("time = %v, want 3000", e.Time)
+	}
+	msgs := e.WebSocketMessages
+	if len(msgs) != 2 || msgs[0].Type != "send" || msgs[1].Type != "receive" || msgs[1].Data != "~m~4~m~~h~1" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if msgs[0].Time != float64(ts.Unix()+1) {
+		t.Fatalf("message time = %v", msgs[0].Time)
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Log struct {
+			Entries []map[string]json.RawMessage `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw.Log.Entries[1]["_webSocketMessages"]; !ok {
+		t.Fatalf("missing _webSocketMessages in %s", data)
+	}
+}
+
+func TestBuildAttachesMessagesToCapturedHandshake(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := NewBuilder("test")
+	b.AddHTTP(&types.HTTPCapture{Timestamp: ts, RequestID: "9.1", URL: "wss://x/ws", Method: "GET", Response: &types.HTTPResponse{Status: 101}})
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: ts, RequestID: "9.1", URL: "wss://x/ws", EventType: "frame_sent", Opcode: 1, PayloadData: "a"})
+	h := b.Build()
+	if len(h.Log.Entries) != 1 || len(h.Log.Entries[0].WebSocketMessages) != 1 {
+		t.Fatalf("entries = %+v", h.Log.Entries)
+	}
+}
@@ -0,0 +1,306 @@
+// Package jobs runs long operations (chart backfills, batch exports) in the
+// background and tracks their status, progress and result.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+	// StatusInterrupted marks a restored job that was running when the
+	// process stopped.
+	StatusInterrupted Status = "interrupted"
+)
+
+// Done reports whether the job has finished, successfully or not.
+func (s Status) Done() bool { return s != StatusRunning }
+
+// Job is a point-in-time view of a background job.
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Key        string     `json:"key,omitempty"`
+	Status     Status     `json:"status"`
+	Params     any        `json:"params,omitempty"`
+	Progress   any        `json:"progress,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// RunFunc does the work of a job. It should call report with progress as
+// it goes and return when ctx is canceled. The returned result is kept even
+// on error, so partial output can still be retrieved.
+type RunFunc func(ctx context.Context, report func(progress any)) (result any, err error)
+
+type ctxKey struct{}
+
+// IDFromContext returns the ID of the job whose RunFunc received ctx.
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// ErrBusy is returned by Start when a running job already holds the key.
+var ErrBusy = errors.New("a job with this key is already running")
+
+// ErrNotFound is returned for unknown job IDs.
+var ErrNotFound = errors.New("job not found")
+
+type entry struct {
+	job    Job
+	result any
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager tracks jobs in memory. Finished jobs and their results are
+// kept for the life of the process.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	jobs      map[string]*entry
+	observers []func(Job)
+	wg        sync.WaitGroup
+}
+
+// NewManager returns an empty Manager.
+func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{ctx: ctx, cancel: cancel, jobs: make(map[string]*entry)}
+}
+
+// Observe registers fn to be called with a copy of a job whenever it
+// starts, reports progress or finishes. fn runs on the job's goroutine.
+func (m *Manager) Observe(fn func(Job)) {
+	m.mu.Lock()
+	m.observers = append(m.observers, fn)
+	m.mu.Unlock()
+}
+
+// Start runs fn in a new goroutine. If key is non-empty, at most one job
+// holding the key runs at a time, whatever its kind, so jobs of different
+// kinds can share a lock (e.g. a chart ID); a second Start returns ErrBusy.
+// params describes the request and is kept with the job.
+func (m *Manager) Start(kind, key string, params, progress any, fn RunFunc) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.busy(key) {
+		return Job{}, ErrBusy
+	}
+	now := time.Now().UTC()
+	e := &entry{job: Job{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Key:       key,
+		Status:    StatusRunning,
+		Params:    params,
+		Progress:  progress,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}
+	m.jobs[e.job.ID] = e
+	m.launch(e, fn)
+	return e.job, nil
+}
+
+// Restore registers a finished job loaded from disk, so it can be listed
+// and resumed with Rerun. A job saved as running becomes interrupted.
+func (m *Manager) Restore(job Job) {
+	if job.Status == StatusRunning {
+		job.Status = StatusInterrupted
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.jobs[job.ID]; !ok {
+		m.jobs[job.ID] = &entry{job: job}
+	}
+}
+
+// Rerun starts a finished job again under the same ID, keeping its params
+// and last progress for fn to resume from.
+func (m *Manager) Rerun(id string, fn RunFunc) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if !e.job.Status.Done() || m.busy(e.job.Key) {
+		return Job{}, ErrBusy
+	}
+	e.job.Status = StatusRunning
+	e.job.Error = ""
+	e.job.FinishedAt = nil
+	e.job.UpdatedAt = time.Now().UTC()
+	e.result = nil
+	m.launch(e, fn)
+	return e.job, nil
+}
+
+// busy reports whether a running job holds key. m.mu must be held.
+func (m *Manager) busy(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, e := range m.jobs {
+		if e.job.Key == key && !e.job.Status.Done() {
+			return true
+		}
+	}
+	return false
+}
+
+// launch starts e running fn. m.mu must be held.
+func (m *Manager) launch(e *entry, fn RunFunc) {
+	ctx, cancel := context.WithCancel(context.WithValue(m.ctx, ctxKey{}, e.job.ID))
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	m.wg.Add(1)
+	go m.run(ctx, cancel, e, e.done, fn)
+}
+
+func (m *Manager) run(ctx context.Context, cancel context.CancelFunc, e *entry, done chan struct{}, fn RunFunc) {
+	defer m.wg.Done()
+	defer close(done)
+	defer cancel()
+
+	m.notify(e)
+	result, err := fn(ctx, func(progress any) {
+		m.mu.Lock()
+		e.job.Progress = progress
+		e.job.UpdatedAt = time.Now().UTC()
+		m.mu.Unlock()
+		m.notify(e)
+	})
+	defer m.notify(e)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	e.result = result
+	e.job.UpdatedAt = now
+	e.job.FinishedAt = &now
+	switch {
+	case err == nil:
+		e.job.Status = StatusCompleted
+	case ctx.Err() != nil && errors.Is(err, context.Canceled):
+		e.job.Status = StatusCanceled
+	default:
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+	}
+}
+
+// notify calls the observers with a snapshot of e.
+func (m *Manager) notify(e *entry) {
+	m.mu.Lock()
+	job, observers := e.job, m.observers
+	m.mu.Unlock()
+	for _, fn := range observers {
+		fn(job)
+	}
+}
+
+// Get returns the current state of a job.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.job, nil
+}
+
+// Result returns the job state and its result, which is nil until the job
+// has finished.
+func (m *Manager) Result(id string) (Job, any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, nil, ErrNotFound
+	}
+	return e.job, e.result, nil
+}
+
+// List returns jobs of the given kind (all kinds if empty), newest first.
+func (m *Manager) List(kind string) []Job {
+	m.mu.Lock()
+	out := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		if kind == "" || e.job.Kind == kind {
+			out = append(out, e.job)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// Cancel stops a running job and waits for it to finish. Canceling a
+// finished job is a no-op.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	var cancel context.CancelFunc
+	var done chan struct{}
+	if ok {
+		cancel, done = e.cancel, e.done
+	}
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return m.Get(id)
+}
+
+// Wait blocks until the job has finished or ctx is done.
+func (m *Manager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	var done chan struct{}
+	if ok {
+		done = e.done
+	}
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if done == nil {
+		return m.Get(id)
+	}
+	select {
+	case <-done:
+		return m.Get(id)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// Close cancels all running jobs and waits for them to return.
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
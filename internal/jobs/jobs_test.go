@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestManagerLifecycle(t *testing.T) {
+	m := NewManager()
+	defer m.Close()
+
+	started := make(chan struct{})
+	job, err := m.Start("backfill", "chart-1", nil, 0, func(ctx context.Context, report func(any)) (any, error) {
+		if IDFromContext(ctx) == "" {
+			t.Error("RunFunc context has no job ID")
+		}
+		report(1)
+		close(started)
+		<-ctx.Done()
+		return "partial", ctx.Err()
+	})
+	if err != nil || job.Status != StatusRunning {
+		t.Fatalf("Start() = %+v, %v", job, err)
+	}
+	<-started
+	if _, err := m.Start("backfill", "chart-1", nil, 0, nil); !errors.Is(err, ErrBusy) {
+		t.Fatalf("second Start() err = %v, want ErrBusy", err)
+	}
+	if _, err := m.Start("batch_export", "chart-1", nil, 0, nil); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Start() of another kind on the same key err = %v, want ErrBusy", err)
+	}
+	if got, _ := m.Get(job.ID); got.Progress != 1 {
+		t.Fatalf("progress = %v, want 1", got.Progress)
+	}
+
+	job, err = m.Cancel(job.ID)
+	if err != nil || job.Status != StatusCanceled || job.FinishedAt == nil {
+		t.Fatalf("Cancel() = %+v, %v", job, err)
+	}
+	if _, result, _ := m.Result(job.ID); result != "partial" {
+		t.Fatalf("result = %v, want partial", result)
+	}
+
+	failed, _ := m.Start("backfill", "chart-1", nil, nil, func(ctx context.Context, report func(any)) (any, error) {
+		return nil, errors.New("chart changed")
+	})
+	failed, _ = m.Wait(context.Background(), failed.ID)
+	if failed.Status != StatusFailed || failed.Error != "chart changed" {
+		t.Fatalf("failed job = %+v", failed)
+	}
+	if list := m.List("backfill"); len(list) != 2 || list[0].ID != failed.ID {
+		t.Fatalf("List() = %+v", list)
+	}
+	if _, err := m.Get("nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(unknown) err = %v", err)
+	}
+}
+
+func TestStoreRestoresInterruptedJobs(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager()
+	reported := make(chan struct{})
+	m.Observe(func(j Job) {
+		if err := store.Save(j); err != nil {
+			t.Error(err)
+		}
+		if j.Progress != nil && j.Status == StatusRunning {
+			close(reported)
+		}
+	})
+	block := make(chan struct{})
+	job, _ := m.Start("batch_export", "chart-1", map[string]any{"symbols": []string{"AAPL"}}, nil, func(ctx context.Context, report func(any)) (any, error) {
+		report(map[string]int{"done": 1})
+		<-block
+		return nil, nil
+	})
+	// Simulate a crash: the last saved state is still running.
+	<-reported
+	saved, err := store.Load("batch_export")
+	close(block)
+	m.Close()
+	if err != nil || len(saved) != 1 || saved[0].Status != StatusRunning {
+		t.Fatalf("Load() = %+v, %v", saved, err)
+	}
+	var progress map[string]int
+	if err := json.Unmarshal(saved[0].Progress.(json.RawMessage), &progress); err != nil || progress["done"] != 1 {
+		t.Fatalf("saved progress = %s", saved[0].Progress)
+	}
+	if other, _ := store.Load("backfill"); len(other) != 0 {
+		t.Fatalf("Load(other kind) = %+v", other)
+	}
+
+	m2 := NewManager()
+	defer m2.Close()
+	m2.Restore(saved[0])
+	if got, _ := m2.Get(job.ID); got.Status != StatusInterrupted {
+		t.Fatalf("restored status = %q", got.Status)
+	}
+	rerun, err := m2.Rerun(job.ID, func(ctx context.Context, report func(any)) (any, error) {
+		if IDFromContext(ctx) != job.ID {
+			t.Errorf("rerun job ID = %q", IDFromContext(ctx))
+		}
+		return nil, nil
+	})
+	if err != nil || rerun.ID != job.ID {
+		t.Fatalf("Rerun() = %+v, %v", rerun, err)
+	}
+	if got, _ := m2.Wait(context.Background(), job.ID); got.Status != StatusCompleted {
+		t.Fatalf("rerun status = %q", got.Status)
+	}
+}
+
+func TestStoreSavesResults(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := "0b8f7c52-3c1e-4d8e-9d0b-1f2a3b4c5d6e"
+	var res struct {
+		Bars [][]any `json:"bars"`
+	}
+	if err := store.LoadResult(id, &res); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadResult(unsaved) err = %v, want os.ErrNotExist", err)
+	}
+	if err := store.SaveResult(id, map[string]any{"bars": [][]any{{1771632000.0, 191.02, nil}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.LoadResult(id, &res); err != nil || len(res.Bars) != 1 || res.Bars[0][1] != 191.02 || res.Bars[0][2] != nil {
+		t.Fatalf("LoadResult() = %+v, %v", res, err)
+	}
+	if err := store.SaveResult("../escape", nil); err == nil {
+		t.Fatal("SaveResult accepted an invalid job id")
+	}
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var jobIDRe = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Store persists jobs as <dir>/<id>/job.json, next to any files the job
+// writes into its directory and the result.json of jobs whose results are
+// too large to keep in memory.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a Store and ensures the directory exists.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("job store: mkdir %s: %w", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir returns the job's directory, creating it if needed.
+func (s *Store) Dir(id string) (string, error) {
+	if !jobIDRe.MatchString(id) {
+		return "", fmt.Errorf("invalid job id: %q", id)
+	}
+	dir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("job store: mkdir %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// Save atomically writes the job's state.
+func (s *Store) Save(job Job) error {
+	dir, err := s.Dir(job.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "job.json.tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "job.json"))
+}
+
+// SaveResult atomically writes v as the job's result.json.
+func (s *Store) SaveResult(id string, v any) error {
+	dir, err := s.Dir(id)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "result.json.tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = json.NewEncoder(w).Encode(v)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("job store: write result of %s: %w", id, err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, "result.json"))
+}
+
+// LoadResult decodes the job's result.json into v. The error wraps
+// os.ErrNotExist if the job saved no result.
+func (s *Store) LoadResult(id string, v any) error {
+	if !jobIDRe.MatchString(id) {
+		return fmt.Errorf("invalid job id: %q", id)
+	}
+	f, err := os.Open(filepath.Join(s.dir, id, "result.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(v); err != nil {
+		return fmt.Errorf("job store: read result of %s: %w", id, err)
+	}
+	return nil
+}
+
+// Load returns the saved jobs of the given kind. Params and Progress are
+// left as json.RawMessage for the owner of the kind to decode. Unreadable
+// job files are logged and skipped.
+func (s *Store) Load(kind string) ([]Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("job store: read %s: %w", s.dir, err)
+	}
+	var out []Job
+	for _, ent := range entries {
+		if !ent.IsDir() || !jobIDRe.MatchString(ent.Name()) {
+			continue
+		}
+		path := filepath.Join(s.dir, ent.Name(), "job.json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				slog.Warn("job store: skipping unreadable job", "path", path, "error", err)
+			}
+			continue
+		}
+		var raw struct {
+			Job
+			Params   json.RawMessage `json:"params"`
+			Progress json.RawMessage `json:"progress"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			slog.Warn("job store: skipping corrupt job", "path", path, "error", err)
+			continue
+		}
+		if raw.Kind != kind || raw.ID != ent.Name() {
+			continue
+		}
+		job := raw.Job
+		if len(raw.Params) > 0 {
+			job.Params = raw.Params
+		}
+		if len(raw.Progress) > 0 {
+			job.Progress = raw.Progress
+		}
+		out = append(out, job)
+	}
+	return out, nil
+}
@@ -0,0 +1,336 @@
+// Package query filters and aggregates researcher captures read back from
+// the JSONL tree written by storage.WriterRegistry.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// Message type keys for split WebSocket messages that carry no "m" field.
+const (
+	TypeHeartbeat = "~h~"
+	TypeRaw       = "(raw)"
+)
+
+// Record is a decoded capture line. Raw is the original JSON.
+type Record struct {
+	DataType  string // "http" or "websocket"
+	Timestamp time.Time
+	TabID     string
+	URL       string
+	Method    string // http only
+	Status    int    // http only; 0 when no response was captured
+	EventType string // websocket event, or types.EventCaptureGap for gap markers in either stream
+	Direction string // websocket only: incoming or outgoing
+	Messages  []Message
+	Bytes     int
+	Raw       json.RawMessage
+}
+
+// Message is one ~m~ message of a WebSocket frame.
+type Message struct {
+	Type  string
+	Bytes int
+}
+
+// Parse decodes a capture line of the given data type.
+func Parse(dataType string, line []byte) (Record, error) {
+	r := Record{DataType: dataType, Raw: append(json.RawMessage(nil), line...)}
+	if types.IsCaptureGap(line) {
+		var g types.CaptureGap
+		if err := json.Unmarshal(line, &g); err != nil {
+			return r, err
+		}
+		r.Timestamp, r.TabID, r.URL, r.EventType = g.Timestamp, g.TabID, g.URL, g.EventType
+		return r, nil
+	}
+	switch dataType {
+	case "http":
+		var c types.HTTPCapture
+		if err := json.Unmarshal(line, &c); err != nil {
+			return r, err
+		}
+		r.Timestamp, r.TabID, r.URL, r.Method = c.Timestamp, c.TabID, c.URL, c.Method
+		r.Bytes = len(c.Request.PostData)
+		if c.Response != nil {
+			r.Status = c.Response.Status
+			r.Bytes += responseSize(c.Response)
+		}
+	case "websocket":
+		var c types.WebSocketCapture
+		if err := json.Unmarshal(line, &c); err != nil {
+			return r, err
+		}
+		r.Timestamp, r.TabID, r.URL = c.Timestamp, c.TabID, c.URL
+		r.EventType, r.Direction = c.EventType, c.Direction
+		r.Bytes = len(c.PayloadData)
+		if c.Truncated {
+			r.Bytes = c.OriginalSize
+		}
+		for _, raw := range tvproto.SplitFrames(c.PayloadData) {
+			r.Messages = append(r.Messages, Message{Type: messageType(raw), Bytes: len(raw)})
+		}
+	default:
+		return r, fmt.Errorf("unknown data type %q", dataType)
+	}
+	return r, nil
+}
+
+func responseSize(r *types.HTTPResponse) int {
+	switch {
+	case r.Truncated:
+		return r.OriginalSize
+	case r.BodyBase64 != "":
+		return len(r.BodyBase64) / 4 * 3
+	default:
+		return len(r.Body)
+	}
+}
+
+func messageType(raw string) string {
+	if tvproto.IsHeartbeat(raw) {
+		return TypeHeartbeat
+	}
+	if msg, ok := tvproto.ParseMessage(raw); ok && msg.Type != "" {
+		return msg.Type
+	}
+	return TypeRaw
+}
+
+// Filter selects records. Zero fields match everything. HTTP-only fields
+// (Methods, Statuses) exclude WebSocket records when set, and
+// WebSocket-only fields (Types, Direction) exclude HTTP records.
+type Filter struct {
+	From, To  time.Time // To is exclusive
+	URL       *regexp.Regexp
+	Methods   []string
+	Statuses  []StatusMatch
+	Types     []string
+	Direction string
+	Tab       string // target or browser ID prefix, case-insensitive
+}
+
+// StatusMatch matches one status code or, with Class set, a class such as
+// 4xx.
+type StatusMatch struct {
+	Code  int
+	Class bool
+}
+
+// ParseStatuses parses a comma-separated list like "200,404,5xx".
+func ParseStatuses(s string) ([]StatusMatch, error) {
+	var out []StatusMatch
+	for _, part := range SplitList(s) {
+		if len(part) == 3 && strings.HasSuffix(strings.ToLower(part), "xx") && part[0] >= '1' && part[0] <= '5' {
+			out = append(out, StatusMatch{Code: int(part[0]-'0') * 100, Class: true})
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q", part)
+		}
+		out = append(out, StatusMatch{Code: code})
+	}
+	return out, nil
+}
+
+func (m StatusMatch) match(status int) bool {
+	if m.Class {
+		return status/100*100 == m.Code
+	}
+	return status == m.Code
+}
+
+// Match reports whether r passes every filter.
+func (f Filter) Match(r Record) bool {
+	if !f.From.IsZero() && r.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.Timestamp.Before(f.To) {
+		return false
+	}
+	if f.Tab != "" && !strings.HasPrefix(strings.ToUpper(r.TabID), strings.ToUpper(f.Tab)) {
+		return false
+	}
+	if f.URL != nil && !f.URL.MatchString(r.URL) {
+		return false
+	}
+	if len(f.Methods) > 0 && (r.DataType != "http" || !containsFold(f.Methods, r.Method)) {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		if r.DataType != "http" {
+			return false
+		}
+		ok := false
+		for _, m := range f.Statuses {
+			ok = ok || m.match(r.Status)
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.Direction != "" && (r.DataType != "websocket" || r.Direction != f.Direction) {
+		return false
+	}
+	if len(f.Types) > 0 {
+		return r.DataType == "websocket" && len(f.messages(r)) > 0
+	}
+	return true
+}
+
+// messages returns r's messages that pass the type filter.
+func (f Filter) messages(r Record) []Message {
+	if len(f.Types) == 0 {
+		return r.Messages
+	}
+	var out []Message
+	for _, m := range r.Messages {
+		if slices.Contains(f.Types, m.Type) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// DataType returns the only data type the filter can match, or "" if it
+// can match both.
+func (f Filter) DataType() string {
+	switch {
+	case len(f.Methods) > 0 || len(f.Statuses) > 0:
+		return "http"
+	case len(f.Types) > 0 || f.Direction != "":
+		return "websocket"
+	}
+	return ""
+}
+
+// Grouping keys for an Aggregator.
+const (
+	GroupEndpoint = "endpoint"
+	GroupType     = "type"
+)
+
+// Group is one row of an aggregation.
+type Group struct {
+	Key       string    `json:"key"`
+	Count     int       `json:"count"`
+	Bytes     int64     `json:"bytes"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Aggregator counts records and bytes per group.
+type Aggregator struct {
+	by     string
+	filter Filter
+	groups map[string]*Group
+}
+
+// NewAggregator groups by GroupEndpoint (method and URL without query
+// for HTTP, WS and URL for WebSocket frames) or GroupType (the "m" type of
+// each WebSocket message; HTTP records count as "http"). Under GroupType
+// only messages passing f's type filter are counted.
+func NewAggregator(by string, f Filter) (*Aggregator, error) {
+	if by != GroupEndpoint && by != GroupType {
+		return nil, fmt.Errorf("unknown grouping %q (want %s or %s)", by, GroupEndpoint, GroupType)
+	}
+	return &Aggregator{by: by, filter: f, groups: make(map[string]*Group)}, nil
+}
+
+// Add counts a matching record. Capture gap markers are not counted.
+func (a *Aggregator) Add(r Record) {
+	if r.EventType == types.EventCaptureGap {
+		return
+	}
+	if a.by == GroupType && r.DataType == "websocket" {
+		for _, m := range a.filter.messages(r) {
+			a.add(m.Type, m.Bytes, r.Timestamp)
+		}
+		return
+	}
+	if a.by == GroupType {
+		a.add("http", r.Bytes, r.Timestamp)
+		return
+	}
+	if r.DataType == "websocket" && r.EventType != "frame_sent" && r.EventType != "frame_received" {
+		return
+	}
+	a.add(Endpoint(r), r.Bytes, r.Timestamp)
+}
+
+func (a *Aggregator) add(key string, bytes int, ts time.Time) {
+	g, ok := a.groups[key]
+	if !ok {
+		g = &Group{Key: key, FirstSeen: ts, LastSeen: ts}
+		a.groups[key] = g
+	}
+	g.Count++
+	g.Bytes += int64(bytes)
+	if ts.Before(g.FirstSeen) {
+		g.FirstSeen = ts
+	}
+	if ts.After(g.LastSeen) {
+		g.LastSeen = ts
+	}
+}
+
+// Groups returns the groups by descending count, then key.
+func (a *Aggregator) Groups() []Group {
+	out := make([]Group, 0, len(a.groups))
+	for _, g := range a.groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// Endpoint returns "<METHOD> host/path" for HTTP records and
+// "WS host/path" for WebSocket records, dropping the query string.
+func Endpoint(r Record) string {
+	method := r.Method
+	if r.DataType == "websocket" {
+		method = "WS"
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return method + " " + r.URL
+	}
+	return method + " " + u.Host + u.Path
+}
+
+// SplitList splits a comma-separated flag value, dropping empty items.
+func SplitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package query
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+func mustParse(t *testing.T, dataType, line string) Record {
+	t.Helper()
+	r, err := Parse(dataType, []byte(line))
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", line, err)
+	}
+	return r
+}
+
+const (
+	httpLine = `{"timestamp":"2026-01-02T10:00:00Z","request_id":"1","tab_id":"ABCD1234EF","url":"https://www.tradingview.com/api/v1/study-templates?x=1","method":"POST","request":{"post_data":"abcd"},"response":{"status":404,"status_text":"Not Found","body":"nope"}}`
+	wsLine   = `{"timestamp":"2026-01-02T10:00:01Z","request_id":"2","tab_id":"ABCD1234EF","url":"wss://data.tradingview.com/socket.io/websocket?from=chart","event_type":"frame_received","direction":"incoming","opcode":1,"payload_data":"~m~26~m~{\"m\":\"du\",\"p\":[\"cs_1\",{}]}~m~4~m~~h~1"}`
+)
+
+func TestParse(t *testing.T) {
+	h := mustParse(t, "http", httpLine)
+	if h.Method != "POST" || h.Status != 404 || h.Bytes != 8 {
+		t.Fatalf("http record = %+v", h)
+	}
+	w := mustParse(t, "websocket", wsLine)
+	if len(w.Messages) != 2 || w.Messages[0] != (Message{"du", 26}) || w.Messages[1].Type != TypeHeartbeat {
+		t.Fatalf("ws messages = %+v", w.Messages)
+	}
+	if _, err := Parse("http", []byte("{")); err == nil {
+		t.Fatal("expected error for truncated line")
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	h := mustParse(t, "http", httpLine)
+	w := mustParse(t, "websocket", wsLine)
+	statuses, err := ParseStatuses("200, 4xx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		f        Filter
+		http, ws bool
+	}{
+		{"empty", Filter{}, true, true},
+		{"url", Filter{URL: regexp.MustCompile(`study-templates`)}, true, false},
+		{"method", Filter{Methods: []string{"post"}}, true, false},
+		{"status class", Filter{Statuses: statuses}, true, false},
+		{"status miss", Filter{Statuses: []StatusMatch{{Code: 200}}}, false, false},
+		{"type", Filter{Types: []string{"du"}}, false, true},
+		{"type miss", Filter{Types: []string{"qsd"}}, false, false},
+		{"direction", Filter{Direction: "incoming"}, false, true},
+		{"tab prefix", Filter{Tab: "abcd1234"}, true, true},
+		{"tab miss", Filter{Tab: "FFFF"}, false, false},
+		{"time window", Filter{From: time.Date(2026, 1, 2, 10, 0, 1, 0, time.UTC)}, false, true},
+		{"to exclusive", Filter{To: time.Date(2026, 1, 2, 10, 0, 1, 0, time.UTC)}, true, false},
+	}
+	for _, tc := range cases {
+		if got := tc.f.Match(h); got != tc.http {
+			t.Errorf("%s: http match = %v, want %v", tc.name, got, tc.http)
+		}
+		if got := tc.f.Match(w); got != tc.ws {
+			t.Errorf("%s: ws match = %v, want %v", tc.name, got, tc.ws)
+		}
+	}
+	if _, err := ParseStatuses("abc"); err == nil {
+		t.Fatal("expected error for invalid status")
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	h := mustParse(t, "http", httpLine)
+	w := mustParse(t, "websocket", wsLine)
+
+	a, err := NewAggregator(GroupEndpoint, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Add(h)
+	a.Add(w)
+	a.Add(w)
+	groups := a.Groups()
+	if len(groups) != 2 || groups[0].Key != "WS data.tradingview.com/socket.io/websocket" || groups[0].Count != 2 {
+		t.Fatalf("endpoint groups = %+v", groups)
+	}
+	if groups[1].Key != "POST www.tradingview.com/api/v1/study-templates" || groups[1].Bytes != 8 {
+		t.Fatalf("endpoint groups = %+v", groups)
+	}
+
+	a, _ = NewAggregator(GroupType, Filter{Types: []string{"du"}})
+	a.Add(w)
+	groups = a.Groups()
+	if len(groups) != 1 || groups[0].Key != "du" || groups[0].Bytes != 26 {
+		t.Fatalf("type groups = %+v", groups)
+	}
+
+	if _, err := NewAggregator("url", Filter{}); err == nil {
+		t.Fatal("expected error for unknown grouping")
+	}
+}
+
+func TestCaptureGap(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 10, 5, 0, 0, time.UTC)
+	line, _ := json.Marshal(types.CaptureGap{Timestamp: ts, EventType: types.EventCaptureGap, TabID: "ABCD1234EF", GapStart: ts.Add(-time.Minute), GapEnd: ts, Reason: "browser_disconnected"})
+	if !types.IsCaptureGap(line) || types.IsCaptureGap([]byte(wsLine)) {
+		t.Fatal("IsCaptureGap misclassified a line")
+	}
+	g := mustParse(t, "http", string(line))
+	if g.EventType != types.EventCaptureGap || !g.Timestamp.Equal(ts) || g.TabID != "ABCD1234EF" {
+		t.Fatalf("gap record = %+v", g)
+	}
+	if !(Filter{Tab: "abcd"}).Match(g) || (Filter{Methods: []string{"GET"}}).Match(g) {
+		t.Fatal("gap filter match")
+	}
+	a, _ := NewAggregator(GroupEndpoint, Filter{})
+	a.Add(g)
+	if len(a.Groups()) != 0 {
+		t.Fatalf("gap was aggregated: %+v", a.Groups())
+	}
+}
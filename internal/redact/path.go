@@ -0,0 +1,33 @@
+package redact
+
+import "github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+
+// apply replaces every value of doc at steps with fn(value) and returns the
+// updated document. Paths that do not resolve are ignored.
+func apply(doc any, steps []tvproto.PathStep, fn func(any) any) any {
+	if len(steps) == 0 {
+		return fn(doc)
+	}
+	st, rest := steps[0], steps[1:]
+	switch v := doc.(type) {
+	case map[string]any:
+		if st.Key == "" {
+			return doc
+		}
+		for k, child := range v {
+			if st.Any || k == st.Key {
+				v[k] = apply(child, rest, fn)
+			}
+		}
+	case []any:
+		if st.Key != "" {
+			return doc
+		}
+		for i, child := range v {
+			if st.Any || i == st.Index {
+				v[i] = apply(child, rest, fn)
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,359 @@
+// Package redact scrubs secrets from researcher captures before they are
+// written: denylisted headers, query parameters, JSON body paths and
+// anything that looks like a JWT. Values are replaced by a fixed mask or,
+// in hash mode, by a keyed hash so equal values stay correlatable across
+// records; an optional vault file maps hashes back to the originals.
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// Mask replaces redacted values in mask mode.
+const Mask = "[REDACTED]"
+
+// Redaction modes.
+const (
+	ModeMask = "mask"
+	ModeHash = "hash"
+)
+
+// Config is the YAML redaction configuration.
+type Config struct {
+	// Enabled turns redaction on. A missing config file also disables it.
+	Enabled bool `yaml:"enabled"`
+	// Mode is "mask" (default) or "hash".
+	Mode string `yaml:"mode,omitempty"`
+	// HashKey keys the HMAC in hash mode; ${VAR} references are expanded.
+	HashKey string `yaml:"hash_key,omitempty"`
+	// Vault, in hash mode, is a JSONL file that each new hash is appended
+	// to with its original value, so the capture owner can reverse it.
+	// Never share it with the captures.
+	Vault string `yaml:"vault,omitempty"`
+	// Headers are request and response header names, case-insensitive.
+	Headers []string `yaml:"headers,omitempty"`
+	// QueryParams are URL query parameter names, case-insensitive.
+	QueryParams []string `yaml:"query_params,omitempty"`
+	// BodyPaths mask values in JSON request/response bodies and WebSocket
+	// messages.
+	BodyPaths []BodyPath `yaml:"body_paths,omitempty"`
+	// JWT redacts anything shaped like a JSON Web Token in URLs, headers,
+	// bodies and frames.
+	JWT bool `yaml:"jwt,omitempty"`
+}
+
+// BodyPath selects JSON values to redact. Path uses the relay filter
+// syntax ("$.user.email", "items[*].token", "p[0]") plus "*" for any object
+// key. WebSocket paths are evaluated against each message as sent (the
+// {"m","p"} object on the chart socket). When MessageTypes is set the path
+// only applies to WebSocket messages of those types.
+type BodyPath struct {
+	Path         string   `yaml:"path"`
+	MessageTypes []string `yaml:"message_types,omitempty"`
+}
+
+// LoadConfig reads a redaction YAML file. An empty path returns a disabled
+// config; a missing file is an error, so a mistyped path cannot silently
+// turn redaction off.
+func LoadConfig(path string) (Config, error) {
+	if path == "" {
+		return Config{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("redact config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("redact config: %w", err)
+	}
+	return cfg, nil
+}
+
+var jwtRe = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)
+
+// Redactor applies a Config. It is safe for concurrent use.
+type Redactor struct {
+	mode    string
+	key     []byte
+	headers map[string]bool
+	params  map[string]bool
+	paths   []compiledPath
+	jwt     bool
+
+	vaultMu   sync.Mutex
+	vault     *os.File
+	vaultSeen map[string]bool
+}
+
+type compiledPath struct {
+	steps []tvproto.PathStep
+	types map[string]bool
+}
+
+// New validates cfg and returns a Redactor, or nil if cfg is disabled.
+func New(cfg Config) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r := &Redactor{mode: cfg.Mode, headers: lowerSet(cfg.Headers), params: lowerSet(cfg.QueryParams), jwt: cfg.JWT}
+	switch r.mode {
+	case "":
+		r.mode = ModeMask
+	case ModeMask:
+	case ModeHash:
+		key := os.ExpandEnv(cfg.HashKey)
+		if key == "" {
+			return nil, fmt.Errorf("redact config: hash mode needs hash_key")
+		}
+		r.key = []byte(key)
+	default:
+		return nil, fmt.Errorf("redact config: invalid mode %q", cfg.Mode)
+	}
+	if cfg.Vault != "" && r.mode != ModeHash {
+		return nil, fmt.Errorf("redact config: vault needs hash mode")
+	}
+	for i, bp := range cfg.BodyPaths {
+		steps, err := tvproto.ParsePath(bp.Path)
+		if err != nil {
+			return nil, fmt.Errorf("redact config: body_paths[%d]: %w", i, err)
+		}
+		r.paths = append(r.paths, compiledPath{steps: steps, types: lowerSet(bp.MessageTypes)})
+	}
+	if cfg.Vault != "" {
+		f, err := os.OpenFile(cfg.Vault, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("redact vault: %w", err)
+		}
+		r.vault, r.vaultSeen = f, make(map[string]bool)
+	}
+	return r, nil
+}
+
+// Mode returns ModeMask or ModeHash.
+func (r *Redactor) Mode() string { return r.mode }
+
+// Close closes the vault file.
+func (r *Redactor) Close() error {
+	if r == nil || r.vault == nil {
+		return nil
+	}
+	r.vaultMu.Lock()
+	defer r.vaultMu.Unlock()
+	return r.vault.Close()
+}
+
+// Apply redacts a *types.HTTPCapture, *types.WebSocketCapture or the URL of
+// a *storage.ResourceEntry in place. Other records are left alone. It
+// matches storage.RecordFilter.
+func (r *Redactor) Apply(record any) {
+	switch c := record.(type) {
+	case *types.HTTPCapture:
+		r.HTTP(c)
+	case *types.WebSocketCapture:
+		r.WebSocket(c)
+	case *storage.ResourceEntry:
+		c.URL = r.url(c.URL)
+	}
+}
+
+// HTTP redacts an HTTP capture in place.
+func (r *Redactor) HTTP(c *types.HTTPCapture) {
+	c.URL = r.url(c.URL)
+	r.headerMap(c.Request.Headers)
+	c.Request.PostData = r.body(c.Request.PostData)
+	if resp := c.Response; resp != nil {
+		r.headerMap(resp.Headers)
+		resp.Body = r.body(resp.Body)
+		resp.StatusText = r.text(resp.StatusText)
+	}
+}
+
+// WebSocket redacts a WebSocket event in place. Frames are split into
+// their ~m~ messages, redacted and re-framed.
+func (r *Redactor) WebSocket(c *types.WebSocketCapture) {
+	c.URL = r.url(c.URL)
+	c.CloseReason = r.text(c.CloseReason)
+	if c.PayloadData == "" {
+		return
+	}
+	if !strings.HasPrefix(c.PayloadData, "~m~") {
+		c.PayloadData = r.message(c.PayloadData)
+		return
+	}
+	msgs := tvproto.SplitFrames(c.PayloadData)
+	for i, m := range msgs {
+		msgs[i] = r.message(m)
+	}
+	c.PayloadData = tvproto.JoinFrames(msgs...)
+}
+
+// message redacts one WebSocket message.
+func (r *Redactor) message(raw string) string {
+	if len(r.paths) > 0 {
+		if msg, ok := tvproto.ParseMessage(raw); ok {
+			raw = r.jsonPaths(raw, strings.ToLower(msg.Type), true)
+		}
+	}
+	return r.text(raw)
+}
+
+// body redacts a request or response body.
+func (r *Redactor) body(s string) string {
+	if s == "" {
+		return s
+	}
+	if len(r.paths) > 0 {
+		s = r.jsonPaths(s, "", false)
+	}
+	return r.text(s)
+}
+
+// jsonPaths masks the configured paths in s if it is a JSON document,
+// re-encoding it only when something changed.
+func (r *Redactor) jsonPaths(s, msgType string, ws bool) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return s
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return s
+	}
+	changed := false
+	for _, p := range r.paths {
+		if len(p.types) > 0 && (!ws || !p.types[msgType]) {
+			continue
+		}
+		doc = apply(doc, p.steps, func(v any) any {
+			changed = true
+			return r.token(scalarText(v))
+		})
+	}
+	if !changed {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func scalarText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// text applies JWT detection to free text.
+func (r *Redactor) text(s string) string {
+	if !r.jwt || !strings.Contains(s, "eyJ") {
+		return s
+	}
+	return jwtRe.ReplaceAllStringFunc(s, r.token)
+}
+
+func (r *Redactor) headerMap(h map[string]string) {
+	for k, v := range h {
+		if r.headers[strings.ToLower(k)] {
+			h[k] = r.token(v)
+		} else {
+			h[k] = r.text(v)
+		}
+	}
+}
+
+// url redacts denylisted query parameters, keeping the order and encoding
+// of the rest, then applies JWT detection.
+func (r *Redactor) url(raw string) string {
+	if len(r.params) > 0 {
+		if base, query, ok := strings.Cut(raw, "?"); ok {
+			frag := ""
+			if i := strings.IndexByte(query, '#'); i >= 0 {
+				query, frag = query[:i], query[i:]
+			}
+			parts := strings.Split(query, "&")
+			for i, part := range parts {
+				name, value, hasValue := strings.Cut(part, "=")
+				n, err := url.QueryUnescape(name)
+				if err != nil || !hasValue || !r.params[strings.ToLower(n)] {
+					continue
+				}
+				if v, err := url.QueryUnescape(value); err == nil {
+					value = v
+				}
+				parts[i] = name + "=" + url.QueryEscape(r.token(value))
+			}
+			raw = base + "?" + strings.Join(parts, "&") + frag
+		}
+	}
+	return r.text(raw)
+}
+
+// token returns the replacement for a secret value.
+func (r *Redactor) token(v string) string {
+	if r.mode != ModeHash {
+		return Mask
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(v))
+	tok := "redacted:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	r.record(tok, v)
+	return tok
+}
+
+// record appends a new token to the vault.
+func (r *Redactor) record(tok, v string) {
+	if r.vault == nil {
+		return
+	}
+	r.vaultMu.Lock()
+	defer r.vaultMu.Unlock()
+	if r.vaultSeen[tok] {
+		return
+	}
+	r.vaultSeen[tok] = true
+	line, _ := json.Marshal(struct {
+		Token     string    `json:"token"`
+		Value     string    `json:"value"`
+		FirstSeen time.Time `json:"first_seen"`
+	}{tok, v, time.Now().UTC()})
+	if _, err := r.vault.Write(append(line, '\n')); err != nil {
+		// Forget the token so a later record retries the write.
+		delete(r.vaultSeen, tok)
+		slog.Warn("redact vault: write failed", "path", r.vault.Name(), "error", err)
+	}
+}
+
+func lowerSet(list []string) map[string]bool {
+	m := make(map[string]bool, len(list))
+	for _, s := range list {
+		m[strings.ToLower(s)] = true
+	}
+	return m
+}
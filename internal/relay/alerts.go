@@ -0,0 +1,455 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+// maxSeenFires bounds the fire-ID dedup set; it is reset once full.
+const maxSeenFires = 4096
+
+// AlertFiredEvent is a normalized alert fire. TradingView reports one fire up
+// to three times (alert_fired, the compact "event" message and fires_updated);
+// Source records which one this came from.
+type AlertFiredEvent struct {
+	Source        string     `json:"source"`
+	FireID        int64      `json:"fire_id"`
+	AlertID       int64      `json:"alert_id"`
+	Name          string     `json:"name,omitempty"`
+	Symbol        string     `json:"symbol,omitempty"`
+	Resolution    string     `json:"resolution,omitempty"`
+	Message       string     `json:"message,omitempty"`
+	Condition     string     `json:"condition,omitempty"`
+	Price         *float64   `json:"price,omitempty"`
+	FireTime      *time.Time `json:"fire_time,omitempty"`
+	BarTime       *time.Time `json:"bar_time,omitempty"`
+	WebhookStatus int        `json:"webhook_status,omitempty"`
+}
+
+// AlertLifecycleEvent is a normalized alert create/update/run/delete message.
+// Action is one of created, updated, running or deleted.
+type AlertLifecycleEvent struct {
+	Action     string     `json:"action"`
+	AlertID    int64      `json:"alert_id"`
+	Name       string     `json:"name,omitempty"`
+	AlertType  string     `json:"alert_type,omitempty"`
+	Symbol     string     `json:"symbol,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	Condition  string     `json:"condition,omitempty"`
+	Frequency  string     `json:"frequency,omitempty"`
+	Price      *float64   `json:"price,omitempty"`
+	Active     *bool      `json:"active,omitempty"`
+	Time       *time.Time `json:"time,omitempty"`
+}
+
+func alertFiredDecoder(source string) func([]json.RawMessage) (any, error) {
+	return func(p []json.RawMessage) (any, error) {
+		out := make([]AlertFiredEvent, 0, len(p))
+		for _, raw := range p {
+			f, err := alertFields(raw)
+			if err != nil {
+				return nil, err
+			}
+			evt := AlertFiredEvent{
+				Source:     source,
+				FireID:     f.int("fire_id"),
+				AlertID:    f.int("alert_id", "aid"),
+				Name:       f.str("name"),
+				Symbol:     f.symbol("symbol", "sym"),
+				Resolution: f.str("resolution", "res"),
+				Message:    f.str("message", "desc"),
+				FireTime:   f.time("fire_time"),
+				BarTime:    f.time("bar_time"),
+			}
+			if source == "event" {
+				// The compact form uses "id" for the fire ID.
+				evt.FireID = f.int("id")
+			}
+			if wh, ok := f["webhook"].(map[string]any); ok {
+				evt.WebhookStatus = int(fields(wh).int("http_code"))
+			}
+			evt.Condition, _, evt.Price = f.condition()
+			out = append(out, evt)
+		}
+		return out, nil
+	}
+}
+
+func alertLifecycleDecoder(action string) func([]json.RawMessage) (any, error) {
+	return func(p []json.RawMessage) (any, error) {
+		out := make([]AlertLifecycleEvent, 0, len(p))
+		for _, raw := range p {
+			raw = bytes.TrimSpace(raw)
+			if len(raw) > 0 && raw[0] != '{' {
+				// alerts_deleted carries bare alert IDs.
+				out = append(out, AlertLifecycleEvent{Action: action, AlertID: anyInt(rawValue(raw))})
+				continue
+			}
+			f, err := alertFields(raw)
+			if err != nil {
+				return nil, err
+			}
+			evt := AlertLifecycleEvent{
+				Action:     action,
+				AlertID:    f.int("alert_id", "id"),
+				Name:       f.str("name"),
+				AlertType:  f.str("type"),
+				Symbol:     f.symbol("symbol", "sym"),
+				Resolution: f.str("resolution", "res"),
+				Message:    f.str("message", "desc"),
+				Time:       f.time("create_time"),
+			}
+			if b, ok := f["active"].(bool); ok {
+				evt.Active = &b
+			}
+			evt.Condition, evt.Frequency, evt.Price = f.condition()
+			out = append(out, evt)
+		}
+		return out, nil
+	}
+}
+
+// fields is a loosely typed alert object. Its accessors take several
+// candidate keys because the same value is named differently across the
+// full and abbreviated message variants.
+type fields map[string]any
+
+func alertFields(raw json.RawMessage) (fields, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var f fields
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func rawValue(raw json.RawMessage) any {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	_ = dec.Decode(&v)
+	return v
+}
+
+func (f fields) first(keys ...string) any {
+	for _, k := range keys {
+		if v, ok := f[k]; ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func (f fields) str(keys ...string) string {
+	switch v := f.first(keys...).(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+func (f fields) int(keys ...string) int64 {
+	return anyInt(f.first(keys...))
+}
+
+// time accepts both the ISO strings of the full messages and the unix
+// seconds of the compact "event" message.
+func (f fields) time(keys ...string) *time.Time {
+	var t time.Time
+	switch v := f.first(keys...).(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil
+		}
+		t = parsed.UTC()
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil || n <= 0 {
+			return nil
+		}
+		t = time.Unix(n, 0).UTC()
+	default:
+		return nil
+	}
+	return &t
+}
+
+// symbol returns the plain ticker for a symbol field.
+func (f fields) symbol(keys ...string) string {
+	return tvproto.ParseSymbolSpec(f.str(keys...))
+}
+
+// condition extracts the first condition's type, frequency and static price
+// level. alert_running only carries it inside the escaped "extra" JSON.
+func (f fields) condition() (kind, frequency string, price *float64) {
+	conds, _ := f["conditions"].([]any)
+	if len(conds) == 0 {
+		if extra := f.str("extra"); extra != "" {
+			if ef, err := alertFields(json.RawMessage(extra)); err == nil {
+				conds, _ = ef["conditions"].([]any)
+			}
+		}
+	}
+	if len(conds) == 0 {
+		return "", "", nil
+	}
+	c, ok := conds[0].(map[string]any)
+	if !ok {
+		return "", "", nil
+	}
+	cf := fields(c)
+	kind, frequency = cf.str("type"), cf.str("frequency")
+	series, _ := c["series"].([]any)
+	for _, s := range series {
+		sm, ok := s.(map[string]any)
+		if !ok || sm["type"] != "value" {
+			continue
+		}
+		if n, ok := sm["value"].(json.Number); ok {
+			if v, err := n.Float64(); err == nil {
+				price = &v
+				break
+			}
+		}
+	}
+	return kind, frequency, price
+}
+
+func anyInt(v any) int64 {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if fl, err := n.Float64(); err == nil {
+			return int64(fl)
+		}
+	case float64:
+		return int64(n)
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	}
+	return 0
+}
+
+// AlertTracker turns private-feed alert messages from the relay broker into
+// AlertFiredEvent and AlertLifecycleEvent values. It remembers each alert's
+// symbol, condition and price from its lifecycle messages so fires (which
+// do not carry the condition) can be enriched, and collapses the duplicate
+// alert_fired/event notifications of a single fire into one event.
+type AlertTracker struct {
+	source *Broker
+	out    *Broker
+
+	handled recentMessages // worker only
+
+	mu     sync.Mutex
+	alerts map[int64]AlertLifecycleEvent
+	seen   map[int64]bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAlertTracker creates a tracker that consumes events from source.
+func NewAlertTracker(source *Broker) *AlertTracker {
+	return &AlertTracker{
+		source: source,
+		out:    NewBroker(),
+		alerts: make(map[int64]AlertLifecycleEvent),
+		seen:   make(map[int64]bool),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start subscribes to the source broker and begins tracking alerts.
+func (t *AlertTracker) Start() {
+	sub := t.source.SubscribeWith(SubscribeOptions{Label: "alert_tracker"})
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.source.Unsubscribe(sub.ID)
+		for {
+			select {
+			case <-t.stop:
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				t.handle(evt)
+			}
+		}
+	}()
+}
+
+// Stop ends tracking and waits for the worker to exit.
+func (t *AlertTracker) Stop() {
+	close(t.stop)
+	t.wg.Wait()
+}
+
+// Events returns the broker the derived events are published on.
+func (t *AlertTracker) Events() *Broker {
+	return t.out
+}
+
+func (t *AlertTracker) handle(evt Event) {
+	if t.handled.seen(evt.MessageID) {
+		return
+	}
+	msg, ok := tvproto.ParseMessage(evt.Payload)
+	if !ok {
+		return
+	}
+	switch msg.Type {
+	case "alert_fired", "event", "fires_updated", "alerts_created", "alerts_updated",
+		"alert_running", "alerts_deleted", "alert_deleted":
+	default:
+		return
+	}
+	v, err := DecodeMessage(msg)
+	if err != nil {
+		slog.Debug("alert tracker: decode failed", "type", msg.Type, "error", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch events := v.(type) {
+	case []AlertFiredEvent:
+		for _, e := range events {
+			t.applyFireLocked(e)
+		}
+	case []AlertLifecycleEvent:
+		for _, e := range events {
+			t.applyLifecycleLocked(e)
+		}
+	}
+}
+
+func (t *AlertTracker) applyFireLocked(e AlertFiredEvent) {
+	if known, ok := t.alerts[e.AlertID]; ok {
+		if e.Symbol == "" {
+			e.Symbol = known.Symbol
+		}
+		if e.Name == "" {
+			e.Name = known.Name
+		}
+		if e.Condition == "" {
+			e.Condition = known.Condition
+		}
+		if e.Price == nil {
+			e.Price = known.Price
+		}
+	}
+	typ := "fire_updated"
+	if e.Source != "fires_updated" {
+		if e.FireID != 0 && t.seen[e.FireID] {
+			return
+		}
+		if len(t.seen) >= maxSeenFires {
+			t.seen = make(map[int64]bool)
+		}
+		t.seen[e.FireID] = true
+		typ = "alert_fired"
+	}
+	t.emitLocked(typ, e.Symbol, e)
+}
+
+func (t *AlertTracker) applyLifecycleLocked(e AlertLifecycleEvent) {
+	if e.Action == "deleted" {
+		if known, ok := t.alerts[e.AlertID]; ok && e.Symbol == "" {
+			e.Symbol, e.Name = known.Symbol, known.Name
+		}
+		delete(t.alerts, e.AlertID)
+	} else {
+		t.alerts[e.AlertID] = mergeLifecycle(t.alerts[e.AlertID], e)
+	}
+	t.emitLocked("alert_"+e.Action, e.Symbol, e)
+}
+
+// mergeLifecycle overlays the non-empty fields of next onto prev.
+func mergeLifecycle(prev, next AlertLifecycleEvent) AlertLifecycleEvent {
+	out := next
+	if out.Name == "" {
+		out.Name = prev.Name
+	}
+	if out.AlertType == "" {
+		out.AlertType = prev.AlertType
+	}
+	if out.Symbol == "" {
+		out.Symbol = prev.Symbol
+	}
+	if out.Resolution == "" {
+		out.Resolution = prev.Resolution
+	}
+	if out.Message == "" {
+		out.Message = prev.Message
+	}
+	if out.Condition == "" {
+		out.Condition, out.Frequency = prev.Condition, prev.Frequency
+	}
+	if out.Price == nil {
+		out.Price = prev.Price
+	}
+	if out.Active == nil {
+		out.Active = prev.Active
+	}
+	return out
+}
+
+func (t *AlertTracker) emitLocked(typ, symbol string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	t.out.Publish(Event{Feed: "alerts", Type: typ, Symbol: symbol, Payload: string(data)})
+}
+
+// AlertEventsHandler returns an http.HandlerFunc that streams normalized alert
+// events as SSE. Event names are alert_fired, fire_updated, alert_created,
+// alert_updated, alert_running and alert_deleted. Clients may filter via
+// ?types=alert_fired and ?symbols=COINBASE:BTCUSD, and resume from
+// Last-Event-ID after a reconnect.
+func AlertEventsHandler(t *AlertTracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		types := parseListParam(q.Get("types"))
+		symbols := parseListParam(q.Get("symbols"))
+
+		sub, ok := subscribeSSE(w, r, t.out)
+		if !ok {
+			return
+		}
+		defer t.out.Unsubscribe(sub.ID)
+
+		flusher, ok := beginSSE(w)
+		if !ok {
+			return
+		}
+
+		pumpSSE(w, r, flusher, sub, resumeEvents(t.out, r, nil), func(evt Event) (string, string, bool) {
+			if types != nil && !types[evt.Type] {
+				return "", "", false
+			}
+			if symbols != nil && !symbols[evt.Symbol] {
+				return "", "", false
+			}
+			return evt.Type, evt.Payload, true
+		})
+	}
+}
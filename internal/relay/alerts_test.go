@@ -0,0 +1,100 @@
+package relay
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+func privateFeedEvent(content string) Event {
+	return Event{Feed: "private_feed", Payload: `{"id":7,"text":{"content":` + content + `,"channel":"private_1"}}`}
+}
+
+func TestParseMessageUnwrapsEnvelope(t *testing.T) {
+	inner := `{"m":"alert_fired","p":{"alert_id":1}}`
+	for name, content := range map[string]string{
+		"object": inner,
+		"string": strconv.Quote(inner),
+	} {
+		msg, ok := tvproto.ParseMessage(privateFeedEvent(content).Payload)
+		if !ok || msg.Type != "alert_fired" {
+			t.Fatalf("%s: got %+v ok=%v, want alert_fired", name, msg, ok)
+		}
+	}
+}
+
+func drainAlerts(ch <-chan Event) []Event {
+	var out []Event
+	for len(ch) > 0 {
+		out = append(out, <-ch)
+	}
+	return out
+}
+
+func TestAlertTrackerEnrichesAndDedupesFires(t *testing.T) {
+	tr := NewAlertTracker(NewBroker())
+	_, ch := tr.out.Subscribe()
+
+	tr.handle(privateFeedEvent(`{"m":"alerts_created","p":[{"alert_id":4074311184,"type":"price","active":false,` +
+		`"symbol":"={\"symbol\":\"COINBASE:BTCUSD\",\"adjustment\":\"splits\"}","resolution":"1",` +
+		`"conditions":[{"type":"cross","frequency":"on_first_fire","series":[{"type":"barset"},{"type":"value","value":68521.11}]}]}]}`))
+	tr.handle(privateFeedEvent(strconv.Quote(`{"m":"alert_fired","p":{"fire_id":46723852620,"alert_id":4074311184,` +
+		`"fire_time":"2026-02-21T18:46:44Z","bar_time":"2026-02-21T18:46:00Z","message":"BTCUSD Crossing 68,521.11"}}`)))
+	tr.handle(privateFeedEvent(`{"m":"event","p":{"id":46723852620,"aid":4074311184,"fire_time":1771699604,"sym":"COINBASE:BTCUSD"}}`))
+	tr.handle(privateFeedEvent(`{"m":"fires_updated","p":[{"fire_id":46723852620,"alert_id":4074311184,"webhook":{"http_code":400}}]}`))
+
+	got := drainAlerts(ch)
+	var types []string
+	for _, e := range got {
+		types = append(types, e.Type)
+	}
+	if len(got) != 3 || got[0].Type != "alert_created" || got[1].Type != "alert_fired" || got[2].Type != "fire_updated" {
+		t.Fatalf("event types = %v, want [alert_created alert_fired fire_updated]", types)
+	}
+
+	var fired AlertFiredEvent
+	if err := json.Unmarshal([]byte(got[1].Payload), &fired); err != nil {
+		t.Fatal(err)
+	}
+	if fired.AlertID != 4074311184 || fired.FireID != 46723852620 || fired.Symbol != "COINBASE:BTCUSD" {
+		t.Fatalf("fired = %+v", fired)
+	}
+	if fired.Condition != "cross" || fired.Price == nil || *fired.Price != 68521.11 {
+		t.Fatalf("fire not enriched with condition/price: %+v", fired)
+	}
+	if fired.FireTime == nil || fired.FireTime.Unix() != 1771699604 {
+		t.Fatalf("fire_time = %v", fired.FireTime)
+	}
+
+	var updated AlertFiredEvent
+	if err := json.Unmarshal([]byte(got[2].Payload), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.WebhookStatus != 400 || updated.Symbol != "COINBASE:BTCUSD" {
+		t.Fatalf("fire_updated = %+v", updated)
+	}
+}
+
+func TestAlertLifecycleDeleteVariants(t *testing.T) {
+	tr := NewAlertTracker(NewBroker())
+	_, ch := tr.out.Subscribe()
+
+	tr.handle(privateFeedEvent(`{"m":"alerts_deleted","p":[4074311184,"4074311185"]}`))
+	tr.handle(privateFeedEvent(`{"m":"alert_deleted","p":{"id":4074311186}}`))
+
+	got := drainAlerts(ch)
+	if len(got) != 3 {
+		t.Fatalf("got %d events, want 3", len(got))
+	}
+	for i, want := range []int64{4074311184, 4074311185, 4074311186} {
+		var e AlertLifecycleEvent
+		if err := json.Unmarshal([]byte(got[i].Payload), &e); err != nil {
+			t.Fatal(err)
+		}
+		if got[i].Type != "alert_deleted" || e.AlertID != want {
+			t.Fatalf("event %d = %s %+v, want alert_deleted %d", i, got[i].Type, e, want)
+		}
+	}
+}
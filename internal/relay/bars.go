@@ -0,0 +1,256 @@
+package relay
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+const barCloseCheckInterval = time.Second
+
+// BarEvent is a normalized OHLCV bar for a chart's main series.
+// Closed is true once the bar is final: either a newer bar has started or
+// the bar's lbs.bar_close_time has passed.
+type BarEvent struct {
+	ChartID    string  `json:"chart_id"`
+	Session    string  `json:"session"`
+	SeriesID   string  `json:"series_id"`
+	Symbol     string  `json:"symbol,omitempty"`
+	Resolution string  `json:"resolution,omitempty"`
+	Time       int64   `json:"time"`
+	Open       float64 `json:"open"`
+	High       float64 `json:"high"`
+	Low        float64 `json:"low"`
+	Close      float64 `json:"close"`
+	Volume     float64 `json:"volume"`
+	BarIndex   int     `json:"bar_index"`
+	Closed     bool    `json:"closed"`
+}
+
+type seriesKey struct {
+	chartID  string
+	session  string
+	seriesID string
+}
+
+type barState struct {
+	bar       Bar
+	closeTime int64
+	closed    bool
+}
+
+// BarTracker turns du series updates from the relay broker into BarEvents
+// for each chart's main series and publishes them on its own broker.
+type BarTracker struct {
+	source *Broker
+	out    *Broker
+	series *tvproto.SeriesRegistry // may be nil
+
+	handled recentMessages // worker only
+
+	mu   sync.Mutex
+	bars map[seriesKey]*barState
+
+	now  func() time.Time
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewBarTracker creates a tracker that consumes events from source. When
+// series is non-nil, bars are labelled with the symbol and resolution the
+// client requested for their series, and the main series is the first one
+// the client created in each chart session; otherwise it is sds_1.
+func NewBarTracker(source *Broker, series *tvproto.SeriesRegistry) *BarTracker {
+	return &BarTracker{
+		source: source,
+		out:    NewBroker(),
+		series: series,
+		bars:   make(map[seriesKey]*barState),
+		now:    time.Now,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start subscribes to the source broker and begins tracking bars.
+func (t *BarTracker) Start() {
+	sub := t.source.SubscribeWith(SubscribeOptions{Label: "bar_tracker"})
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.source.Unsubscribe(sub.ID)
+		ticker := time.NewTicker(barCloseCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				t.handle(evt)
+			case <-ticker.C:
+				t.closeExpired()
+			}
+		}
+	}()
+}
+
+// Stop ends tracking and waits for the worker to exit.
+func (t *BarTracker) Stop() {
+	close(t.stop)
+	t.wg.Wait()
+}
+
+// Events returns the broker the derived events are published on.
+func (t *BarTracker) Events() *Broker {
+	return t.out
+}
+
+func (t *BarTracker) handle(evt Event) {
+	if evt.Type != "du" && evt.Type != "timescale_update" || t.handled.seen(evt.MessageID) {
+		return
+	}
+	msg, ok := tvproto.ParseMessage(evt.Payload)
+	if !ok {
+		return
+	}
+	v, err := DecodeMessage(msg)
+	if err != nil {
+		slog.Debug("bar tracker: decode failed", "type", evt.Type, "error", err)
+		return
+	}
+	du, ok := v.(DataUpdate)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, su := range du.Series {
+		if len(su.Bars) == 0 || !t.isMainSeries(evt.ChartID, du.Session, su.SeriesID) {
+			continue
+		}
+		key := seriesKey{chartID: evt.ChartID, session: du.Session, seriesID: su.SeriesID}
+		if evt.Type == "timescale_update" {
+			// History (re)load: remember the latest bar without streaming history.
+			last := su.Bars[len(su.Bars)-1]
+			t.bars[key] = &barState{bar: last, closeTime: su.BarCloseTime}
+			continue
+		}
+		t.applyLocked(key, su)
+	}
+}
+
+// isMainSeries reports whether seriesID is the main series of a chart
+// session: the one the registry saw created first, or sds_1 when the
+// session's create_series was not observed (e.g. the relay started after
+// the chart loaded). Bar arrival order is not used, since a compare series
+// may update before the main one.
+func (t *BarTracker) isMainSeries(chartID, session, seriesID string) bool {
+	if t.series != nil {
+		if id, ok := t.series.MainSeries(chartID, session); ok {
+			return id == seriesID
+		}
+	}
+	return seriesID == "sds_1"
+}
+
+func (t *BarTracker) applyLocked(key seriesKey, su SeriesUpdate) {
+	st := t.bars[key]
+	for i, b := range su.Bars {
+		if st != nil && b.Index < st.bar.Index {
+			continue
+		}
+		if st != nil && b.Index > st.bar.Index && !st.closed {
+			t.emitLocked(key, st.bar, true)
+		}
+		if st == nil || b.Index > st.bar.Index {
+			st = &barState{}
+			t.bars[key] = st
+		}
+		st.bar = b
+		if su.BarCloseTime > 0 {
+			st.closeTime = su.BarCloseTime
+		}
+		// Every bar but the last in one update is already superseded.
+		if i < len(su.Bars)-1 {
+			st.closed = true
+		}
+		t.emitLocked(key, b, st.closed)
+	}
+}
+
+// closeExpired emits a closed event for bars whose close time has passed
+// without a newer bar arriving (e.g. an illiquid symbol).
+func (t *BarTracker) closeExpired() {
+	now := t.now().Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, st := range t.bars {
+		if st.closed || st.closeTime == 0 || now < st.closeTime {
+			continue
+		}
+		st.closed = true
+		t.emitLocked(key, st.bar, true)
+	}
+}
+
+func (t *BarTracker) emitLocked(key seriesKey, b Bar, closed bool) {
+	evt := BarEvent{
+		ChartID:  key.chartID,
+		Session:  key.session,
+		SeriesID: key.seriesID,
+		Time:     b.Time,
+		Open:     b.Open,
+		High:     b.High,
+		Low:      b.Low,
+		Close:    b.Close,
+		Volume:   b.Volume,
+		BarIndex: b.Index,
+		Closed:   closed,
+	}
+	if t.series != nil {
+		if info, ok := t.series.Lookup(key.chartID, key.session, key.seriesID); ok {
+			evt.Symbol, evt.Resolution = info.Symbol, info.Resolution
+		}
+	}
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return
+	}
+	t.out.Publish(Event{Feed: "bars", Type: "bar", ChartID: key.chartID, Symbol: evt.Symbol, Payload: string(data)})
+}
+
+// BarStreamHandler returns an http.HandlerFunc that streams BarEvents for the
+// chart in the {chart_id} path value as SSE "bar" events. Reconnecting
+// clients resume from Last-Event-ID.
+func BarStreamHandler(t *BarTracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chartID := strings.TrimSpace(r.PathValue("chart_id"))
+		if chartID == "" {
+			http.Error(w, "chart_id is required", http.StatusBadRequest)
+			return
+		}
+
+		sub, ok := subscribeSSE(w, r, t.out)
+		if !ok {
+			return
+		}
+		defer t.out.Unsubscribe(sub.ID)
+
+		flusher, ok := beginSSE(w)
+		if !ok {
+			return
+		}
+
+		pumpSSE(w, r, flusher, sub, resumeEvents(t.out, r, nil), func(evt Event) (string, string, bool) {
+			return evt.Type, evt.Payload, evt.ChartID == chartID
+		})
+	}
+}
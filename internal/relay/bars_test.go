@@ -0,0 +1,115 @@
+package relay
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+func duEvent(chartID, series string, bars string, closeTime int64) Event {
+	payload := `{"m":"du","p":["cs_1",{"` + series + `":{"s":` + bars + `,"lbs":{"bar_close_time":` +
+		jsonInt(closeTime) + `}}}]}`
+	return Event{Feed: "chart_data", Type: "du", ChartID: chartID, Payload: payload}
+}
+
+func jsonInt(v int64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func drainBars(t *testing.T, ch <-chan Event) []BarEvent {
+	t.Helper()
+	var out []BarEvent
+	for {
+		select {
+		case evt := <-ch:
+			var b BarEvent
+			if err := json.Unmarshal([]byte(evt.Payload), &b); err != nil {
+				t.Fatalf("unmarshal bar: %v", err)
+			}
+			out = append(out, b)
+		default:
+			return out
+		}
+	}
+}
+
+func TestBarTrackerClosesOnNewBar(t *testing.T) {
+	tr := NewBarTracker(NewBroker(), nil)
+	_, ch := tr.out.Subscribe()
+
+	tr.handle(duEvent("abc", "sds_1", `[{"i":10,"v":[60,1,2,0.5,1.5,10]}]`, 120))
+	tr.handle(duEvent("abc", "sds_1", `[{"i":10,"v":[60,1,2.5,0.5,2,12]}]`, 120))
+	tr.handle(duEvent("abc", "sds_1", `[{"i":11,"v":[120,2,2,2,2,1]}]`, 180))
+
+	got := drainBars(t, ch)
+	if len(got) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(got), got)
+	}
+	if got[0].Closed || got[1].Closed {
+		t.Fatalf("in-progress updates marked closed: %+v", got[:2])
+	}
+	if !got[2].Closed || got[2].BarIndex != 10 || got[2].Close != 2 {
+		t.Fatalf("closing event = %+v, want bar 10 closed at 2", got[2])
+	}
+	if got[3].Closed || got[3].BarIndex != 11 || got[3].ChartID != "abc" {
+		t.Fatalf("new bar event = %+v", got[3])
+	}
+}
+
+func TestBarTrackerClosesOnCloseTime(t *testing.T) {
+	tr := NewBarTracker(NewBroker(), nil)
+	_, ch := tr.out.Subscribe()
+	tr.now = func() time.Time { return time.Unix(119, 0) }
+
+	tr.handle(duEvent("abc", "sds_1", `[{"i":10,"v":[60,1,2,0.5,1.5,10]}]`, 120))
+	tr.closeExpired()
+	if got := drainBars(t, ch); len(got) != 1 {
+		t.Fatalf("got %d events before close time, want 1", len(got))
+	}
+
+	tr.now = func() time.Time { return time.Unix(120, 0) }
+	tr.closeExpired()
+	tr.closeExpired()
+	got := drainBars(t, ch)
+	if len(got) != 1 || !got[0].Closed {
+		t.Fatalf("after close time got %+v, want one closed event", got)
+	}
+}
+
+func TestBarTrackerIgnoresNonMainSeries(t *testing.T) {
+	tr := NewBarTracker(NewBroker(), nil)
+	_, ch := tr.out.Subscribe()
+
+	tr.handle(duEvent("abc", "sds_1", `[{"i":1,"v":[60,1,1,1,1,1]}]`, 120))
+	tr.handle(duEvent("abc", "sds_2", `[{"i":1,"v":[60,9,9,9,9,9]}]`, 120))
+
+	got := drainBars(t, ch)
+	if len(got) != 1 || got[0].SeriesID != "sds_1" {
+		t.Fatalf("got %+v, want only sds_1", got)
+	}
+}
+
+func TestBarTrackerMainSeriesFromRegistry(t *testing.T) {
+	reg := tvproto.NewSeriesRegistry()
+	reg.Observe("abc", mustParse(t, `{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","1",300,""]}`))
+	reg.Observe("abc", mustParse(t, `{"m":"create_series","p":["cs_1","sds_2","s1","sds_sym_2","1",300,""]}`))
+	tr := NewBarTracker(NewBroker(), reg)
+	_, ch := tr.out.Subscribe()
+
+	// The compare series updating first does not make it the main series.
+	tr.handle(duEvent("abc", "sds_2", `[{"i":1,"v":[60,9,9,9,9,9]}]`, 120))
+	tr.handle(duEvent("abc", "sds_1", `[{"i":1,"v":[60,1,1,1,1,1]}]`, 120))
+	if got := drainBars(t, ch); len(got) != 1 || got[0].SeriesID != "sds_1" {
+		t.Fatalf("got %+v, want only sds_1", got)
+	}
+
+	// Without a create_series for the session, only sds_1 is main.
+	tr.handle(Event{Feed: "chart_data", Type: "du", ChartID: "other",
+		Payload: `{"m":"du","p":["cs_1",{"sds_2":{"s":[{"i":1,"v":[60,9,9,9,9,9]}]}}]}`})
+	if got := drainBars(t, ch); len(got) != 0 {
+		t.Fatalf("got %+v for an unknown session's sds_2", got)
+	}
+}
@@ -1,66 +1,243 @@
 package relay
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
-const subscriberBufSize = 256
+const (
+	subscriberBufSize = 256
+
+	// historySize is the number of recent events kept per feed for replay
+	// to reconnecting SSE clients.
+	historySize = 1024
+)
 
 // Event represents a single relay event to be sent via SSE.
+// Payload is a single message split out of the WebSocket frame; Type is its
+// "m" field (empty for non-JSON payloads). ChartID is the chart tab that owns
+// the socket, when the relay could map its CDP session. Symbol is set on
+// derived events that concern a single symbol (e.g. merged quotes). ID is
+// assigned by the broker on Publish and increases monotonically. Direction
+// is DirectionReceived or DirectionSent for relayed frames and empty for
+// derived events. The relay publishes a message once per matching feed;
+// MessageID is shared by those copies (and 0 on derived events), so
+// consumers of every feed can handle the message once.
 type Event struct {
-	Feed    string
-	Payload string
+	ID        uint64
+	MessageID uint64
+	Feed      string
+	Type      string
+	ChartID   string
+	Symbol    string
+	Direction string
+	Payload   string
 }
 
-// Broker fans out events to all subscribed SSE clients.
+// Frame directions, as seen from the browser.
+const (
+	DirectionReceived = "received"
+	DirectionSent     = "sent"
+)
+
+// OverflowPolicy decides what happens when a subscriber's buffer is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest discards the event that does not fit (the default).
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest discards the oldest queued event to make room.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDisconnect unsubscribes the subscriber, closing its channel.
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// ParseOverflowPolicy parses a policy name; empty means OverflowDropNewest.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case "":
+		return OverflowDropNewest, nil
+	case OverflowDropNewest, OverflowDropOldest, OverflowDisconnect:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown overflow policy %q (want drop_newest, drop_oldest or disconnect)", s)
+}
+
+// SubscribeOptions configures a subscription. Label identifies the
+// subscriber in Stats.
+type SubscribeOptions struct {
+	Label  string
+	Policy OverflowPolicy
+}
+
+// Subscription is a registered subscriber. Events arrive on C, which is
+// closed on Unsubscribe or when the OverflowDisconnect policy triggers.
+type Subscription struct {
+	ID          int64
+	C           <-chan Event
+	Label       string
+	Policy      OverflowPolicy
+	ConnectedAt time.Time
+
+	ch           chan Event
+	delivered    atomic.Uint64
+	dropped      atomic.Uint64
+	disconnected atomic.Bool
+}
+
+// Delivered returns the number of events handed to the subscriber.
+func (s *Subscription) Delivered() uint64 { return s.delivered.Load() }
+
+// Dropped returns the number of events the subscriber lost to overflow.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Disconnected reports whether the subscription was closed by the
+// OverflowDisconnect policy.
+func (s *Subscription) Disconnected() bool { return s.disconnected.Load() }
+
+// offer queues evt according to the overflow policy. It returns false when
+// the subscriber must be disconnected.
+func (s *Subscription) offer(evt Event) bool {
+	for {
+		select {
+		case s.ch <- evt:
+			s.delivered.Add(1)
+			return true
+		default:
+		}
+		switch s.Policy {
+		case OverflowDropOldest:
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+				s.delivered.Add(^uint64(0))
+			default:
+			}
+		case OverflowDisconnect:
+			s.dropped.Add(1)
+			return false
+		default:
+			s.dropped.Add(1)
+			return true
+		}
+	}
+}
+
+// SubscriberStats is a snapshot of one subscriber's counters.
+type SubscriberStats struct {
+	ID          int64          `json:"id"`
+	Label       string         `json:"label,omitempty"`
+	Policy      OverflowPolicy `json:"policy"`
+	ConnectedAt time.Time      `json:"connected_at"`
+	Delivered   uint64         `json:"delivered"`
+	Dropped     uint64         `json:"dropped"`
+	Queued      int            `json:"queued"`
+}
+
+// BrokerStats is a snapshot of the broker and all its subscribers.
+type BrokerStats struct {
+	Clients     int               `json:"clients"`
+	Published   uint64            `json:"published"`
+	Subscribers []SubscriberStats `json:"subscribers"`
+}
+
+// Broker fans out events to all subscribed SSE clients and keeps a bounded
+// per-feed history so reconnecting clients can catch up.
 type Broker struct {
+	// pubMu serializes Publish, so every subscriber receives events in ID
+	// order even with concurrent publishers.
+	pubMu sync.Mutex
+
 	mu          sync.RWMutex
-	subscribers map[int64]chan Event
+	subscribers map[int64]*Subscription
 	nextID      atomic.Int64
+
+	histMu  sync.RWMutex
+	lastID  uint64
+	history map[string]*eventRing
 }
 
 // NewBroker creates a new SSE event broker.
 func NewBroker() *Broker {
 	return &Broker{
-		subscribers: make(map[int64]chan Event),
+		subscribers: make(map[int64]*Subscription),
+		history:     make(map[string]*eventRing),
 	}
 }
 
-// Subscribe registers a new client. Returns the subscriber ID and a channel
-// to receive events on. The channel is buffered; slow consumers will have
-// events dropped.
+// Subscribe registers a new client with the default drop-newest policy.
+// Returns the subscriber ID and a channel to receive events on. The channel
+// is buffered; slow consumers will have events dropped.
 func (b *Broker) Subscribe() (int64, <-chan Event) {
-	id := b.nextID.Add(1)
+	sub := b.SubscribeWith(SubscribeOptions{})
+	return sub.ID, sub.C
+}
+
+// SubscribeWith registers a new client with the given options.
+func (b *Broker) SubscribeWith(opts SubscribeOptions) *Subscription {
+	if opts.Policy == "" {
+		opts.Policy = OverflowDropNewest
+	}
 	ch := make(chan Event, subscriberBufSize)
+	sub := &Subscription{
+		ID:          b.nextID.Add(1),
+		C:           ch,
+		Label:       opts.Label,
+		Policy:      opts.Policy,
+		ConnectedAt: time.Now().UTC(),
+		ch:          ch,
+	}
 	b.mu.Lock()
-	b.subscribers[id] = ch
+	b.subscribers[sub.ID] = sub
 	b.mu.Unlock()
-	return id, ch
+	return sub
 }
 
 // Unsubscribe removes a subscriber and closes its channel.
 func (b *Broker) Unsubscribe(id int64) {
 	b.mu.Lock()
-	ch, ok := b.subscribers[id]
+	sub, ok := b.subscribers[id]
 	if ok {
 		delete(b.subscribers, id)
-		close(ch)
+		close(sub.ch)
 	}
 	b.mu.Unlock()
 }
 
-// Publish sends an event to all subscribers. Non-blocking: slow clients
-// have events dropped.
+// Publish assigns the event its ID, records it in the feed's history and
+// sends it to all subscribers. Non-blocking: a full subscriber is handled
+// by its overflow policy.
 func (b *Broker) Publish(evt Event) {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	b.histMu.Lock()
+	b.lastID++
+	evt.ID = b.lastID
+	ring := b.history[evt.Feed]
+	if ring == nil {
+		ring = &eventRing{buf: make([]Event, 0, historySize)}
+		b.history[evt.Feed] = ring
+	}
+	ring.add(evt)
+	b.histMu.Unlock()
+
+	var evict []*Subscription
 	b.mu.RLock()
-	defer b.mu.RUnlock()
-	for _, ch := range b.subscribers {
-		select {
-		case ch <- evt:
-		default:
+	for _, sub := range b.subscribers {
+		if !sub.offer(evt) {
+			evict = append(evict, sub)
 		}
 	}
+	b.mu.RUnlock()
+
+	for _, sub := range evict {
+		sub.disconnected.Store(true)
+		b.Unsubscribe(sub.ID)
+	}
 }
 
 // ClientCount returns the number of active subscribers.
@@ -69,3 +246,82 @@ func (b *Broker) ClientCount() int {
 	defer b.mu.RUnlock()
 	return len(b.subscribers)
 }
+
+// Stats returns the broker's counters and every subscriber's, ordered by ID.
+func (b *Broker) Stats() BrokerStats {
+	b.histMu.RLock()
+	published := b.lastID
+	b.histMu.RUnlock()
+
+	b.mu.RLock()
+	out := BrokerStats{Clients: len(b.subscribers), Published: published, Subscribers: make([]SubscriberStats, 0, len(b.subscribers))}
+	for _, sub := range b.subscribers {
+		out.Subscribers = append(out.Subscribers, SubscriberStats{
+			ID:          sub.ID,
+			Label:       sub.Label,
+			Policy:      sub.Policy,
+			ConnectedAt: sub.ConnectedAt,
+			Delivered:   sub.Delivered(),
+			Dropped:     sub.Dropped(),
+			Queued:      len(sub.ch),
+		})
+	}
+	b.mu.RUnlock()
+	sort.Slice(out.Subscribers, func(i, j int) bool { return out.Subscribers[i].ID < out.Subscribers[j].ID })
+	return out
+}
+
+// Since returns the buffered events with an ID greater than lastID, in ID
+// order. A nil feeds set matches every feed. Events older than the per-feed
+// history are no longer available.
+func (b *Broker) Since(lastID uint64, feeds map[string]bool) []Event {
+	out, _ := b.Resume(lastID, feeds)
+	return out
+}
+
+// Resume is Since that also reports whether some events after lastID were
+// already evicted from the history of one of the feeds, so a client resuming
+// from lastID cannot be brought fully up to date.
+func (b *Broker) Resume(lastID uint64, feeds map[string]bool) (events []Event, lost bool) {
+	b.histMu.RLock()
+	for feed, ring := range b.history {
+		if feeds != nil && !feeds[feed] {
+			continue
+		}
+		events = ring.since(lastID, events)
+		if ring.evicted > lastID {
+			lost = true
+		}
+	}
+	b.histMu.RUnlock()
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, lost
+}
+
+// eventRing is a fixed-capacity FIFO of the most recent events of one feed.
+type eventRing struct {
+	buf     []Event
+	next    int    // index of the oldest event once buf is full
+	evicted uint64 // ID of the newest event pushed out, 0 if none
+}
+
+func (r *eventRing) add(evt Event) {
+	if len(r.buf) < cap(r.buf) {
+		r.buf = append(r.buf, evt)
+		return
+	}
+	r.evicted = r.buf[r.next].ID
+	r.buf[r.next] = evt
+	r.next = (r.next + 1) % len(r.buf)
+}
+
+func (r *eventRing) since(lastID uint64, out []Event) []Event {
+	n := len(r.buf)
+	for i := 0; i < n; i++ {
+		evt := r.buf[(r.next+i)%n]
+		if evt.ID > lastID {
+			out = append(out, evt)
+		}
+	}
+	return out
+}
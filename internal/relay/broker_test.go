@@ -0,0 +1,172 @@
+package relay
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBrokerSinceWrapsPerFeed(t *testing.T) {
+	b := NewBroker()
+	for i := 0; i < historySize+10; i++ {
+		b.Publish(Event{Feed: "chart_data", Payload: "x"})
+	}
+	b.Publish(Event{Feed: "private_feed", Payload: "y"})
+
+	all := b.Since(0, nil)
+	if len(all) != historySize+1 {
+		t.Fatalf("got %d buffered events, want %d", len(all), historySize+1)
+	}
+	if all[0].ID != 11 || all[len(all)-1].Feed != "private_feed" {
+		t.Fatalf("oldest = %d, last feed = %s", all[0].ID, all[len(all)-1].Feed)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].ID <= all[i-1].ID {
+			t.Fatalf("events out of order at %d: %d after %d", i, all[i].ID, all[i-1].ID)
+		}
+	}
+
+	if got := b.Since(uint64(historySize+5), map[string]bool{"chart_data": true}); len(got) != 5 {
+		t.Fatalf("filtered since = %d events, want 5", len(got))
+	}
+}
+
+func TestSSEHandlerReplaysFromLastEventID(t *testing.T) {
+	b := NewBroker()
+	b.Publish(Event{Feed: "private_feed", Payload: `{"m":"a"}`})
+	b.Publish(Event{Feed: "chart_data", Payload: `{"m":"du"}`})
+	b.Publish(Event{Feed: "private_feed", Payload: `{"m":"b"}`})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/api/v1/relay/events?feeds=private_feed", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "1")
+	rec := httptest.NewRecorder()
+	SSEHandler(b)(rec, req)
+
+	body := rec.Body.String()
+	want := "id: 3\nevent: private_feed\ndata: {\"m\":\"b\"}\n\n"
+	if body != want {
+		t.Fatalf("body = %q, want %q", body, want)
+	}
+	if strings.Contains(body, `"a"`) {
+		t.Fatal("event before Last-Event-ID was replayed")
+	}
+}
+
+func TestSSEHandlerReportsGap(t *testing.T) {
+	b := NewBroker()
+	for i := 0; i < historySize+2; i++ {
+		b.Publish(Event{Feed: "chart_data", Payload: "{}"})
+	}
+	b.Publish(Event{Feed: "private_feed", Payload: "{}"})
+
+	replay := func(feeds string) string {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest("GET", "/api/v1/relay/events?feeds="+feeds, nil).WithContext(ctx)
+		req.Header.Set("Last-Event-ID", "1")
+		rec := httptest.NewRecorder()
+		SSEHandler(b)(rec, req)
+		return rec.Body.String()
+	}
+	// Event 2 was evicted from chart_data, so resuming from 1 lost it.
+	want := "event: gap\ndata: {\"last_event_id\":1,\"first_event_id\":3}\n\nid: 3\n"
+	if body := replay("chart_data"); !strings.HasPrefix(body, want) {
+		t.Fatalf("body starts %q, want %q", body[:min(len(body), 80)], want)
+	}
+	if body := replay("private_feed"); strings.Contains(body, "event: gap") {
+		t.Fatalf("gap reported for a feed that lost nothing: %q", body)
+	}
+}
+
+func TestBrokerPublishOrderWithConcurrentPublishers(t *testing.T) {
+	b := NewBroker()
+	sub := b.SubscribeWith(SubscribeOptions{})
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < subscriberBufSize/4; i++ {
+				b.Publish(Event{Feed: "f"})
+			}
+		}()
+	}
+	wg.Wait()
+	var last uint64
+	for i := 0; i < subscriberBufSize; i++ {
+		evt := <-sub.C
+		if evt.ID <= last {
+			t.Fatalf("event %d delivered after %d", evt.ID, last)
+		}
+		last = evt.ID
+	}
+}
+
+func TestBrokerOverflowPolicies(t *testing.T) {
+	b := NewBroker()
+	newest := b.SubscribeWith(SubscribeOptions{Policy: OverflowDropNewest})
+	oldest := b.SubscribeWith(SubscribeOptions{Policy: OverflowDropOldest})
+	disc := b.SubscribeWith(SubscribeOptions{Policy: OverflowDisconnect})
+
+	for i := 0; i < subscriberBufSize+3; i++ {
+		b.Publish(Event{Feed: "f"})
+	}
+
+	if newest.Dropped() != 3 || newest.Delivered() != subscriberBufSize {
+		t.Fatalf("drop_newest delivered=%d dropped=%d", newest.Delivered(), newest.Dropped())
+	}
+	if first := <-newest.C; first.ID != 1 {
+		t.Fatalf("drop_newest kept first ID %d, want 1", first.ID)
+	}
+	if oldest.Dropped() != 3 || oldest.Delivered() != subscriberBufSize {
+		t.Fatalf("drop_oldest delivered=%d dropped=%d", oldest.Delivered(), oldest.Dropped())
+	}
+	if first := <-oldest.C; first.ID != 4 {
+		t.Fatalf("drop_oldest kept first ID %d, want 4", first.ID)
+	}
+	if !disc.Disconnected() || disc.Dropped() != 1 {
+		t.Fatalf("disconnect: disconnected=%v dropped=%d", disc.Disconnected(), disc.Dropped())
+	}
+
+	st := b.Stats()
+	if st.Clients != 2 || st.Published != subscriberBufSize+3 || len(st.Subscribers) != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestPumpSSESendsOverflowOnDisconnect(t *testing.T) {
+	b := NewBroker()
+	sub := b.SubscribeWith(SubscribeOptions{Policy: OverflowDisconnect})
+	for i := 0; i < subscriberBufSize+1; i++ {
+		b.Publish(Event{Feed: "f", Payload: "{}"})
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/relay/events", nil)
+	rec := httptest.NewRecorder()
+	pumpSSE(rec, req, rec, sub, resumed{}, func(evt Event) (string, string, bool) {
+		return evt.Feed, evt.Payload, true
+	})
+
+	body := rec.Body.String()
+	first := "event: overflow\ndata: {\"dropped\":1,\"total_dropped\":1,\"last_event_id\":0,\"disconnected\":true}\n\n"
+	if !strings.HasPrefix(body, first) {
+		t.Fatalf("body does not start with overflow notice: %q", body[:min(len(body), 200)])
+	}
+	want := "event: overflow\ndata: {\"dropped\":0,\"total_dropped\":1,\"last_event_id\":256,\"disconnected\":true}\n\n"
+	if !strings.HasSuffix(body, want) {
+		t.Fatalf("body does not end with overflow notice: %q", body[max(0, len(body)-200):])
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	if p, err := ParseOverflowPolicy(""); err != nil || p != OverflowDropNewest {
+		t.Fatalf("empty = %q, %v", p, err)
+	}
+	if _, err := ParseOverflowPolicy("block"); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
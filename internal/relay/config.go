@@ -2,21 +2,121 @@ package relay
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// FeedConfig describes a single WebSocket feed to relay.
+// FeedConfig describes a single WebSocket feed to relay. A socket matches
+// when its URL contains URLPattern and/or matches the URLRegex regular
+// expression; every matching feed receives its messages. Directions selects
+// which frames are relayed: "received" (server to browser), "sent" (browser
+// to server) or both; empty means received only. Sessions keeps only
+// messages whose session ID matches one of the globs
+// (e.g. "qs_multiplexer_watchlist_*"), and every entry of Filters must
+// accept a message for it to be relayed.
 type FeedConfig struct {
-	Name         string   `yaml:"name"`
-	URLPattern   string   `yaml:"url_pattern"`
+	Name         string          `yaml:"name"`
+	URLPattern   string          `yaml:"url_pattern,omitempty"`
+	URLRegex     string          `yaml:"url_regex,omitempty"`
+	MessageTypes []string        `yaml:"message_types,omitempty"`
+	Directions   []string        `yaml:"directions,omitempty"`
+	Sessions     []string        `yaml:"sessions,omitempty"`
+	Filters      []PayloadFilter `yaml:"filters,omitempty"`
+}
+
+// PayloadFilter keeps messages whose value at Path equals one of Values or
+// matches the Match regular expression. Path addresses the unwrapped
+// {"m","p"} message, e.g. "p[1].n" for the symbol of a qsd message,
+// "p[*]" for any param or "p[1].*" for any field of the second param. When
+// MessageTypes is set the filter only applies to those types; other
+// messages pass it.
+type PayloadFilter struct {
+	Path         string   `yaml:"path"`
+	Values       []string `yaml:"values,omitempty"`
+	Match        string   `yaml:"match,omitempty"`
 	MessageTypes []string `yaml:"message_types,omitempty"`
 }
 
+// WebhookConfig describes an outbound webhook sink. Every event from one of
+// Feeds (all feeds when empty) whose type is in MessageTypes (all types when
+// empty) is POSTed to each of URLs. When Secret is set the body is signed
+// with HMAC-SHA256; the secret may reference environment variables (${VAR}).
+type WebhookConfig struct {
+	Name         string        `yaml:"name"`
+	URLs         []string      `yaml:"urls"`
+	Feeds        []string      `yaml:"feeds,omitempty"`
+	MessageTypes []string      `yaml:"message_types,omitempty"`
+	Decode       bool          `yaml:"decode,omitempty"`
+	Secret       string        `yaml:"secret,omitempty"`
+	MaxRetries   int           `yaml:"max_retries,omitempty"`
+	Backoff      time.Duration `yaml:"backoff,omitempty"`
+	Timeout      time.Duration `yaml:"timeout,omitempty"`
+	QueueSize    int           `yaml:"queue_size,omitempty"`
+}
+
+// RecorderConfig enables the on-disk Recorder. Streams selects "bars"
+// and/or "quotes"; empty records both. An empty Dir disables recording.
+type RecorderConfig struct {
+	Dir     string   `yaml:"dir,omitempty"`
+	Streams []string `yaml:"streams,omitempty"`
+}
+
+// Records reports whether stream ("bars" or "quotes") should be recorded.
+func (c RecorderConfig) Records(stream string) bool {
+	if c.Dir == "" {
+		return false
+	}
+	if len(c.Streams) == 0 {
+		return true
+	}
+	for _, s := range c.Streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
 // RelayConfig is the top-level YAML configuration.
 type RelayConfig struct {
-	Feeds []FeedConfig `yaml:"feeds"`
+	Feeds    []FeedConfig    `yaml:"feeds"`
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+
+	// WebhookDeadLetter is the JSONL file that undeliverable webhook
+	// events are appended to. Empty disables the dead-letter file.
+	WebhookDeadLetter string `yaml:"webhook_dead_letter,omitempty"`
+
+	Recorder RecorderConfig `yaml:"recorder,omitempty"`
+
+	// WSAllowedOrigins lists the browser origins (e.g.
+	// "https://dash.example.com") allowed to open the relay WebSocket
+	// besides the controller's own host. "*" allows any origin.
+	WSAllowedOrigins []string `yaml:"ws_allowed_origins,omitempty"`
+
+	matchers []*feedMatcher // compiled Feeds
+}
+
+// compile validates Feeds and builds their matchers.
+func (c *RelayConfig) compile() error {
+	matchers := make([]*feedMatcher, 0, len(c.Feeds))
+	for i, f := range c.Feeds {
+		if f.Name == "" {
+			return fmt.Errorf("relay config: feed[%d] missing name", i)
+		}
+		if f.URLPattern == "" && f.URLRegex == "" {
+			return fmt.Errorf("relay config: feed[%d] (%s) missing url_pattern or url_regex", i, f.Name)
+		}
+		m, err := compileFeed(f)
+		if err != nil {
+			return fmt.Errorf("relay config: feed[%d] (%s): %w", i, f.Name, err)
+		}
+		matchers = append(matchers, m)
+	}
+	c.matchers = matchers
+	return nil
 }
 
 // LoadConfig reads and validates a relay YAML config file.
@@ -29,12 +129,26 @@ func LoadConfig(path string) (*RelayConfig, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("relay config: %w", err)
 	}
-	for i, f := range cfg.Feeds {
-		if f.Name == "" {
-			return nil, fmt.Errorf("relay config: feed[%d] missing name", i)
+	if err := cfg.compile(); err != nil {
+		return nil, err
+	}
+	for _, s := range cfg.Recorder.Streams {
+		if s != "bars" && s != "quotes" {
+			return nil, fmt.Errorf("relay config: recorder: invalid stream %q", s)
+		}
+	}
+	for i, w := range cfg.Webhooks {
+		if w.Name == "" {
+			return nil, fmt.Errorf("relay config: webhook[%d] missing name", i)
+		}
+		if len(w.URLs) == 0 {
+			return nil, fmt.Errorf("relay config: webhook[%d] (%s) missing urls", i, w.Name)
 		}
-		if f.URLPattern == "" {
-			return nil, fmt.Errorf("relay config: feed[%d] (%s) missing url_pattern", i, f.Name)
+		for _, raw := range w.URLs {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("relay config: webhook[%d] (%s) invalid url %q", i, w.Name, raw)
+			}
 		}
 	}
 	return &cfg, nil
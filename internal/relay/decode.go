@@ -0,0 +1,241 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+// DecodeMessage converts a message into its typed form. Message types
+// without a dedicated decoder return their raw params unchanged.
+func DecodeMessage(m tvproto.Message) (any, error) {
+	fn, ok := decoders[m.Type]
+	if !ok {
+		return m.Params, nil
+	}
+	v, err := fn(m.ParamList())
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", m.Type, err)
+	}
+	return v, nil
+}
+
+// DecodedEvent is the typed SSE representation of a relayed message.
+type DecodedEvent struct {
+	Feed      string `json:"feed"`
+	Type      string `json:"type"`
+	Direction string `json:"direction,omitempty"`
+	Session   string `json:"session,omitempty"`
+	Data      any    `json:"data"`
+}
+
+var decoders = map[string]func([]json.RawMessage) (any, error){
+	"du":               decodeDataUpdate,
+	"timescale_update": decodeDataUpdate,
+	"qsd":              decodeQuoteData,
+	"quote_completed":  decodeQuoteCompleted,
+	"symbol_resolved":  decodeSymbolResolved,
+	"symbol_error":     decodeSymbolResolved,
+	"series_loading":   decodeSeriesStatus,
+	"series_completed": decodeSeriesStatus,
+	"series_timeframe": decodeSeriesStatus,
+	"series_error":     decodeSeriesStatus,
+	"study_loading":    decodeStudyStatus,
+	"study_completed":  decodeStudyStatus,
+	"study_deleted":    decodeStudyStatus,
+	"study_error":      decodeStudyStatus,
+	"alert_fired":      alertFiredDecoder("alert_fired"),
+	"event":            alertFiredDecoder("event"),
+	"fires_updated":    alertFiredDecoder("fires_updated"),
+	"alerts_created":   alertLifecycleDecoder("created"),
+	"alerts_updated":   alertLifecycleDecoder("updated"),
+	"alert_running":    alertLifecycleDecoder("running"),
+	"alerts_deleted":   alertLifecycleDecoder("deleted"),
+	"alert_deleted":    alertLifecycleDecoder("deleted"),
+}
+
+// Bar is one OHLCV bar from a series update.
+type Bar struct {
+	Index  int     `json:"index"`
+	Time   int64   `json:"time"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume"`
+}
+
+// PlotRow is one row of study plot values. Values[0] is the bar time.
+type PlotRow struct {
+	Index  int   `json:"index"`
+	Values []any `json:"values"`
+}
+
+// SeriesUpdate is the per-series payload of a du or timescale_update message.
+// Main and compare series carry Bars; studies carry Plots.
+type SeriesUpdate struct {
+	SeriesID     string          `json:"series_id"`
+	SeriesType   string          `json:"series_type,omitempty"`
+	Bars         []Bar           `json:"bars,omitempty"`
+	Plots        []PlotRow       `json:"plots,omitempty"`
+	BarCloseTime int64           `json:"bar_close_time,omitempty"`
+	Indexes      json.RawMessage `json:"indexes,omitempty"`
+}
+
+// DataUpdate is a decoded du (live tick) or timescale_update (history load).
+type DataUpdate struct {
+	Session string         `json:"session"`
+	Series  []SeriesUpdate `json:"series"`
+}
+
+// QuoteUpdate is a decoded qsd message. Values is sparse: only fields that
+// changed since the previous update are present.
+type QuoteUpdate struct {
+	Session string         `json:"session"`
+	Symbol  string         `json:"symbol"`
+	Status  string         `json:"status"`
+	Values  map[string]any `json:"values,omitempty"`
+}
+
+// QuoteCompleted is a decoded quote_completed message.
+type QuoteCompleted struct {
+	Session string `json:"session"`
+	Symbol  string `json:"symbol"`
+}
+
+// SymbolResolved is a decoded symbol_resolved or symbol_error message.
+type SymbolResolved struct {
+	Session  string         `json:"session"`
+	SymbolID string         `json:"symbol_id"`
+	Info     map[string]any `json:"info,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// SeriesStatus is a decoded series_loading/completed/timeframe/error message.
+type SeriesStatus struct {
+	Session    string            `json:"session"`
+	SeriesID   string            `json:"series_id"`
+	Turnaround string            `json:"turnaround,omitempty"`
+	Extra      []json.RawMessage `json:"extra,omitempty"`
+}
+
+// StudyStatus is a decoded study_loading/completed/deleted/error message.
+type StudyStatus struct {
+	Session    string            `json:"session"`
+	StudyID    string            `json:"study_id"`
+	Turnaround string            `json:"turnaround,omitempty"`
+	Extra      []json.RawMessage `json:"extra,omitempty"`
+}
+
+type wireSeries struct {
+	S  []wirePoint `json:"s"`
+	St []wirePoint `json:"st"`
+	T  string      `json:"t"`
+	NS *struct {
+		Indexes json.RawMessage `json:"indexes"`
+	} `json:"ns"`
+	LBS *struct {
+		BarCloseTime float64 `json:"bar_close_time"`
+	} `json:"lbs"`
+}
+
+type wirePoint struct {
+	I int   `json:"i"`
+	V []any `json:"v"`
+}
+
+func decodeDataUpdate(p []json.RawMessage) (any, error) {
+	if len(p) < 2 {
+		return nil, fmt.Errorf("expected 2 params, got %d", len(p))
+	}
+	var series map[string]wireSeries
+	if err := json.Unmarshal(p[1], &series); err != nil {
+		return nil, err
+	}
+	out := DataUpdate{Session: tvproto.ParamString(p, 0), Series: make([]SeriesUpdate, 0, len(series))}
+	for id, ws := range series {
+		su := SeriesUpdate{SeriesID: id, SeriesType: ws.T}
+		if ws.NS != nil {
+			su.Indexes = ws.NS.Indexes
+		}
+		if ws.LBS != nil {
+			su.BarCloseTime = int64(ws.LBS.BarCloseTime)
+		}
+		for _, pt := range ws.S {
+			su.Bars = append(su.Bars, barFromValues(pt.I, pt.V))
+		}
+		for _, pt := range ws.St {
+			su.Plots = append(su.Plots, PlotRow{Index: pt.I, Values: pt.V})
+		}
+		out.Series = append(out.Series, su)
+	}
+	sort.Slice(out.Series, func(i, j int) bool { return out.Series[i].SeriesID < out.Series[j].SeriesID })
+	return out, nil
+}
+
+func barFromValues(idx int, v []any) Bar {
+	b := Bar{Index: idx}
+	fields := []*float64{nil, &b.Open, &b.High, &b.Low, &b.Close, &b.Volume}
+	for i, raw := range v {
+		f, ok := raw.(float64)
+		if !ok {
+			continue
+		}
+		if i == 0 {
+			b.Time = int64(f)
+			continue
+		}
+		if i < len(fields) {
+			*fields[i] = f
+		}
+	}
+	return b
+}
+
+func decodeQuoteData(p []json.RawMessage) (any, error) {
+	if len(p) < 2 {
+		return nil, fmt.Errorf("expected 2 params, got %d", len(p))
+	}
+	var body struct {
+		N string         `json:"n"`
+		S string         `json:"s"`
+		V map[string]any `json:"v"`
+	}
+	if err := json.Unmarshal(p[1], &body); err != nil {
+		return nil, err
+	}
+	return QuoteUpdate{Session: tvproto.ParamString(p, 0), Symbol: body.N, Status: body.S, Values: body.V}, nil
+}
+
+func decodeQuoteCompleted(p []json.RawMessage) (any, error) {
+	return QuoteCompleted{Session: tvproto.ParamString(p, 0), Symbol: tvproto.ParamString(p, 1)}, nil
+}
+
+func decodeSymbolResolved(p []json.RawMessage) (any, error) {
+	out := SymbolResolved{Session: tvproto.ParamString(p, 0), SymbolID: tvproto.ParamString(p, 1)}
+	if len(p) > 2 {
+		if err := json.Unmarshal(p[2], &out.Info); err != nil {
+			// symbol_error carries a plain error string in the third slot.
+			out.Error = tvproto.ParamString(p, 2)
+		}
+	}
+	return out, nil
+}
+
+func decodeSeriesStatus(p []json.RawMessage) (any, error) {
+	out := SeriesStatus{Session: tvproto.ParamString(p, 0), SeriesID: tvproto.ParamString(p, 1), Turnaround: tvproto.ParamString(p, 2)}
+	if len(p) > 3 {
+		out.Extra = p[3:]
+	}
+	return out, nil
+}
+
+func decodeStudyStatus(p []json.RawMessage) (any, error) {
+	out := StudyStatus{Session: tvproto.ParamString(p, 0), StudyID: tvproto.ParamString(p, 1), Turnaround: tvproto.ParamString(p, 2)}
+	if len(p) > 3 {
+		out.Extra = p[3:]
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+func TestDecodeDataUpdate(t *testing.T) {
+	raw := `{"m":"du","p":["cs_abc",{"sds_1":{"s":[{"i":301,"v":[1771699560.0,68474.52,68483.99,68474.52,68483.99,0.35798]}],"ns":{"d":"","indexes":"nochange"},"t":"s3","lbs":{"bar_close_time":1771699620}}}]}`
+	msg, ok := tvproto.ParseMessage(raw)
+	if !ok {
+		t.Fatalf("ParseMessage() ok = false")
+	}
+	if msg.Type != "du" || msg.Session() != "cs_abc" {
+		t.Fatalf("type/session = %q/%q, want du/cs_abc", msg.Type, msg.Session())
+	}
+	v, err := DecodeMessage(msg)
+	if err != nil {
+		t.Fatalf("DecodeMessage() error = %v", err)
+	}
+	du, ok := v.(DataUpdate)
+	if !ok {
+		t.Fatalf("DecodeMessage() = %T, want DataUpdate", v)
+	}
+	if len(du.Series) != 1 || len(du.Series[0].Bars) != 1 {
+		t.Fatalf("unexpected series shape: %+v", du.Series)
+	}
+	want := Bar{Index: 301, Time: 1771699560, Open: 68474.52, High: 68483.99, Low: 68474.52, Close: 68483.99, Volume: 0.35798}
+	if got := du.Series[0].Bars[0]; got != want {
+		t.Fatalf("bar = %+v, want %+v", got, want)
+	}
+	if du.Series[0].BarCloseTime != 1771699620 {
+		t.Fatalf("bar_close_time = %d, want 1771699620", du.Series[0].BarCloseTime)
+	}
+}
+
+func TestDecodeQuoteData(t *testing.T) {
+	msg, _ := tvproto.ParseMessage(`{"m":"qsd","p":["qs_multiplexer_watchlist_x",{"n":"COINBASE:BTCUSD","s":"ok","v":{"lp":68483.99,"ch":499.57}}]}`)
+	v, err := DecodeMessage(msg)
+	if err != nil {
+		t.Fatalf("DecodeMessage() error = %v", err)
+	}
+	q := v.(QuoteUpdate)
+	if q.Symbol != "COINBASE:BTCUSD" || q.Values["lp"] != 68483.99 {
+		t.Fatalf("quote = %+v", q)
+	}
+}
@@ -1,55 +1,260 @@
 package relay
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
 )
 
+// keepaliveInterval is how often an SSE comment is sent so proxies do not
+// close idle streams.
+const keepaliveInterval = 15 * time.Second
+
 // SSEHandler returns an http.HandlerFunc that streams relay events as SSE.
 // Clients may filter feeds via ?feeds=name1,name2 query parameter.
+// With ?decode=true each event's data is a DecodedEvent with typed fields
+// instead of the raw message JSON. Every event carries an id; a client that
+// reconnects with Last-Event-ID gets the buffered events it missed.
+// ?overflow= selects the subscriber's OverflowPolicy, and
+// ?directions=sent,received limits frames by direction.
 func SSEHandler(broker *Broker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		flusher, ok := w.(http.Flusher)
+		// Parse optional feed filter.
+		feedFilter := parseListParam(r.URL.Query().Get("feeds"))
+		dirFilter := parseListParam(r.URL.Query().Get("directions"))
+
+		decode := r.URL.Query().Get("decode") == "true"
+
+		sub, ok := subscribeSSE(w, r, broker)
 		if !ok {
-			http.Error(w, "streaming not supported", http.StatusInternalServerError)
 			return
 		}
+		defer broker.Unsubscribe(sub.ID)
 
-		// Parse optional feed filter.
-		var feedFilter map[string]bool
-		if q := r.URL.Query().Get("feeds"); q != "" {
-			feedFilter = make(map[string]bool)
-			for _, f := range strings.Split(q, ",") {
-				if f = strings.TrimSpace(f); f != "" {
-					feedFilter[f] = true
-				}
+		flusher, ok := beginSSE(w)
+		if !ok {
+			return
+		}
+
+		pumpSSE(w, r, flusher, sub, resumeEvents(broker, r, feedFilter), func(evt Event) (string, string, bool) {
+			if feedFilter != nil && !feedFilter[evt.Feed] {
+				return "", "", false
+			}
+			if dirFilter != nil && !dirFilter[evt.Direction] {
+				return "", "", false
+			}
+			data := evt.Payload
+			if decode {
+				data = decodeEventData(evt)
 			}
+			return evt.Feed, data, true
+		})
+	}
+}
+
+// StatsHandler returns an http.HandlerFunc serving the broker's BrokerStats
+// as JSON.
+func StatsHandler(broker *Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(broker.Stats()); err != nil {
+			slog.Debug("relay stats response write failed", "error", err)
 		}
+	}
+}
 
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("X-Accel-Buffering", "no")
-		flusher.Flush()
+// subscribeSSE subscribes an SSE client to b using the ?overflow= policy.
+// It writes a 400 response and reports false for an unknown policy.
+func subscribeSSE(w http.ResponseWriter, r *http.Request, b *Broker) (*Subscription, bool) {
+	policy, err := ParseOverflowPolicy(r.URL.Query().Get("overflow"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return b.SubscribeWith(SubscribeOptions{Label: r.URL.Path + " " + r.RemoteAddr, Policy: policy}), true
+}
 
-		id, ch := broker.Subscribe()
-		defer broker.Unsubscribe(id)
+// lastEventID returns the client's resume point: the Last-Event-ID header
+// that EventSource sends on reconnect, or ?last_event_id= for other clients.
+func lastEventID(r *http.Request) uint64 {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	id, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+	return id
+}
 
-		for {
-			select {
-			case <-r.Context().Done():
-				return
-			case evt, ok := <-ch:
-				if !ok {
-					return
-				}
-				if feedFilter != nil && !feedFilter[evt.Feed] {
-					continue
-				}
-				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Feed, evt.Payload)
+// resumed is what a reconnecting client missed: the buffered events after
+// its Last-Event-ID and whether older ones it never saw were evicted.
+type resumed struct {
+	from   uint64
+	events []Event
+	lost   bool
+}
+
+// resumeEvents returns the buffered events a reconnecting client missed, or
+// nothing when the request carries no Last-Event-ID. Call it after
+// subscribing so nothing falls between the replay and the live stream.
+func resumeEvents(b *Broker, r *http.Request, feeds map[string]bool) resumed {
+	last := lastEventID(r)
+	if last == 0 {
+		return resumed{}
+	}
+	events, lost := b.Resume(last, feeds)
+	return resumed{from: last, events: events, lost: lost}
+}
+
+// GapNotice is the data of the synthetic "gap" SSE event sent before the
+// replay when the client's Last-Event-ID is older than the buffer: events
+// after LastEventID were lost, and the replay starts at FirstEventID (0 when
+// nothing is buffered).
+type GapNotice struct {
+	LastEventID  uint64 `json:"last_event_id"`
+	FirstEventID uint64 `json:"first_event_id"`
+}
+
+// OverflowNotice is the data of the synthetic "overflow" SSE event sent when
+// the subscriber lost events. LastEventID is the last event the client saw;
+// reconnecting with it as Last-Event-ID replays what is still buffered.
+type OverflowNotice struct {
+	Dropped      uint64 `json:"dropped"`
+	TotalDropped uint64 `json:"total_dropped"`
+	LastEventID  uint64 `json:"last_event_id"`
+	Disconnected bool   `json:"disconnected,omitempty"`
+}
+
+// pumpSSE writes the replay events and then live events from sub until the
+// client disconnects or the subscription is closed. render maps an event to
+// its SSE event name and data, or returns false to skip it. A "gap" event
+// precedes a replay that could not cover everything since the client's
+// Last-Event-ID. Live events already covered by the replay are skipped, an
+// "overflow" event precedes the next event after the subscription dropped
+// some (and ends a stream closed by the disconnect policy), and a keepalive
+// comment is sent periodically.
+func pumpSSE(w http.ResponseWriter, r *http.Request, flusher http.Flusher, sub *Subscription, replay resumed, render func(Event) (name, data string, ok bool)) {
+	var last, reported uint64
+	notifyOverflow := func(closing bool) {
+		total := sub.Dropped()
+		if total == reported && !(closing && sub.Disconnected()) {
+			return
+		}
+		data, _ := json.Marshal(OverflowNotice{
+			Dropped:      total - reported,
+			TotalDropped: total,
+			LastEventID:  last,
+			Disconnected: sub.Disconnected(),
+		})
+		writeSSE(w, 0, "overflow", string(data))
+		reported = total
+	}
+
+	if replay.lost {
+		gap := GapNotice{LastEventID: replay.from}
+		if len(replay.events) > 0 {
+			gap.FirstEventID = replay.events[0].ID
+		}
+		data, _ := json.Marshal(gap)
+		writeSSE(w, 0, "gap", string(data))
+	}
+	for _, evt := range replay.events {
+		if name, data, ok := render(evt); ok {
+			writeSSE(w, evt.ID, name, data)
+		}
+		last = evt.ID
+	}
+	flusher.Flush()
+	replayed := last
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case evt, ok := <-sub.C:
+			if !ok {
+				notifyOverflow(true)
 				flusher.Flush()
+				return
 			}
+			if evt.ID <= replayed {
+				continue
+			}
+			notifyOverflow(false)
+			if name, data, ok := render(evt); ok {
+				writeSSE(w, evt.ID, name, data)
+			}
+			last = evt.ID
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE writes one SSE event. Events without an ID (e.g. snapshots that
+// are not part of the broker stream) omit the id field.
+func writeSSE(w http.ResponseWriter, id uint64, name, data string) {
+	if id > 0 {
+		fmt.Fprintf(w, "id: %d\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
+
+// parseListParam parses a comma-separated query value into a set.
+// Returns nil (meaning "no filter") when the value is empty.
+func parseListParam(q string) map[string]bool {
+	if q == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, f := range strings.Split(q, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			set[f] = true
 		}
 	}
+	return set
+}
+
+// beginSSE writes the event-stream response headers. It reports false (after
+// writing an error response) when the ResponseWriter cannot stream.
+func beginSSE(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	flusher.Flush()
+	return flusher, true
+}
+
+// decodeEventData renders an event as DecodedEvent JSON, falling back to the
+// raw payload when the message cannot be decoded.
+func decodeEventData(evt Event) string {
+	msg, ok := tvproto.ParseMessage(evt.Payload)
+	if !ok {
+		return evt.Payload
+	}
+	data, err := DecodeMessage(msg)
+	if err != nil {
+		slog.Debug("relay: decode failed", "feed", evt.Feed, "type", msg.Type, "error", err)
+		return evt.Payload
+	}
+	out, err := json.Marshal(DecodedEvent{Feed: evt.Feed, Type: msg.Type, Direction: evt.Direction, Session: msg.Session(), Data: data})
+	if err != nil {
+		return evt.Payload
+	}
+	return string(out)
 }
@@ -0,0 +1,200 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+// feedMatcher is the compiled form of a FeedConfig.
+type feedMatcher struct {
+	name       string
+	urlSubstr  string
+	urlRe      *regexp.Regexp
+	msgFilter  map[string]bool // nil means accept all
+	directions map[string]bool
+	sessions   []string // path.Match globs; empty means any session
+	filters    []payloadMatcher
+}
+
+// payloadMatcher is the compiled form of a PayloadFilter.
+type payloadMatcher struct {
+	path   []tvproto.PathStep
+	types  map[string]bool // nil means every message type
+	values map[string]bool
+	re     *regexp.Regexp
+}
+
+func compileFeed(f FeedConfig) (*feedMatcher, error) {
+	m := &feedMatcher{name: f.Name, urlSubstr: f.URLPattern}
+	if f.URLRegex != "" {
+		re, err := regexp.Compile(f.URLRegex)
+		if err != nil {
+			return nil, fmt.Errorf("url_regex: %w", err)
+		}
+		m.urlRe = re
+	}
+	m.directions = map[string]bool{DirectionReceived: true}
+	if len(f.Directions) > 0 {
+		m.directions = toSet(f.Directions)
+	}
+	for _, d := range f.Directions {
+		if d != DirectionReceived && d != DirectionSent {
+			return nil, fmt.Errorf("invalid direction %q", d)
+		}
+	}
+	m.msgFilter = toSet(f.MessageTypes)
+	for _, s := range f.Sessions {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("invalid session pattern %q: %w", s, err)
+		}
+	}
+	m.sessions = f.Sessions
+	for i, pf := range f.Filters {
+		pm, err := compilePayloadFilter(pf)
+		if err != nil {
+			return nil, fmt.Errorf("filters[%d]: %w", i, err)
+		}
+		m.filters = append(m.filters, pm)
+	}
+	return m, nil
+}
+
+func compilePayloadFilter(pf PayloadFilter) (payloadMatcher, error) {
+	// Paths are evaluated against the unwrapped {"m","p"} message.
+	steps, err := tvproto.ParsePath(pf.Path)
+	if err != nil {
+		return payloadMatcher{}, err
+	}
+	if len(pf.Values) == 0 && pf.Match == "" {
+		return payloadMatcher{}, fmt.Errorf("path %q needs values or match", pf.Path)
+	}
+	pm := payloadMatcher{path: steps, values: toSet(pf.Values), types: toSet(pf.MessageTypes)}
+	if pf.Match != "" {
+		re, err := regexp.Compile(pf.Match)
+		if err != nil {
+			return payloadMatcher{}, fmt.Errorf("match: %w", err)
+		}
+		pm.re = re
+	}
+	return pm, nil
+}
+
+// matchURL reports whether the feed applies to a socket URL. A feed with
+// both url_pattern and url_regex requires both to match.
+func (m *feedMatcher) matchURL(url string) bool {
+	if m.urlSubstr != "" && !strings.Contains(url, m.urlSubstr) {
+		return false
+	}
+	if m.urlRe != nil && !m.urlRe.MatchString(url) {
+		return false
+	}
+	return true
+}
+
+// accept applies the feed's direction, message-type, session and payload
+// filters to one message.
+func (m *feedMatcher) accept(direction string, msg tvproto.Message, parsed bool) bool {
+	if !m.directions[direction] {
+		return false
+	}
+	if m.msgFilter != nil && (!parsed || !m.msgFilter[msg.Type]) {
+		return false
+	}
+	if len(m.sessions) > 0 && parsed {
+		// Messages without a session (e.g. protocol errors) pass.
+		if s := msg.Session(); s != "" && !matchAny(m.sessions, s) {
+			return false
+		}
+	}
+	for _, f := range m.filters {
+		if f.types != nil && (!parsed || !f.types[msg.Type]) {
+			continue
+		}
+		if !parsed || !f.match(msg) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(globs []string, s string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether any value at the filter's path is accepted. String
+// values also match by their plain symbol when given as a "={...}" spec.
+func (f payloadMatcher) match(msg tvproto.Message) bool {
+	for _, v := range resolvePath(msg, f.path) {
+		s := scalarString(v)
+		for _, cand := range []string{s, tvproto.ParseSymbolSpec(s)} {
+			if f.values[cand] || (f.re != nil && f.re.MatchString(cand)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolvePath returns every value the path selects in msg.
+func resolvePath(msg tvproto.Message, steps []tvproto.PathStep) []json.RawMessage {
+	var cur []json.RawMessage
+	switch {
+	case steps[0].Key == "m":
+		typ, _ := json.Marshal(msg.Type)
+		cur = []json.RawMessage{typ}
+	case steps[0].Key == "p" && len(msg.Params) > 0:
+		cur = []json.RawMessage{msg.Params}
+	default:
+		return nil
+	}
+	for _, st := range steps[1:] {
+		var next []json.RawMessage
+		for _, v := range cur {
+			if st.Key != "" {
+				var obj map[string]json.RawMessage
+				if json.Unmarshal(v, &obj) != nil {
+					continue
+				}
+				if st.Any {
+					for _, child := range obj {
+						next = append(next, child)
+					}
+				} else if child, ok := obj[st.Key]; ok {
+					next = append(next, child)
+				}
+				continue
+			}
+			var arr []json.RawMessage
+			if json.Unmarshal(v, &arr) != nil {
+				continue
+			}
+			if st.Any {
+				next = append(next, arr...)
+			} else if st.Index < len(arr) {
+				next = append(next, arr[st.Index])
+			}
+		}
+		cur = next
+	}
+	return cur
+}
+
+// scalarString renders a JSON value for comparison: strings unquoted,
+// anything else as its JSON text.
+func scalarString(v json.RawMessage) string {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return strings.TrimSpace(string(v))
+}
@@ -0,0 +1,169 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+func TestRelayFansOutAndFiltersPayloads(t *testing.T) {
+	cfg := &RelayConfig{Feeds: []FeedConfig{
+		{Name: "chart_data", URLRegex: `^wss://(data|prodata)\.tradingview\.com/socket\.io/websocket`},
+		{
+			Name:         "watchlist_quotes",
+			URLPattern:   "socket.io/websocket",
+			MessageTypes: []string{"qsd"},
+			Sessions:     []string{"qs_multiplexer_watchlist_*"},
+			Filters:      []PayloadFilter{{Path: "p[1].n", Values: []string{"NASDAQ:AAPL"}}},
+		},
+	}}
+	b := NewBroker()
+	rl := NewRelay(cfg, b)
+	rl.onWebSocketCreated("", json.RawMessage(`{"requestId":"1","url":"wss://prodata.tradingview.com/socket.io/websocket?from=chart"}`))
+	rl.onWebSocketCreated("", json.RawMessage(`{"requestId":"2","url":"wss://pushstream.tradingview.com/message-pipe-ws/public"}`))
+
+	frame := func(id, raw string) json.RawMessage {
+		data, _ := json.Marshal(map[string]any{"requestId": id, "response": map[string]string{"payloadData": encodeFrame(raw)}})
+		return data
+	}
+	rl.onWebSocketFrameReceived("", frame("1", `{"m":"qsd","p":["qs_multiplexer_watchlist_abc",{"n":"NASDAQ:AAPL","s":"ok","v":{"lp":1}}]}`))
+	rl.onWebSocketFrameReceived("", frame("1", `{"m":"qsd","p":["qs_multiplexer_watchlist_abc",{"n":"NYSE:IBM","s":"ok","v":{"lp":2}}]}`))
+	rl.onWebSocketFrameReceived("", frame("1", `{"m":"qsd","p":["qs_snapshoter_xyz",{"n":"NASDAQ:AAPL","s":"ok","v":{"lp":3}}]}`))
+	rl.onWebSocketFrameReceived("", frame("2", `{"m":"qsd","p":["qs_multiplexer_watchlist_abc",{"n":"NASDAQ:AAPL"}]}`))
+	// Heartbeats reach only the feed without filters.
+	rl.onWebSocketFrameReceived("", frame("1", "~h~7"))
+
+	counts := map[string]int{}
+	for _, evt := range b.Since(0, nil) {
+		counts[evt.Feed]++
+	}
+	if counts["chart_data"] != 4 || counts["watchlist_quotes"] != 1 || len(counts) != 2 {
+		t.Fatalf("per-feed counts = %v, want chart_data=4 watchlist_quotes=1", counts)
+	}
+}
+
+func TestOverlappingFeedsFeedConsumersOnce(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { posts.Add(1) }))
+	defer srv.Close()
+
+	cfg := &RelayConfig{Feeds: []FeedConfig{
+		{Name: "chart_data", URLPattern: "socket.io/websocket", MessageTypes: []string{"du", "qsd"}},
+		{Name: "watchlist_quotes", URLPattern: "socket.io/websocket", MessageTypes: []string{"qsd"}},
+	}}
+	b := NewBroker()
+	rl := NewRelay(cfg, b)
+	quotes := NewQuoteAggregator(b)
+	quotes.Start()
+	defer quotes.Stop()
+	_, quoteCh := quotes.Events().Subscribe()
+	hooks := NewWebhookDispatcher([]WebhookConfig{{Name: "all", URLs: []string{srv.URL}}}, "", b)
+	hooks.Start()
+	defer hooks.Stop()
+
+	rl.onWebSocketCreated("", json.RawMessage(`{"requestId":"1","url":"wss://data.tradingview.com/socket.io/websocket"}`))
+	data, _ := json.Marshal(map[string]any{"requestId": "1", "response": map[string]string{
+		"payloadData": encodeFrame(`{"m":"qsd","p":["qs_1",{"n":"NASDAQ:AAPL","s":"ok","v":{"lp":1}}]}`)}})
+	rl.onWebSocketFrameReceived("", data)
+
+	copies := b.Since(0, nil)
+	if len(copies) != 2 || copies[0].MessageID == 0 || copies[0].MessageID != copies[1].MessageID {
+		t.Fatalf("published = %+v, want two copies with one MessageID", copies)
+	}
+	waitFor(t, func() bool { return posts.Load() == 1 && len(quoteCh) == 1 })
+	time.Sleep(50 * time.Millisecond)
+	if n, q := posts.Load(), len(quoteCh); n != 1 || q != 1 {
+		t.Fatalf("webhook posts = %d, quote events = %d, want 1 each", n, q)
+	}
+
+	// The bar tracker skips the second copy of a du as well.
+	tr := NewBarTracker(NewBroker(), nil)
+	_, barCh := tr.out.Subscribe()
+	du := duEvent("abc", "sds_1", `[{"i":1,"v":[60,1,1,1,1,1]}]`, 120)
+	du.MessageID = 7
+	tr.handle(du)
+	du.Feed = "other"
+	tr.handle(du)
+	if got := drainBars(t, barCh); len(got) != 1 {
+		t.Fatalf("bar events = %d, want 1", len(got))
+	}
+}
+
+func TestPayloadFilterPaths(t *testing.T) {
+	msg := mustParse(t, `{"m":"quote_add_symbols","p":["qs_1","NYSE:IBM","={\"symbol\":\"NASDAQ:AAPL\"}"]}`)
+	cases := []struct {
+		filter PayloadFilter
+		want   bool
+	}{
+		{PayloadFilter{Path: "p[*]", Values: []string{"NASDAQ:AAPL"}}, true},
+		{PayloadFilter{Path: "$.p[1]", Match: `^NYSE:`}, true},
+		{PayloadFilter{Path: "p[5]", Values: []string{"NYSE:IBM"}}, false},
+		{PayloadFilter{Path: "m", Values: []string{"quote_add_symbols"}}, true},
+		{PayloadFilter{Path: "p[1]", Values: []string{"X"}, MessageTypes: []string{"qsd"}}, true},
+	}
+	for _, c := range cases {
+		m, err := compileFeed(FeedConfig{Name: "f", URLPattern: "x", Filters: []PayloadFilter{c.filter}})
+		if err != nil {
+			t.Fatalf("%+v: %v", c.filter, err)
+		}
+		if got := m.accept(DirectionReceived, msg, true); got != c.want {
+			t.Errorf("%+v: accept = %v, want %v", c.filter, got, c.want)
+		}
+	}
+
+	// A "*" key matches every field of an object.
+	m, err := compileFeed(FeedConfig{Name: "f", URLPattern: "x", Filters: []PayloadFilter{{Path: "p[1].*", Values: []string{"NYSE:IBM"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.accept(DirectionReceived, mustParse(t, `{"m":"qsd","p":["qs_1",{"n":"NYSE:IBM","s":"ok"}]}`), true) {
+		t.Error("p[1].* did not match the qsd symbol")
+	}
+}
+
+func TestLoadConfigRejectsBadPatterns(t *testing.T) {
+	bad := map[string]string{
+		"regex":     "  - name: f\n    url_regex: \"(\"\n",
+		"no url":    "  - name: f\n",
+		"path":      "  - name: f\n    url_pattern: x\n    filters:\n      - path: \"p[a]\"\n        values: [y]\n",
+		"no values": "  - name: f\n    url_pattern: x\n    filters:\n      - path: \"p[1].n\"\n",
+		"session":   "  - name: f\n    url_pattern: x\n    sessions: [\"qs_[\"]\n",
+		"direction": "  - name: f\n    url_pattern: x\n    directions: [both]\n",
+	}
+	path := filepath.Join(t.TempDir(), "relay.yaml")
+	for name, feeds := range bad {
+		if err := os.WriteFile(path, []byte("feeds:\n"+feeds), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestShippedChartDataFeedCarriesBars(t *testing.T) {
+	cfg, err := LoadConfig("../../config/relay.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range cfg.matchers {
+		if m.name != "chart_data" {
+			continue
+		}
+		// The bar tracker reads both live ticks and history loads.
+		for _, typ := range []string{"du", "timescale_update"} {
+			if !m.accept(DirectionReceived, tvproto.Message{Type: typ}, true) {
+				t.Errorf("chart_data drops %s", typ)
+			}
+		}
+		return
+	}
+	t.Fatal("no chart_data feed")
+}
@@ -0,0 +1,221 @@
+package relay
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+// Quote is the merged current state of one symbol, built by folding the
+// sparse qsd deltas into a full set of values. The common price fields are
+// lifted out of Values for convenience; Values keeps everything received.
+type Quote struct {
+	Symbol    string         `json:"symbol"`
+	Status    string         `json:"status"`
+	LastPrice *float64       `json:"lp,omitempty"`
+	Change    *float64       `json:"ch,omitempty"`
+	ChangePct *float64       `json:"chp,omitempty"`
+	Bid       *float64       `json:"bid,omitempty"`
+	Ask       *float64       `json:"ask,omitempty"`
+	Volume    *float64       `json:"volume,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Values    map[string]any `json:"values"`
+}
+
+// QuoteAggregator consumes qsd messages from the relay broker and keeps the
+// merged quote for every symbol seen. Each merge is republished on its own
+// broker for streaming clients.
+type QuoteAggregator struct {
+	source *Broker
+	out    *Broker
+
+	handled recentMessages // worker only
+
+	mu     sync.RWMutex
+	quotes map[string]*Quote
+
+	now  func() time.Time
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewQuoteAggregator creates an aggregator that consumes events from source.
+func NewQuoteAggregator(source *Broker) *QuoteAggregator {
+	return &QuoteAggregator{
+		source: source,
+		out:    NewBroker(),
+		quotes: make(map[string]*Quote),
+		now:    time.Now,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start subscribes to the source broker and begins merging quotes.
+func (a *QuoteAggregator) Start() {
+	sub := a.source.SubscribeWith(SubscribeOptions{Label: "quote_aggregator"})
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer a.source.Unsubscribe(sub.ID)
+		for {
+			select {
+			case <-a.stop:
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				a.handle(evt)
+			}
+		}
+	}()
+}
+
+// Stop ends aggregation and waits for the worker to exit.
+func (a *QuoteAggregator) Stop() {
+	close(a.stop)
+	a.wg.Wait()
+}
+
+// Events returns the broker the derived events are published on.
+func (a *QuoteAggregator) Events() *Broker {
+	return a.out
+}
+
+// Snapshot returns the current quote of every known symbol (or only those
+// in symbols, when non-nil), sorted by symbol.
+func (a *QuoteAggregator) Snapshot(symbols map[string]bool) []Quote {
+	a.mu.RLock()
+	out := make([]Quote, 0, len(a.quotes))
+	for sym, q := range a.quotes {
+		if symbols != nil && !symbols[sym] {
+			continue
+		}
+		out = append(out, q.clone())
+	}
+	a.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+func (a *QuoteAggregator) handle(evt Event) {
+	if evt.Type != "qsd" || a.handled.seen(evt.MessageID) {
+		return
+	}
+	msg, ok := tvproto.ParseMessage(evt.Payload)
+	if !ok {
+		return
+	}
+	v, err := DecodeMessage(msg)
+	if err != nil {
+		slog.Debug("quote aggregator: decode failed", "error", err)
+		return
+	}
+	qu, ok := v.(QuoteUpdate)
+	if !ok || qu.Symbol == "" {
+		return
+	}
+
+	a.mu.Lock()
+	q := a.quotes[qu.Symbol]
+	if q == nil {
+		q = &Quote{Symbol: qu.Symbol, Values: make(map[string]any)}
+		a.quotes[qu.Symbol] = q
+	}
+	q.merge(qu, a.now().UTC())
+	merged := q.clone()
+	a.mu.Unlock()
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return
+	}
+	a.out.Publish(Event{Feed: "quotes", Type: "quote", Symbol: merged.Symbol, Payload: string(data)})
+}
+
+func (q *Quote) merge(u QuoteUpdate, at time.Time) {
+	if u.Status != "" {
+		q.Status = u.Status
+	}
+	for k, v := range u.Values {
+		q.Values[k] = v
+	}
+	q.LastPrice = floatValue(q.Values, "lp")
+	q.Change = floatValue(q.Values, "ch")
+	q.ChangePct = floatValue(q.Values, "chp")
+	q.Bid = floatValue(q.Values, "bid")
+	q.Ask = floatValue(q.Values, "ask")
+	q.Volume = floatValue(q.Values, "volume")
+	q.UpdatedAt = at
+}
+
+func (q *Quote) clone() Quote {
+	c := *q
+	c.Values = make(map[string]any, len(q.Values))
+	for k, v := range q.Values {
+		c.Values[k] = v
+	}
+	return c
+}
+
+func floatValue(values map[string]any, key string) *float64 {
+	f, ok := values[key].(float64)
+	if !ok {
+		return nil
+	}
+	return &f
+}
+
+// QuotesHandler returns an http.HandlerFunc serving the merged quote snapshot
+// as JSON. Clients may limit it via ?symbols=EXCHANGE:SYM1,EXCHANGE:SYM2.
+func QuotesHandler(a *QuoteAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Quotes []Quote `json:"quotes"`
+		}{Quotes: a.Snapshot(parseListParam(r.URL.Query().Get("symbols")))}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			slog.Debug("quotes response write failed", "error", err)
+		}
+	}
+}
+
+// QuoteStreamHandler returns an http.HandlerFunc that streams merged quotes
+// as SSE "quote" events. The current state of each matching symbol is sent
+// first, then every update. Filter with ?symbols=EXCHANGE:SYM1,EXCHANGE:SYM2.
+func QuoteStreamHandler(a *QuoteAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbols := parseListParam(r.URL.Query().Get("symbols"))
+
+		// Subscribe before taking the snapshot so no update falls in between.
+		sub, ok := subscribeSSE(w, r, a.out)
+		if !ok {
+			return
+		}
+		defer a.out.Unsubscribe(sub.ID)
+
+		flusher, ok := beginSSE(w)
+		if !ok {
+			return
+		}
+
+		// The snapshot already holds the merged state, so reconnecting clients
+		// need no replay.
+		for _, q := range a.Snapshot(symbols) {
+			data, err := json.Marshal(q)
+			if err != nil {
+				continue
+			}
+			writeSSE(w, 0, "quote", string(data))
+		}
+
+		pumpSSE(w, r, flusher, sub, resumed{}, func(evt Event) (string, string, bool) {
+			return evt.Type, evt.Payload, symbols == nil || symbols[evt.Symbol]
+		})
+	}
+}
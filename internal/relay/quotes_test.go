@@ -0,0 +1,61 @@
+package relay
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func qsdEvent(symbol, status, values string) Event {
+	payload := `{"m":"qsd","p":["qs_multiplexer_watchlist_1",{"n":"` + symbol + `","s":"` + status + `","v":` + values + `}]}`
+	return Event{Feed: "chart_data", Type: "qsd", Payload: payload}
+}
+
+func TestQuoteAggregatorMergesDeltas(t *testing.T) {
+	a := NewQuoteAggregator(NewBroker())
+	_, ch := a.out.Subscribe()
+
+	a.handle(qsdEvent("NASDAQ:AAPL", "ok", `{"lp":190.5,"ch":1.2,"chp":0.63,"volume":1000,"description":"Apple Inc."}`))
+	a.handle(qsdEvent("NASDAQ:AAPL", "ok", `{"lp":191}`))
+	a.handle(qsdEvent("NYSE:IBM", "ok", `{"lp":140}`))
+
+	snap := a.Snapshot(nil)
+	if len(snap) != 2 || snap[0].Symbol != "NASDAQ:AAPL" || snap[1].Symbol != "NYSE:IBM" {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+	aapl := snap[0]
+	if aapl.LastPrice == nil || *aapl.LastPrice != 191 {
+		t.Fatalf("lp = %v, want 191", aapl.LastPrice)
+	}
+	if aapl.Change == nil || *aapl.Change != 1.2 || aapl.Values["description"] != "Apple Inc." {
+		t.Fatalf("earlier fields lost after delta: %+v", aapl)
+	}
+
+	if got := a.Snapshot(map[string]bool{"NYSE:IBM": true}); len(got) != 1 || got[0].Symbol != "NYSE:IBM" {
+		t.Fatalf("filtered snapshot = %+v", got)
+	}
+
+	var events []Quote
+	for len(ch) > 0 {
+		evt := <-ch
+		var q Quote
+		if err := json.Unmarshal([]byte(evt.Payload), &q); err != nil {
+			t.Fatalf("unmarshal quote: %v", err)
+		}
+		if evt.Symbol != q.Symbol {
+			t.Fatalf("event symbol %q != payload symbol %q", evt.Symbol, q.Symbol)
+		}
+		events = append(events, q)
+	}
+	if len(events) != 3 || events[1].Volume == nil || *events[1].Volume != 1000 {
+		t.Fatalf("stream events should carry merged state: %+v", events)
+	}
+}
+
+func TestQuoteAggregatorIgnoresOtherTypes(t *testing.T) {
+	a := NewQuoteAggregator(NewBroker())
+	a.handle(Event{Type: "du", Payload: `{"m":"du","p":["cs_1",{}]}`})
+	a.handle(Event{Type: "qsd", Payload: `{"m":"qsd","p":["qs_1"]}`})
+	if got := a.Snapshot(nil); len(got) != 0 {
+		t.Fatalf("snapshot = %+v, want empty", got)
+	}
+}
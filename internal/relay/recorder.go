@@ -0,0 +1,323 @@
+package relay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxOpenRecordFiles bounds the append handles a Recorder keeps open.
+const maxOpenRecordFiles = 64
+
+// HistoryBar is one recorded OHLCV bar. Time is the bar open in Unix seconds.
+type HistoryBar struct {
+	Time   int64   `json:"time"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume"`
+}
+
+// QuoteTick is one recorded quote update: the merged price fields of a
+// symbol at the time the update arrived.
+type QuoteTick struct {
+	Time      time.Time `json:"time"`
+	LastPrice *float64  `json:"lp,omitempty"`
+	Change    *float64  `json:"ch,omitempty"`
+	ChangePct *float64  `json:"chp,omitempty"`
+	Bid       *float64  `json:"bid,omitempty"`
+	Ask       *float64  `json:"ask,omitempty"`
+	Volume    *float64  `json:"volume,omitempty"`
+}
+
+// Recorder persists closed bars from a BarTracker and quote updates from a
+// QuoteAggregator to date-partitioned JSONL files, so data keeps being
+// collected while no client is connected:
+//
+//	<dir>/<YYYY-MM-DD>/bars/<symbol>/<resolution>.jsonl
+//	<dir>/<YYYY-MM-DD>/quotes/<symbol>.jsonl
+//
+// Bars are partitioned by their own (UTC) time, quotes by arrival time.
+// Symbol and resolution are path-escaped. Bars without a known symbol and
+// resolution (see tvproto.SeriesRegistry) are not recorded.
+type Recorder struct {
+	dir    string
+	bars   *Broker // may be nil
+	quotes *Broker // may be nil
+
+	mu    sync.Mutex
+	files map[string]*os.File
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRecorder creates a recorder writing under dir. bars and quotes are the
+// output brokers of a BarTracker and QuoteAggregator; either may be nil.
+func NewRecorder(dir string, bars, quotes *Broker) *Recorder {
+	return &Recorder{
+		dir:    dir,
+		bars:   bars,
+		quotes: quotes,
+		files:  make(map[string]*os.File),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start subscribes to the source brokers and begins recording.
+func (r *Recorder) Start() {
+	for _, b := range []*Broker{r.bars, r.quotes} {
+		if b == nil {
+			continue
+		}
+		sub := b.SubscribeWith(SubscribeOptions{Label: "recorder"})
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer b.Unsubscribe(sub.ID)
+			for {
+				select {
+				case <-r.stop:
+					return
+				case evt, ok := <-sub.C:
+					if !ok {
+						return
+					}
+					r.handle(evt)
+				}
+			}
+		}()
+	}
+}
+
+// Stop ends recording, waits for the workers and closes all files.
+func (r *Recorder) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeFilesLocked()
+}
+
+func (r *Recorder) handle(evt Event) {
+	switch evt.Type {
+	case "bar":
+		var b BarEvent
+		if err := json.Unmarshal([]byte(evt.Payload), &b); err != nil || !b.Closed {
+			return
+		}
+		if b.Symbol == "" || b.Resolution == "" {
+			slog.Debug("recorder: skipping unlabelled bar", "chart_id", b.ChartID, "series_id", b.SeriesID)
+			return
+		}
+		bar := HistoryBar{Time: b.Time, Open: b.Open, High: b.High, Low: b.Low, Close: b.Close, Volume: b.Volume}
+		if err := r.WriteBars(b.Symbol, b.Resolution, []HistoryBar{bar}); err != nil {
+			slog.Warn("recorder: write bar failed", "symbol", b.Symbol, "error", err)
+		}
+	case "quote":
+		var q Quote
+		if err := json.Unmarshal([]byte(evt.Payload), &q); err != nil || q.Symbol == "" {
+			return
+		}
+		tick := QuoteTick{Time: q.UpdatedAt, LastPrice: q.LastPrice, Change: q.Change, ChangePct: q.ChangePct, Bid: q.Bid, Ask: q.Ask, Volume: q.Volume}
+		if tick.Time.IsZero() {
+			tick.Time = time.Now()
+		}
+		tick.Time = tick.Time.UTC()
+		path := filepath.Join(r.dir, tick.Time.Format(time.DateOnly), "quotes", url.PathEscape(q.Symbol)+".jsonl")
+		if err := r.appendJSON(path, tick); err != nil {
+			slog.Warn("recorder: write quote failed", "symbol", q.Symbol, "error", err)
+		}
+	}
+}
+
+// WriteBars appends bars for symbol and resolution to the store. Later
+// writes of the same bar time win when reading back.
+func (r *Recorder) WriteBars(symbol, resolution string, bars []HistoryBar) error {
+	for _, b := range bars {
+		if err := r.appendJSON(r.barPath(symbol, resolution, time.Unix(b.Time, 0).UTC()), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) barPath(symbol, resolution string, day time.Time) string {
+	return filepath.Join(r.dir, day.Format(time.DateOnly), "bars", url.PathEscape(symbol), url.PathEscape(resolution)+".jsonl")
+}
+
+func (r *Recorder) appendJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.files[path]
+	if !ok {
+		if len(r.files) >= maxOpenRecordFiles {
+			r.closeFilesLocked()
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		r.files[path] = f
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (r *Recorder) closeFilesLocked() {
+	for path, f := range r.files {
+		if err := f.Close(); err != nil {
+			slog.Warn("recorder: close failed", "file", path, "error", err)
+		}
+		delete(r.files, path)
+	}
+}
+
+// Bars returns the recorded bars for symbol and resolution with from <= time
+// <= to, oldest first, one per bar time.
+func (r *Recorder) Bars(symbol, resolution string, from, to time.Time) ([]HistoryBar, error) {
+	days, err := r.days(from, to)
+	if err != nil {
+		return nil, err
+	}
+	byTime := make(map[int64]HistoryBar)
+	for _, day := range days {
+		if err := readBarFile(r.barPath(symbol, resolution, day), from.Unix(), to.Unix(), byTime); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]HistoryBar, 0, len(byTime))
+	for _, b := range byTime {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time < out[j].Time })
+	return out, nil
+}
+
+// days lists the date partitions present on disk between from and to.
+func (r *Recorder) days(from, to time.Time) ([]time.Time, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	first := from.UTC().Truncate(24 * time.Hour)
+	var out []time.Time
+	for _, e := range entries {
+		day, err := time.Parse(time.DateOnly, e.Name())
+		if err != nil || !e.IsDir() || day.Before(first) || day.After(to) {
+			continue
+		}
+		out = append(out, day)
+	}
+	return out, nil
+}
+
+func readBarFile(path string, from, to int64, into map[int64]HistoryBar) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var b HistoryBar
+		// A line being appended concurrently may be incomplete; skip it.
+		if json.Unmarshal(sc.Bytes(), &b) != nil || b.Time < from || b.Time > to {
+			continue
+		}
+		into[b.Time] = b
+	}
+	return sc.Err()
+}
+
+// HistoryBarsHandler returns an http.HandlerFunc serving recorded bars as
+// JSON for ?symbol=&resolution=&from=&to=. from and to accept Unix seconds,
+// RFC 3339 or YYYY-MM-DD; to defaults to now and from to 24 hours before to.
+func HistoryBarsHandler(rec *Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		symbol, resolution := strings.TrimSpace(q.Get("symbol")), strings.TrimSpace(q.Get("resolution"))
+		if symbol == "" || resolution == "" {
+			http.Error(w, "symbol and resolution are required", http.StatusBadRequest)
+			return
+		}
+		to := time.Now()
+		if v := q.Get("to"); v != "" {
+			t, err := parseHistoryTime(v)
+			if err != nil {
+				http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			to = t
+		}
+		from := to.Add(-24 * time.Hour)
+		if v := q.Get("from"); v != "" {
+			t, err := parseHistoryTime(v)
+			if err != nil {
+				http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			from = t
+		}
+		if from.After(to) {
+			http.Error(w, "from is after to", http.StatusBadRequest)
+			return
+		}
+
+		bars, err := rec.Bars(symbol, resolution, from, to)
+		if err != nil {
+			slog.Error("history bars query failed", "symbol", symbol, "error", err)
+			http.Error(w, "history query failed", http.StatusInternalServerError)
+			return
+		}
+		body := struct {
+			Symbol     string       `json:"symbol"`
+			Resolution string       `json:"resolution"`
+			From       int64        `json:"from"`
+			To         int64        `json:"to"`
+			Bars       []HistoryBar `json:"bars"`
+		}{symbol, resolution, from.Unix(), to.Unix(), bars}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			slog.Debug("history bars response write failed", "error", err)
+		}
+	}
+}
+
+func parseHistoryTime(v string) (time.Time, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not Unix seconds, RFC 3339 or YYYY-MM-DD", v)
+}
@@ -0,0 +1,79 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorderRecordsClosedBarsAndQuotes(t *testing.T) {
+	dir := t.TempDir()
+	bars, quotes := NewBroker(), NewBroker()
+	rec := NewRecorder(dir, bars, quotes)
+	rec.Start()
+
+	publishBar := func(ts int64, close float64, closed bool) {
+		data, _ := json.Marshal(BarEvent{ChartID: "abc", Symbol: "NASDAQ:AAPL", Resolution: "1", Time: ts, Close: close, Closed: closed})
+		bars.Publish(Event{Feed: "bars", Type: "bar", Payload: string(data)})
+	}
+	day := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC).Unix()
+	publishBar(day, 1, false)
+	publishBar(day, 2, true)
+	publishBar(day+60, 3, true)
+	lp := 10.5
+	data, _ := json.Marshal(Quote{Symbol: "NASDAQ:AAPL", LastPrice: &lp, UpdatedAt: time.Unix(day, 0)})
+	quotes.Publish(Event{Feed: "quotes", Type: "quote", Symbol: "NASDAQ:AAPL", Payload: string(data)})
+
+	quotePath := filepath.Join(dir, "2026-03-02", "quotes", "NASDAQ:AAPL.jsonl")
+	waitFor(t, func() bool { _, err := os.Stat(quotePath); return err == nil })
+	waitFor(t, func() bool {
+		got, _ := rec.Bars("NASDAQ:AAPL", "1", time.Unix(day, 0), time.Unix(day+60, 0))
+		return len(got) == 2
+	})
+	rec.Stop()
+
+	got, err := rec.Bars("NASDAQ:AAPL", "1", time.Unix(day, 0), time.Unix(day+60, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Close != 2 || got[1].Close != 3 {
+		t.Fatalf("bars = %+v, want only the two closed bars", got)
+	}
+}
+
+func TestHistoryBarsHandler(t *testing.T) {
+	rec := NewRecorder(t.TempDir(), nil, nil)
+	defer rec.Stop()
+	base := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC).Unix()
+	err := rec.WriteBars("BINANCE:BTCUSDT", "1", []HistoryBar{
+		{Time: base, Close: 1},
+		{Time: base + 60, Close: 2}, // next day's partition
+		{Time: base + 60, Close: 3}, // rewritten bar wins
+		{Time: base + 120, Close: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/history/bars?symbol=BINANCE:BTCUSDT&resolution=1&from=2026-03-01&to="+jsonInt(base+60), nil)
+	rr := httptest.NewRecorder()
+	HistoryBarsHandler(rec)(rr, req)
+	var body struct {
+		Bars []HistoryBar `json:"bars"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%d %s: %v", rr.Code, rr.Body, err)
+	}
+	if len(body.Bars) != 2 || body.Bars[0].Close != 1 || body.Bars[1].Close != 3 {
+		t.Fatalf("bars = %+v", body.Bars)
+	}
+
+	rr = httptest.NewRecorder()
+	HistoryBarsHandler(rec)(rr, httptest.NewRequest("GET", "/api/v1/history/bars?symbol=X", nil))
+	if rr.Code != 400 {
+		t.Fatalf("missing resolution: status %d", rr.Code)
+	}
+}
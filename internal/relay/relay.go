@@ -3,45 +3,67 @@ package relay
 import (
 	"encoding/json"
 	"log/slog"
-	"strings"
 	"sync"
+	"sync/atomic"
 
 	"context"
 
 	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
 )
 
 type connectionInfo struct {
-	feedName     string
-	msgFilter    map[string]bool // nil means accept all
+	chartID string         // chart tab that opened the socket, if known
+	feeds   []*feedMatcher // every feed whose URL pattern matched
 }
 
 // Relay tracks browser WebSocket connections via CDP events and publishes
-// matching frames to an SSE Broker.
+// matching frames to an SSE Broker. Frames in both directions on matched
+// sockets feed the tvproto.SeriesRegistry, whether or not they are published.
 type Relay struct {
 	cfg    *RelayConfig
 	broker *Broker
+	series *tvproto.SeriesRegistry
 
 	mu          sync.Mutex
 	connections map[string]connectionInfo // requestID → info
+	loadWatches map[*loadWatch]struct{}
+	lastMsgID   atomic.Uint64
+
+	chartIDForSession func(sessionID string) string
 
 	unregisterFns []func()
 }
 
-// NewRelay creates a relay engine.
+// NewRelay creates a relay engine. Configs not produced by LoadConfig are
+// compiled here; invalid feeds are logged and ignored.
 func NewRelay(cfg *RelayConfig, broker *Broker) *Relay {
+	if len(cfg.matchers) != len(cfg.Feeds) {
+		if err := cfg.compile(); err != nil {
+			slog.Error("relay: invalid feed config", "error", err)
+		}
+	}
 	return &Relay{
 		cfg:         cfg,
 		broker:      broker,
+		series:      tvproto.NewSeriesRegistry(),
 		connections: make(map[string]connectionInfo),
+		loadWatches: make(map[*loadWatch]struct{}),
 	}
 }
 
+// Series returns the registry mapping chart-session series IDs to symbols
+// and resolutions.
+func (r *Relay) Series() *tvproto.SeriesRegistry {
+	return r.series
+}
+
 // Start enables the Network domain and registers CDP event handlers.
 func (r *Relay) Start(ctx context.Context, client *cdpcontrol.Client) error {
 	if err := client.EnableNetworkDomain(ctx); err != nil {
 		return err
 	}
+	r.chartIDForSession = client.ChartIDForSession
 
 	methods := []struct {
 		name string
@@ -49,6 +71,7 @@ func (r *Relay) Start(ctx context.Context, client *cdpcontrol.Client) error {
 	}{
 		{"Network.webSocketCreated", r.onWebSocketCreated},
 		{"Network.webSocketFrameReceived", r.onWebSocketFrameReceived},
+		{"Network.webSocketFrameSent", r.onWebSocketFrameSent},
 		{"Network.webSocketClosed", r.onWebSocketClosed},
 	}
 
@@ -74,7 +97,7 @@ func (r *Relay) Stop() {
 	slog.Info("relay stopped")
 }
 
-func (r *Relay) onWebSocketCreated(_ string, params json.RawMessage) {
+func (r *Relay) onWebSocketCreated(sessionID string, params json.RawMessage) {
 	var evt struct {
 		RequestID string `json:"requestId"`
 		URL       string `json:"url"`
@@ -83,25 +106,37 @@ func (r *Relay) onWebSocketCreated(_ string, params json.RawMessage) {
 		return
 	}
 
-	for _, feed := range r.cfg.Feeds {
-		if strings.Contains(evt.URL, feed.URLPattern) {
-			info := connectionInfo{feedName: feed.Name}
-			if len(feed.MessageTypes) > 0 {
-				info.msgFilter = make(map[string]bool, len(feed.MessageTypes))
-				for _, mt := range feed.MessageTypes {
-					info.msgFilter[mt] = true
-				}
-			}
-			r.mu.Lock()
-			r.connections[evt.RequestID] = info
-			r.mu.Unlock()
-			slog.Debug("relay: ws matched", "feed", feed.Name, "url", evt.URL, "request_id", evt.RequestID)
-			return
+	var info connectionInfo
+	for _, m := range r.cfg.matchers {
+		if m.matchURL(evt.URL) {
+			info.feeds = append(info.feeds, m)
 		}
 	}
+	if len(info.feeds) == 0 {
+		return
+	}
+	if r.chartIDForSession != nil {
+		info.chartID = r.chartIDForSession(sessionID)
+	}
+	r.mu.Lock()
+	r.connections[evt.RequestID] = info
+	r.mu.Unlock()
+	for _, m := range info.feeds {
+		slog.Debug("relay: ws matched", "feed", m.name, "url", evt.URL, "request_id", evt.RequestID, "chart_id", info.chartID)
+	}
 }
 
 func (r *Relay) onWebSocketFrameReceived(_ string, params json.RawMessage) {
+	r.onFrame(DirectionReceived, params)
+}
+
+func (r *Relay) onWebSocketFrameSent(_ string, params json.RawMessage) {
+	r.onFrame(DirectionSent, params)
+}
+
+// onFrame handles a frame in either direction; both CDP events share the
+// {requestId, response: {payloadData}} shape.
+func (r *Relay) onFrame(direction string, params json.RawMessage) {
 	var evt struct {
 		RequestID string `json:"requestId"`
 		Response  struct {
@@ -124,17 +159,96 @@ func (r *Relay) onWebSocketFrameReceived(_ string, params json.RawMessage) {
 		return
 	}
 
-	// If no message type filter, publish everything.
-	if info.msgFilter == nil {
-		r.broker.Publish(Event{Feed: info.feedName, Payload: payload})
+	// A single frame may carry several ~m~ messages; each is published
+	// (and filtered) on its own, once per matching feed, with one MessageID.
+	// Heartbeats do not parse, so only feeds without type or payload
+	// filters relay them.
+	for _, raw := range tvproto.SplitFrames(payload) {
+		msg, ok := tvproto.ParseMessage(raw)
+		if ok {
+			r.series.Observe(info.chartID, msg)
+			if msg.Type == "series_completed" && direction == DirectionReceived {
+				r.seriesCompleted(info.chartID, msg)
+			}
+		}
+		var id uint64
+		for _, m := range info.feeds {
+			if !m.accept(direction, msg, ok) {
+				continue
+			}
+			if id == 0 {
+				id = r.lastMsgID.Add(1)
+			}
+			r.broker.Publish(Event{MessageID: id, Feed: m.name, Type: msg.Type, ChartID: info.chartID, Direction: direction, Payload: raw})
+		}
+	}
+}
+
+type loadWatch struct {
+	chartID string
+	ch      chan struct{}
+}
+
+// WatchSeriesCompleted returns a channel that receives each time the
+// chart's main series (any series until create_series is seen) completes
+// loading, and a function that ends the watch. Completions arriving while
+// one is still unread are merged.
+func (r *Relay) WatchSeriesCompleted(chartID string) (<-chan struct{}, func()) {
+	w := &loadWatch{chartID: chartID, ch: make(chan struct{}, 1)}
+	r.mu.Lock()
+	r.loadWatches[w] = struct{}{}
+	r.mu.Unlock()
+	return w.ch, func() {
+		r.mu.Lock()
+		delete(r.loadWatches, w)
+		r.mu.Unlock()
+	}
+}
+
+// seriesCompleted wakes the load watches of chartID for a series_completed
+// message ["cs_1", "sds_1", "s1"].
+func (r *Relay) seriesCompleted(chartID string, msg tvproto.Message) {
+	p := msg.ParamList()
+	if main, ok := r.series.MainSeries(chartID, msg.Session()); ok && main != tvproto.ParamString(p, 1) {
 		return
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for w := range r.loadWatches {
+		if w.chartID != chartID {
+			continue
+		}
+		select {
+		case w.ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// recentMessages remembers the last MessageIDs a broker consumer handled,
+// so a consumer of every feed (the bar, quote and alert trackers, webhooks)
+// handles a message relayed on several feeds once. The copies of a message
+// are published together, so a short window is enough. Not safe for
+// concurrent use.
+type recentMessages struct {
+	ids  [16]uint64
+	next int
+}
 
-	// Extract "m" field for filtering.
-	msgType := extractMessageType(payload)
-	if msgType != "" && info.msgFilter[msgType] {
-		r.broker.Publish(Event{Feed: info.feedName, Payload: payload})
+// seen reports whether the message was already handled and records it.
+// Events without a MessageID are never duplicates.
+func (m *recentMessages) seen(id uint64) bool {
+	if id == 0 {
+		return false
 	}
+	for _, v := range m.ids {
+		if v == id {
+			return true
+		}
+	}
+	m.ids[m.next] = id
+	m.next = (m.next + 1) % len(m.ids)
+	return false
 }
 
 func (r *Relay) onWebSocketClosed(_ string, params json.RawMessage) {
@@ -145,36 +259,19 @@ func (r *Relay) onWebSocketClosed(_ string, params json.RawMessage) {
 		return
 	}
 	r.mu.Lock()
+	info, ok := r.connections[evt.RequestID]
 	delete(r.connections, evt.RequestID)
-	r.mu.Unlock()
-}
-
-// extractMessageType extracts the "m" field from a JSON payload.
-// It handles both plain JSON and socket.io framed messages (~m~NNN~m~...).
-func extractMessageType(payload string) string {
-	data := payload
-
-	// Strip socket.io frame prefix: ~m~NNN~m~
-	if strings.HasPrefix(data, "~m~") {
-		// Find second ~m~ marker
-		idx := strings.Index(data[3:], "~m~")
-		if idx >= 0 {
-			data = data[3+idx+3:]
+	lastForChart := ok
+	for _, other := range r.connections {
+		if other.chartID == info.chartID {
+			lastForChart = false
+			break
 		}
 	}
-
-	// Quick check: must look like JSON object
-	data = strings.TrimSpace(data)
-	if len(data) == 0 || data[0] != '{' {
-		return ""
-	}
-
-	// Lightweight extraction: unmarshal just the "m" field.
-	var obj struct {
-		M string `json:"m"`
-	}
-	if err := json.Unmarshal([]byte(data), &obj); err != nil {
-		return ""
+	r.mu.Unlock()
+	// Series IDs are per socket; once the chart has no socket left, its
+	// mappings can only go stale.
+	if lastForChart {
+		r.series.Forget(info.chartID)
 	}
-	return obj.M
 }
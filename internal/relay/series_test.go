@@ -0,0 +1,146 @@
+package relay
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+)
+
+// encodeFrame wraps a message in TradingView's ~m~len~m~ framing.
+func encodeFrame(msg string) string {
+	return "~m~" + strconv.Itoa(len(msg)) + "~m~" + msg
+}
+
+func mustParse(t *testing.T, raw string) tvproto.Message {
+	t.Helper()
+	msg, ok := tvproto.ParseMessage(raw)
+	if !ok {
+		t.Fatalf("parse %s", raw)
+	}
+	return msg
+}
+
+func TestRelayCapturesConfiguredDirections(t *testing.T) {
+	cfg := &RelayConfig{Feeds: []FeedConfig{
+		{Name: "chart_data", URLPattern: "socket.io/websocket", Directions: []string{DirectionReceived}},
+	}}
+	b := NewBroker()
+	rl := NewRelay(cfg, b)
+	rl.onWebSocketCreated("", json.RawMessage(`{"requestId":"1","url":"wss://data.tradingview.com/socket.io/websocket"}`))
+
+	frame := func(raw string) json.RawMessage {
+		data, _ := json.Marshal(map[string]any{"requestId": "1", "response": map[string]string{"payloadData": encodeFrame(raw)}})
+		return data
+	}
+	rl.onWebSocketFrameSent("", frame(`{"m":"resolve_symbol","p":["cs_1","sds_sym_1","BINANCE:BTCUSDT"]}`))
+	rl.onWebSocketFrameSent("", frame(`{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","1",300,""]}`))
+	rl.onWebSocketFrameReceived("", frame(`{"m":"du","p":["cs_1",{}]}`))
+
+	got := b.Since(0, nil)
+	if len(got) != 1 || got[0].Type != "du" || got[0].Direction != DirectionReceived {
+		t.Fatalf("published = %+v, want only the received du", got)
+	}
+	if s, ok := rl.Series().Lookup("", "cs_1", "sds_1"); !ok || s.Symbol != "BINANCE:BTCUSDT" {
+		t.Fatalf("sent frames not observed: %+v, %v", s, ok)
+	}
+
+	// With both directions enabled, sent frames are published too.
+	cfg.Feeds[0].Directions = []string{DirectionReceived, DirectionSent}
+	if err := cfg.compile(); err != nil {
+		t.Fatal(err)
+	}
+	rl.onWebSocketCreated("", json.RawMessage(`{"requestId":"1","url":"wss://data.tradingview.com/socket.io/websocket"}`))
+	rl.onWebSocketFrameSent("", frame(`{"m":"quote_add_symbols","p":["qs_1","NASDAQ:AAPL"]}`))
+	if last := b.Since(1, nil); len(last) != 1 || last[0].Direction != DirectionSent {
+		t.Fatalf("sent frame not published: %+v", last)
+	}
+}
+
+func TestRelayForgetsSeriesOnClose(t *testing.T) {
+	cfg := &RelayConfig{Feeds: []FeedConfig{{Name: "chart_data", URLPattern: "socket.io/websocket"}}}
+	rl := NewRelay(cfg, NewBroker())
+	rl.chartIDForSession = func(string) string { return "abc" }
+	for _, id := range []string{"1", "2"} {
+		rl.onWebSocketCreated("S", json.RawMessage(`{"requestId":"`+id+`","url":"wss://data.tradingview.com/socket.io/websocket"}`))
+	}
+	data, _ := json.Marshal(map[string]any{"requestId": "1", "response": map[string]string{
+		"payloadData": encodeFrame(`{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","1",300,""]}`)}})
+	rl.onWebSocketFrameSent("S", data)
+
+	// Another socket of the same chart is still open.
+	rl.onWebSocketClosed("S", json.RawMessage(`{"requestId":"1"}`))
+	if len(rl.Series().List()) != 1 {
+		t.Fatalf("series dropped while chart still has a socket: %+v", rl.Series().List())
+	}
+	rl.onWebSocketClosed("S", json.RawMessage(`{"requestId":"2"}`))
+	if got := rl.Series().List(); len(got) != 0 {
+		t.Fatalf("series kept after the chart's sockets closed: %+v", got)
+	}
+	if _, ok := rl.Series().MainSeries("abc", "cs_1"); ok {
+		t.Fatal("main series kept after close")
+	}
+}
+
+func TestRelayWatchSeriesCompleted(t *testing.T) {
+	cfg := &RelayConfig{Feeds: []FeedConfig{{Name: "chart_data", URLPattern: "socket.io/websocket", MessageTypes: []string{"du"}}}}
+	rl := NewRelay(cfg, NewBroker())
+	rl.chartIDForSession = func(string) string { return "abc" }
+	rl.onWebSocketCreated("S", json.RawMessage(`{"requestId":"1","url":"wss://data.tradingview.com/socket.io/websocket"}`))
+	frame := func(sent bool, msgs ...string) {
+		data, _ := json.Marshal(map[string]any{"requestId": "1", "response": map[string]string{"payloadData": tvproto.JoinFrames(msgs...)}})
+		if sent {
+			rl.onWebSocketFrameSent("S", data)
+		} else {
+			rl.onWebSocketFrameReceived("S", data)
+		}
+	}
+	frame(true, `{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","1",300,""]}`,
+		`{"m":"create_series","p":["cs_1","sds_2","s1","sds_sym_2","1",300,""]}`)
+
+	completed, stop := rl.WatchSeriesCompleted("abc")
+	other, stopOther := rl.WatchSeriesCompleted("xyz")
+	defer stopOther()
+	// The compare series and other charts do not count; feed filters do not
+	// apply.
+	frame(false, `{"m":"series_completed","p":["cs_1","sds_2","s1"]}`)
+	select {
+	case <-completed:
+		t.Fatal("completion of a non-main series signaled")
+	default:
+	}
+	frame(false, `{"m":"series_loading","p":["cs_1","sds_1","s1"]}`, `{"m":"series_completed","p":["cs_1","sds_1","s1"]}`)
+	select {
+	case <-completed:
+	default:
+		t.Fatal("main series completion not signaled")
+	}
+	select {
+	case <-other:
+		t.Fatal("another chart's watch signaled")
+	default:
+	}
+
+	stop()
+	frame(false, `{"m":"series_completed","p":["cs_1","sds_1","s1"]}`)
+	select {
+	case <-completed:
+		t.Fatal("signaled after stop")
+	default:
+	}
+}
+
+func TestBarTrackerLabelsSymbol(t *testing.T) {
+	reg := tvproto.NewSeriesRegistry()
+	reg.Observe("abc", mustParse(t, `{"m":"resolve_symbol","p":["cs_1","sds_sym_1","NASDAQ:AAPL"]}`))
+	reg.Observe("abc", mustParse(t, `{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","5",300,""]}`))
+	tr := NewBarTracker(NewBroker(), reg)
+	_, ch := tr.out.Subscribe()
+
+	tr.handle(duEvent("abc", "sds_1", `[{"i":1,"v":[60,1,2,0.5,1.5,10]}]`, 120))
+	got := drainBars(t, ch)
+	if len(got) != 1 || got[0].Symbol != "NASDAQ:AAPL" || got[0].Resolution != "5" {
+		t.Fatalf("bars = %+v", got)
+	}
+}
@@ -0,0 +1,379 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultWebhookRetries   = 5
+	defaultWebhookBackoff   = time.Second
+	defaultWebhookTimeout   = 10 * time.Second
+	defaultWebhookQueueSize = 256
+	maxWebhookBackoff       = time.Minute
+
+	// SignatureHeader carries "sha256=<hex HMAC of the body>" when the
+	// webhook has a secret.
+	SignatureHeader = "X-Relay-Signature"
+)
+
+// WebhookPayload is the JSON body POSTed for each event. Payload is the
+// relayed message (or its decoded form when the webhook sets decode: true).
+type WebhookPayload struct {
+	DeliveryID string          `json:"delivery_id"`
+	Webhook    string          `json:"webhook"`
+	Feed       string          `json:"feed"`
+	Type       string          `json:"type,omitempty"`
+	ChartID    string          `json:"chart_id,omitempty"`
+	Direction  string          `json:"direction,omitempty"`
+	Time       time.Time       `json:"time"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// WebhookDispatcher POSTs matching relay events to configured webhooks.
+// Each destination URL has its own bounded queue and worker, so a slow or
+// failing endpoint never delays the others. Deliveries are retried with
+// exponential backoff, or after the server's Retry-After; events that
+// exhaust their retries, overflow a queue or are still pending at Stop are
+// appended to the dead-letter file.
+type WebhookDispatcher struct {
+	source *Broker
+	dests  []*webhookDest
+	dead   *deadLetter
+	nextID atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type webhookDest struct {
+	cfg    WebhookConfig
+	url    string
+	secret []byte
+	feeds  map[string]bool
+	types  map[string]bool
+	client *http.Client
+	queue  chan webhookJob
+	sent   recentMessages // dispatcher only
+}
+
+type webhookJob struct {
+	id   string
+	body []byte
+	typ  string
+	feed string
+}
+
+// NewWebhookDispatcher creates a dispatcher for the given webhooks. An empty
+// deadLetterPath disables the dead-letter file.
+func NewWebhookDispatcher(webhooks []WebhookConfig, deadLetterPath string, source *Broker) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &WebhookDispatcher{source: source, ctx: ctx, cancel: cancel}
+	if deadLetterPath != "" {
+		d.dead = &deadLetter{path: deadLetterPath}
+	}
+	for _, w := range webhooks {
+		w = webhookDefaults(w)
+		for _, u := range w.URLs {
+			d.dests = append(d.dests, &webhookDest{
+				cfg:    w,
+				url:    u,
+				secret: []byte(os.ExpandEnv(w.Secret)),
+				feeds:  toSet(w.Feeds),
+				types:  toSet(w.MessageTypes),
+				client: &http.Client{Timeout: w.Timeout},
+				queue:  make(chan webhookJob, w.QueueSize),
+			})
+		}
+	}
+	return d
+}
+
+func webhookDefaults(w WebhookConfig) WebhookConfig {
+	if w.MaxRetries <= 0 {
+		w.MaxRetries = defaultWebhookRetries
+	}
+	if w.Backoff <= 0 {
+		w.Backoff = defaultWebhookBackoff
+	}
+	if w.Timeout <= 0 {
+		w.Timeout = defaultWebhookTimeout
+	}
+	if w.QueueSize <= 0 {
+		w.QueueSize = defaultWebhookQueueSize
+	}
+	return w
+}
+
+func toSet(items []string) map[string]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(items))
+	for _, it := range items {
+		m[it] = true
+	}
+	return m
+}
+
+// Start subscribes to the source broker and starts one worker per destination.
+func (d *WebhookDispatcher) Start() {
+	for _, dest := range d.dests {
+		d.wg.Add(1)
+		go d.runDest(dest)
+	}
+
+	sub := d.source.SubscribeWith(SubscribeOptions{Label: "webhooks"})
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer d.source.Unsubscribe(sub.ID)
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case evt, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				d.dispatch(evt)
+			}
+		}
+	}()
+	slog.Info("relay webhooks started", "destinations", len(d.dests))
+}
+
+// Stop cancels in-flight deliveries and waits for all workers to exit.
+// Events that were not yet delivered, in flight or queued, are appended to
+// the dead-letter file with the error "shutdown".
+func (d *WebhookDispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+	for _, dest := range d.dests {
+		for {
+			select {
+			case job := <-dest.queue:
+				d.deadLetter(dest, job, 0, "shutdown")
+				continue
+			default:
+			}
+			break
+		}
+	}
+}
+
+func (d *WebhookDispatcher) dispatch(evt Event) {
+	now := time.Now().UTC()
+	for _, dest := range d.dests {
+		if dest.feeds != nil && !dest.feeds[evt.Feed] {
+			continue
+		}
+		if dest.types != nil && !dest.types[evt.Type] {
+			continue
+		}
+		if dest.sent.seen(evt.MessageID) {
+			continue
+		}
+		job, err := d.buildJob(dest, evt, now)
+		if err != nil {
+			slog.Debug("relay webhook: build body failed", "webhook", dest.cfg.Name, "error", err)
+			continue
+		}
+		select {
+		case dest.queue <- job:
+		default:
+			slog.Warn("relay webhook: queue full", "webhook", dest.cfg.Name, "url", dest.url)
+			d.deadLetter(dest, job, 0, "queue full")
+		}
+	}
+}
+
+func (d *WebhookDispatcher) buildJob(dest *webhookDest, evt Event, now time.Time) (webhookJob, error) {
+	payload := evt.Payload
+	if dest.cfg.Decode {
+		payload = decodeEventData(evt)
+	}
+	raw := json.RawMessage(payload)
+	if !json.Valid(raw) {
+		// Non-JSON payloads are sent as a JSON string.
+		quoted, err := json.Marshal(payload)
+		if err != nil {
+			return webhookJob{}, err
+		}
+		raw = quoted
+	}
+	id := fmt.Sprintf("%d-%d", now.UnixNano(), d.nextID.Add(1))
+	body, err := json.Marshal(WebhookPayload{
+		DeliveryID: id,
+		Webhook:    dest.cfg.Name,
+		Feed:       evt.Feed,
+		Type:       evt.Type,
+		ChartID:    evt.ChartID,
+		Direction:  evt.Direction,
+		Time:       now,
+		Payload:    raw,
+	})
+	if err != nil {
+		return webhookJob{}, err
+	}
+	return webhookJob{id: id, body: body, typ: evt.Type, feed: evt.Feed}, nil
+}
+
+func (d *WebhookDispatcher) runDest(dest *webhookDest) {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case job := <-dest.queue:
+			d.deliver(dest, job)
+		}
+	}
+}
+
+// deliver POSTs one job, retrying network errors, 429 and 5xx responses.
+// A Retry-After header replaces the backoff for the next attempt, capped at
+// maxWebhookBackoff.
+func (d *WebhookDispatcher) deliver(dest *webhookDest, job webhookJob) {
+	backoff := dest.cfg.Backoff
+	var wait time.Duration
+	var lastErr error
+	attempts := 0
+	for attempts <= dest.cfg.MaxRetries {
+		if attempts > 0 {
+			select {
+			case <-d.ctx.Done():
+				d.deadLetter(dest, job, attempts, "shutdown")
+				return
+			case <-time.After(wait):
+			}
+		}
+		attempts++
+		retryAfter, retry, err := d.post(dest, job)
+		if err == nil {
+			return
+		}
+		lastErr = err
+		if d.ctx.Err() != nil {
+			d.deadLetter(dest, job, attempts, "shutdown")
+			return
+		}
+		if !retry {
+			break
+		}
+		wait = backoff
+		if retryAfter > 0 {
+			wait = min(retryAfter, maxWebhookBackoff)
+		}
+		backoff = min(backoff*2, maxWebhookBackoff)
+		slog.Debug("relay webhook: delivery failed", "webhook", dest.cfg.Name, "url", dest.url, "attempt", attempts, "error", err)
+	}
+	slog.Warn("relay webhook: giving up", "webhook", dest.cfg.Name, "url", dest.url, "attempts", attempts, "error", lastErr)
+	d.deadLetter(dest, job, attempts, lastErr.Error())
+}
+
+func (d *WebhookDispatcher) post(dest *webhookDest, job webhookJob) (retryAfter time.Duration, retry bool, err error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, dest.url, bytes.NewReader(job.body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Relay-Delivery", job.id)
+	req.Header.Set("X-Relay-Feed", job.feed)
+	req.Header.Set("X-Relay-Event", job.typ)
+	if len(dest.secret) > 0 {
+		req.Header.Set(SignatureHeader, SignWebhookBody(dest.secret, job.body))
+	}
+	resp, err := dest.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// parseRetryAfter returns the delay a Retry-After header asks for, given in
+// seconds or as an HTTP date; zero when absent, invalid or already past.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// SignWebhookBody returns the X-Relay-Signature value for body.
+func SignWebhookBody(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *WebhookDispatcher) deadLetter(dest *webhookDest, job webhookJob, attempts int, reason string) {
+	if d.dead == nil {
+		return
+	}
+	rec := struct {
+		Time     time.Time       `json:"time"`
+		Webhook  string          `json:"webhook"`
+		URL      string          `json:"url"`
+		Attempts int             `json:"attempts"`
+		Error    string          `json:"error"`
+		Body     json.RawMessage `json:"body"`
+	}{time.Now().UTC(), dest.cfg.Name, dest.url, attempts, reason, job.body}
+	if err := d.dead.append(rec); err != nil {
+		slog.Error("relay webhook: dead-letter write failed", "path", d.dead.path, "error", err)
+	}
+}
+
+// deadLetter appends JSON records to a file, one per line.
+type deadLetter struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (dl *deadLetter) append(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(dl.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dl.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
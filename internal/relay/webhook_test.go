@@ -0,0 +1,209 @@
+package relay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookRetriesAndSigns(t *testing.T) {
+	var calls atomic.Int32
+	var okBody atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if got, want := r.Header.Get(SignatureHeader), SignWebhookBody([]byte("s3cret"), body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		okBody.Store(body)
+	}))
+	defer srv.Close()
+
+	src := NewBroker()
+	d := NewWebhookDispatcher([]WebhookConfig{{
+		Name:         "fires",
+		URLs:         []string{srv.URL},
+		Feeds:        []string{"private_feed"},
+		MessageTypes: []string{"alert_fired"},
+		Secret:       "s3cret",
+		Backoff:      time.Millisecond,
+	}}, "", src)
+	d.Start()
+	defer d.Stop()
+
+	src.Publish(Event{Feed: "chart_data", Type: "du", Payload: `{"m":"du"}`})
+	src.Publish(Event{Feed: "private_feed", Type: "alert_fired", Payload: `{"m":"alert_fired","p":{"alert_id":1}}`})
+
+	waitFor(t, func() bool { return okBody.Load() != nil })
+	var got WebhookPayload
+	if err := json.Unmarshal(okBody.Load().([]byte), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Webhook != "fires" || got.Type != "alert_fired" || !strings.Contains(string(got.Payload), `"alert_id":1`) {
+		t.Fatalf("payload = %+v", got)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3 (du filtered, 2 retries)", n)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "dead", "webhooks.jsonl")
+	src := NewBroker()
+	d := NewWebhookDispatcher([]WebhookConfig{{Name: "x", URLs: []string{srv.URL}, Backoff: time.Millisecond}}, path, src)
+	d.Start()
+	defer d.Stop()
+
+	src.Publish(Event{Feed: "public", Payload: "not json"})
+
+	var data []byte
+	waitFor(t, func() bool {
+		data, _ = os.ReadFile(path)
+		return len(data) > 0
+	})
+	var rec struct {
+		Attempts int            `json:"attempts"`
+		Error    string         `json:"error"`
+		Body     WebhookPayload `json:"body"`
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("dead letter %q: %v", data, err)
+	}
+	// 4xx is not retried.
+	if rec.Attempts != 1 || !strings.Contains(rec.Error, "400") || string(rec.Body.Payload) != `"not json"` {
+		t.Fatalf("dead letter record = %+v", rec)
+	}
+}
+
+func TestWebhookHonorsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var first, second atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			first.Store(time.Now().UnixNano())
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		second.Store(time.Now().UnixNano())
+	}))
+	defer srv.Close()
+
+	src := NewBroker()
+	d := NewWebhookDispatcher([]WebhookConfig{{Name: "x", URLs: []string{srv.URL}, Backoff: time.Millisecond}}, "", src)
+	d.Start()
+	defer d.Stop()
+
+	src.Publish(Event{Feed: "public", Payload: `{}`})
+	waitFor(t, func() bool { return second.Load() != 0 })
+	if gap := time.Duration(second.Load() - first.Load()); gap < 900*time.Millisecond {
+		t.Fatalf("retried after %v, want the 1s Retry-After", gap)
+	}
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for v, want := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"soon":                          0,
+		"Sun, 01 Mar 2026 10:00:30 GMT": 30 * time.Second,
+		"Sun, 01 Mar 2026 09:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(v, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestWebhookStopDeadLettersPending(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	src := NewBroker()
+	d := NewWebhookDispatcher([]WebhookConfig{{Name: "x", URLs: []string{srv.URL}}}, path, src)
+	d.Start()
+
+	for i := 0; i < 3; i++ {
+		src.Publish(Event{Feed: "public", Payload: `{}`})
+	}
+	<-started
+	// The first event is in flight; wait for the other two to be queued.
+	waitFor(t, func() bool { return len(d.dests[0].queue) == 2 })
+	d.Stop()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("dead letters = %d, want 3:\n%s", len(lines), data)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"error":"shutdown"`) {
+			t.Fatalf("dead letter %s", line)
+		}
+	}
+}
+
+func TestLoadConfigWebhooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relay.yaml")
+	yaml := `feeds:
+  - name: private_feed
+    url_pattern: private_feed
+webhook_dead_letter: dead.jsonl
+webhooks:
+  - name: fires
+    urls: ["https://example.com/hook"]
+    backoff: 250ms
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Webhooks) != 1 || cfg.Webhooks[0].Backoff != 250*time.Millisecond || cfg.WebhookDeadLetter != "dead.jsonl" {
+		t.Fatalf("config = %+v", cfg)
+	}
+
+	bad := strings.Replace(yaml, "https://example.com/hook", "example.com/hook", 1)
+	if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for URL without scheme")
+	}
+}
@@ -0,0 +1,386 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// Operation executes a controller call requested over the relay WebSocket.
+// params is the raw "params" object of the request.
+type Operation func(ctx context.Context, params json.RawMessage) (any, error)
+
+// WSRequest is a client message on the relay WebSocket.
+//
+//	{"id":"1","action":"subscribe","feeds":["chart_data"],"types":["du"],"symbols":["NASDAQ:AAPL"]}
+//	{"id":"2","action":"unsubscribe","types":["du"]}
+//	{"id":"3","action":"call","op":"set_symbol","params":{"chart_id":"abc","symbol":"NASDAQ:MSFT"}}
+//	{"id":"4","action":"ping"}
+type WSRequest struct {
+	ID      string          `json:"id,omitempty"`
+	Action  string          `json:"action"`
+	Feeds   []string        `json:"feeds,omitempty"`
+	Types   []string        `json:"types,omitempty"`
+	Symbols []string        `json:"symbols,omitempty"`
+	Decode  *bool           `json:"decode,omitempty"`
+	Op      string          `json:"op,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// WSMessage is a server message on the relay WebSocket. Type is one of
+// event, subscribed, result, error, overflow or pong.
+type WSMessage struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	EventID   uint64          `json:"event_id,omitempty"`
+	Feed      string          `json:"feed,omitempty"`
+	EventType string          `json:"event_type,omitempty"`
+	ChartID   string          `json:"chart_id,omitempty"`
+	Symbol    string          `json:"symbol,omitempty"`
+	Direction string          `json:"direction,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Filter    *WSFilter       `json:"filter,omitempty"`
+	Result    any             `json:"result,omitempty"`
+	Error     *WSError        `json:"error,omitempty"`
+}
+
+// WSFilter is the connection's current subscription. Feeds must be
+// subscribed by name ("*" for all); empty Types or Symbols match everything.
+type WSFilter struct {
+	Feeds   []string `json:"feeds"`
+	Types   []string `json:"types"`
+	Symbols []string `json:"symbols"`
+	Decode  bool     `json:"decode"`
+}
+
+// WSError is the error body of a failed request. Code is the controller
+// error code (e.g. VALIDATION, CHART_NOT_FOUND) where available.
+type WSError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WebSocketHandler returns an http.HandlerFunc for the bidirectional relay
+// socket. Events from every source broker (the relay broker and the derived
+// bar, quote and alert streams) are forwarded according to the connection's
+// runtime subscription, and "call" requests run the named Operation.
+// series resolves the symbols of relayed du and timescale_update messages
+// for symbol filters; when nil, those messages never match one.
+//
+// Since calls drive the chart, browser connections are refused (403) unless
+// their Origin is the relay's own host or one of allowedOrigins, so a page
+// open in the same browser cannot hijack the socket. Requests without an
+// Origin header (non-browser clients) are accepted.
+func WebSocketHandler(sources []*Broker, series *tvproto.SeriesRegistry, ops map[string]Operation, allowedOrigins []string) http.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		allowed[strings.ToLower(strings.TrimRight(o, "/"))] = true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !originAllowed(r, allowed) {
+			slog.Warn("relay ws: origin rejected", "origin", r.Header.Get("Origin"), "remote", r.RemoteAddr)
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			slog.Debug("relay ws: upgrade failed", "error", err)
+			return
+		}
+		c := &wsConn{conn: conn, ops: ops, series: series, set: newWSFilterSet()}
+		c.serve(r.Context(), sources, r.URL.Path+" "+r.RemoteAddr)
+	}
+}
+
+// originAllowed reports whether a WebSocket request may be upgraded: it has
+// no Origin, its Origin's host is the request's Host, or the Origin is in
+// allowed ("*" allows any).
+func originAllowed(r *http.Request, allowed map[string]bool) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || allowed["*"] || allowed[strings.ToLower(origin)] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+type wsConn struct {
+	conn   net.Conn
+	ops    map[string]Operation
+	series *tvproto.SeriesRegistry
+
+	writeMu sync.Mutex
+
+	filterMu sync.RWMutex
+	set      wsFilterSet
+}
+
+type wsFilterSet struct {
+	feeds, types, symbols map[string]bool
+	decode                bool
+}
+
+func newWSFilterSet() wsFilterSet {
+	return wsFilterSet{feeds: map[string]bool{}, types: map[string]bool{}, symbols: map[string]bool{}}
+}
+
+func (c *wsConn) serve(reqCtx context.Context, sources []*Broker, label string) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(reqCtx))
+	defer cancel()
+	defer c.conn.Close()
+
+	var wg sync.WaitGroup
+	for _, b := range sources {
+		sub := b.SubscribeWith(SubscribeOptions{Label: label})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer b.Unsubscribe(sub.ID)
+			c.forward(ctx, sub)
+		}()
+	}
+
+	var calls sync.WaitGroup
+	for {
+		data, op, err := wsutil.ReadClientData(c.conn)
+		if err != nil {
+			break
+		}
+		if op != ws.OpText {
+			continue
+		}
+		var req WSRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.write(WSMessage{Type: "error", Error: &WSError{Code: cdpcontrol.CodeValidation, Message: "invalid JSON: " + err.Error()}})
+			continue
+		}
+		if req.Action == "call" {
+			// Calls may take seconds; run them concurrently with the reader.
+			calls.Add(1)
+			go func() {
+				defer calls.Done()
+				c.call(ctx, req)
+			}()
+			continue
+		}
+		c.handle(req)
+	}
+	cancel()
+	calls.Wait()
+	wg.Wait()
+}
+
+func (c *wsConn) handle(req WSRequest) {
+	switch req.Action {
+	case "subscribe", "unsubscribe":
+		c.filterMu.Lock()
+		if req.Action == "subscribe" {
+			addAll(c.set.feeds, req.Feeds)
+			addAll(c.set.types, req.Types)
+			addAll(c.set.symbols, req.Symbols)
+		} else if len(req.Feeds)+len(req.Types)+len(req.Symbols) == 0 {
+			c.set = newWSFilterSet()
+		} else {
+			removeAll(c.set.feeds, req.Feeds)
+			removeAll(c.set.types, req.Types)
+			removeAll(c.set.symbols, req.Symbols)
+		}
+		if req.Decode != nil {
+			c.set.decode = *req.Decode
+		}
+		f := WSFilter{Feeds: keys(c.set.feeds), Types: keys(c.set.types), Symbols: keys(c.set.symbols), Decode: c.set.decode}
+		c.filterMu.Unlock()
+		c.write(WSMessage{Type: "subscribed", ID: req.ID, Filter: &f})
+	case "ping":
+		c.write(WSMessage{Type: "pong", ID: req.ID})
+	default:
+		c.write(WSMessage{Type: "error", ID: req.ID, Error: &WSError{Code: cdpcontrol.CodeValidation, Message: "unknown action " + req.Action}})
+	}
+}
+
+func (c *wsConn) call(ctx context.Context, req WSRequest) {
+	fn, ok := c.ops[req.Op]
+	if !ok {
+		c.write(WSMessage{Type: "error", ID: req.ID, Error: &WSError{Code: cdpcontrol.CodeValidation, Message: "unknown op " + req.Op}})
+		return
+	}
+	params := req.Params
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	res, err := fn(ctx, params)
+	if err != nil {
+		c.write(WSMessage{Type: "error", ID: req.ID, Error: wsError(err)})
+		return
+	}
+	c.write(WSMessage{Type: "result", ID: req.ID, Result: res})
+}
+
+func wsError(err error) *WSError {
+	var coded *cdpcontrol.CodedError
+	if errors.As(err, &coded) {
+		return &WSError{Code: coded.Code, Message: coded.Message}
+	}
+	return &WSError{Code: "INTERNAL", Message: err.Error()}
+}
+
+func (c *wsConn) forward(ctx context.Context, sub *Subscription) {
+	var reported uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if d := sub.Dropped(); d > reported {
+				data, _ := json.Marshal(OverflowNotice{Dropped: d - reported, TotalDropped: d})
+				c.write(WSMessage{Type: "overflow", Feed: evt.Feed, Data: data})
+				reported = d
+			}
+			if msg, ok := c.match(evt); ok {
+				c.write(msg)
+			}
+		}
+	}
+}
+
+// match applies the connection's filter to evt and renders it.
+func (c *wsConn) match(evt Event) (WSMessage, bool) {
+	c.filterMu.RLock()
+	set := c.set
+	ok := (set.feeds["*"] || set.feeds[evt.Feed]) &&
+		(len(set.types) == 0 || set.types[evt.Type])
+	var symbol string
+	if ok && len(set.symbols) > 0 {
+		ok = false
+		for _, s := range eventSymbols(evt, c.series) {
+			if set.symbols[s] {
+				symbol, ok = s, true
+				break
+			}
+		}
+	}
+	c.filterMu.RUnlock()
+	if !ok {
+		return WSMessage{}, false
+	}
+
+	payload := evt.Payload
+	if set.decode {
+		payload = decodeEventData(evt)
+	}
+	data := json.RawMessage(payload)
+	if !json.Valid(data) {
+		data, _ = json.Marshal(payload)
+	}
+	if symbol == "" {
+		symbol = evt.Symbol
+	}
+	return WSMessage{
+		Type:      "event",
+		EventID:   evt.ID,
+		Feed:      evt.Feed,
+		EventType: evt.Type,
+		ChartID:   evt.ChartID,
+		Symbol:    symbol,
+		Direction: evt.Direction,
+		Data:      data,
+	}, true
+}
+
+// eventSymbols returns the symbols an event concerns: Event.Symbol for
+// derived streams, the "n" field of a relayed qsd message, or the symbols
+// series maps the series of a relayed du or timescale_update message to (a
+// single message can update the main series and compare symbols together).
+func eventSymbols(evt Event, series *tvproto.SeriesRegistry) []string {
+	if evt.Symbol != "" {
+		return []string{evt.Symbol}
+	}
+	switch evt.Type {
+	case "qsd":
+		msg, ok := tvproto.ParseMessage(evt.Payload)
+		if !ok {
+			return nil
+		}
+		v, err := DecodeMessage(msg)
+		if err != nil {
+			return nil
+		}
+		if qu, ok := v.(QuoteUpdate); ok && qu.Symbol != "" {
+			return []string{qu.Symbol}
+		}
+	case "du", "timescale_update":
+		if series == nil {
+			return nil
+		}
+		msg, ok := tvproto.ParseMessage(evt.Payload)
+		if !ok {
+			return nil
+		}
+		p := msg.ParamList()
+		if len(p) < 2 {
+			return nil
+		}
+		var ids map[string]json.RawMessage
+		if err := json.Unmarshal(p[1], &ids); err != nil {
+			return nil
+		}
+		var out []string
+		for id := range ids {
+			if info, ok := series.Lookup(evt.ChartID, msg.Session(), id); ok && info.Symbol != "" {
+				out = append(out, info.Symbol)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (c *wsConn) write(msg WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Debug("relay ws: marshal failed", "type", msg.Type, "error", err)
+		return
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := wsutil.WriteServerMessage(c.conn, ws.OpText, data); err != nil {
+		slog.Debug("relay ws: write failed", "error", err)
+		c.conn.Close()
+	}
+}
+
+func addAll(set map[string]bool, items []string) {
+	for _, it := range items {
+		set[it] = true
+	}
+}
+
+func removeAll(set map[string]bool, items []string) {
+	for _, it := range items {
+		delete(set, it)
+	}
+}
+
+func keys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,155 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/cdpcontrol"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+func dialWS(t *testing.T, url string) net.Conn {
+	t.Helper()
+	conn, _, _, err := ws.Dial(context.Background(), "ws"+strings.TrimPrefix(url, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func sendWS(t *testing.T, conn net.Conn, req string) {
+	t.Helper()
+	if err := wsutil.WriteClientText(conn, []byte(req)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readWS(t *testing.T, conn net.Conn) WSMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	data, err := wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg WSMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	return msg
+}
+
+func TestWebSocketSubscribeAndCall(t *testing.T) {
+	b := NewBroker()
+	ops := map[string]Operation{
+		"set_symbol": func(_ context.Context, params json.RawMessage) (any, error) {
+			var p struct{ Symbol string }
+			_ = json.Unmarshal(params, &p)
+			if p.Symbol == "" {
+				return nil, &cdpcontrol.CodedError{Code: cdpcontrol.CodeValidation, Message: "symbol is required"}
+			}
+			return map[string]string{"current_symbol": p.Symbol}, nil
+		},
+	}
+	srv := httptest.NewServer(WebSocketHandler([]*Broker{b}, nil, ops, nil))
+	defer srv.Close()
+	conn := dialWS(t, srv.URL)
+
+	sendWS(t, conn, `{"id":"1","action":"subscribe","feeds":["chart_data"],"symbols":["NASDAQ:AAPL"]}`)
+	if msg := readWS(t, conn); msg.Type != "subscribed" || msg.ID != "1" || len(msg.Filter.Symbols) != 1 {
+		t.Fatalf("subscribe reply = %+v", msg)
+	}
+
+	b.Publish(Event{Feed: "public", Type: "x", Payload: `{"m":"x"}`})
+	b.Publish(qsdEvent("NYSE:IBM", "ok", `{"lp":1}`))
+	b.Publish(qsdEvent("NASDAQ:AAPL", "ok", `{"lp":2}`))
+	msg := readWS(t, conn)
+	if msg.Type != "event" || msg.Symbol != "NASDAQ:AAPL" || msg.EventType != "qsd" {
+		t.Fatalf("event = %+v", msg)
+	}
+
+	sendWS(t, conn, `{"id":"2","action":"call","op":"set_symbol","params":{"symbol":"NASDAQ:MSFT"}}`)
+	if msg := readWS(t, conn); msg.Type != "result" || msg.ID != "2" {
+		t.Fatalf("call reply = %+v", msg)
+	}
+	sendWS(t, conn, `{"id":"3","action":"call","op":"set_symbol","params":{}}`)
+	if msg := readWS(t, conn); msg.Type != "error" || msg.Error.Code != cdpcontrol.CodeValidation {
+		t.Fatalf("error reply = %+v", msg)
+	}
+
+	sendWS(t, conn, `{"id":"4","action":"unsubscribe"}`)
+	if msg := readWS(t, conn); msg.Type != "subscribed" || len(msg.Filter.Feeds) != 0 {
+		t.Fatalf("unsubscribe reply = %+v", msg)
+	}
+	b.Publish(qsdEvent("NASDAQ:AAPL", "ok", `{"lp":3}`))
+	sendWS(t, conn, `{"id":"5","action":"ping"}`)
+	if msg := readWS(t, conn); msg.Type != "pong" {
+		t.Fatalf("expected pong after unsubscribe, got %+v", msg)
+	}
+}
+
+func TestWebSocketSymbolFilterResolvesSeries(t *testing.T) {
+	reg := tvproto.NewSeriesRegistry()
+	reg.Observe("abc", mustParse(t, `{"m":"resolve_symbol","p":["cs_1","sds_sym_1","NASDAQ:AAPL"]}`))
+	reg.Observe("abc", mustParse(t, `{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","1",300,""]}`))
+	reg.Observe("abc", mustParse(t, `{"m":"resolve_symbol","p":["cs_1","sds_sym_2","NYSE:IBM"]}`))
+	reg.Observe("abc", mustParse(t, `{"m":"create_series","p":["cs_1","sds_2","s1","sds_sym_2","1",300,""]}`))
+	b := NewBroker()
+	srv := httptest.NewServer(WebSocketHandler([]*Broker{b}, reg, nil, nil))
+	defer srv.Close()
+	conn := dialWS(t, srv.URL)
+
+	sendWS(t, conn, `{"id":"1","action":"subscribe","feeds":["chart_data"],"types":["du"],"symbols":["NASDAQ:AAPL"]}`)
+	if msg := readWS(t, conn); msg.Type != "subscribed" {
+		t.Fatalf("subscribe reply = %+v", msg)
+	}
+
+	b.Publish(duEvent("abc", "sds_2", `[{"i":1,"v":[60,9,9,9,9,9]}]`, 120))
+	b.Publish(duEvent("other", "sds_1", `[{"i":1,"v":[60,8,8,8,8,8]}]`, 120))
+	b.Publish(duEvent("abc", "sds_1", `[{"i":1,"v":[60,1,1,1,1,1]}]`, 120))
+	msg := readWS(t, conn)
+	if msg.Type != "event" || msg.EventType != "du" || msg.Symbol != "NASDAQ:AAPL" || msg.ChartID != "abc" {
+		t.Fatalf("event = %+v", msg)
+	}
+}
+
+func TestWebSocketRejectsForeignOrigin(t *testing.T) {
+	srv := httptest.NewServer(WebSocketHandler([]*Broker{NewBroker()}, nil, nil, []string{"https://dash.example.com/"}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for origin, ok := range map[string]bool{
+		"":                         true,
+		srv.URL:                    true,
+		"https://dash.example.com": true,
+		"https://evil.example":     false,
+		"null":                     false,
+	} {
+		d := ws.Dialer{}
+		if origin != "" {
+			d.Header = ws.HandshakeHeaderHTTP(http.Header{"Origin": {origin}})
+		}
+		conn, _, _, err := d.Dial(context.Background(), url)
+		if conn != nil {
+			conn.Close()
+		}
+		if got := err == nil; got != ok {
+			t.Errorf("origin %q: connected = %v (%v), want %v", origin, got, err, ok)
+		}
+		if !ok {
+			var status ws.StatusError
+			if !errors.As(err, &status) || int(status) != http.StatusForbidden {
+				t.Errorf("origin %q: error = %v, want 403", origin, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+type clientConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c clientConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+func recordedSession(t *testing.T) *Session {
+	t.Helper()
+	t0 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	url := "wss://data.tradingview.com/socket.io/websocket?from=chart%2F"
+	ev := func(ms int, event, payload string) *types.WebSocketCapture {
+		return &types.WebSocketCapture{Timestamp: t0.Add(time.Duration(ms) * time.Millisecond), RequestID: "9.1", TabID: "T1",
+			URL: url, EventType: event, Opcode: 1, PayloadData: payload}
+	}
+	spec := `"={\"symbol\":\"BINANCE:BTCUSDT\"}"`
+	b := NewBuilder()
+	for _, e := range []*types.WebSocketCapture{
+		{Timestamp: t0, RequestID: "9.1", URL: url, EventType: "created"},
+		ev(100, "frame_received", tvproto.JoinFrames(`{"session_id":"abc"}`)),
+		ev(1000, "frame_sent", tvproto.JoinFrames(
+			`{"m":"chart_create_session","p":["cs_rec",""]}`,
+			`{"m":"resolve_symbol","p":["cs_rec","sds_sym_1",`+spec+`]}`,
+			`{"m":"create_series","p":["cs_rec","sds_1","s1","sds_sym_1","60",300,""]}`)),
+		ev(1200, "frame_received", tvproto.JoinFrames(
+			`{"m":"symbol_resolved","p":["cs_rec","sds_sym_1",{"pro_name":"BINANCE:BTCUSDT"}]}`,
+			`{"m":"series_loading","p":["cs_rec","sds_1","s1"]}`,
+			`{"m":"du","p":["cs_rec",{"sds_1":{"s":[{"i":0,"v":[1,2,3,4,5,6]}],"t":"s1"}}]}`)),
+		ev(2000, "frame_sent", tvproto.JoinFrames(
+			`{"m":"quote_create_session","p":["qs_rec"]}`,
+			`{"m":"quote_add_symbols","p":["qs_rec","NASDAQ:AAPL","NASDAQ:MSFT"]}`)),
+		ev(2100, "frame_received", tvproto.JoinFrames(
+			`{"m":"qsd","p":["qs_rec",{"n":"NASDAQ:MSFT","s":"ok","v":{"lp":400}}]}`,
+			`{"m":"qsd","p":["qs_rec",{"n":"NASDAQ:AAPL","s":"ok","v":{"lp":200}}]}`)),
+		ev(3000, "frame_received", "~m~4~m~~h~1"),
+		{Timestamp: t0.Add(4 * time.Second), RequestID: "9.1", URL: url, EventType: "frame_received", Opcode: 1, PayloadData: `~m~900~m~{"m":"du"`, Truncated: true},
+	} {
+		b.AddWebSocket(e)
+	}
+	b.AddWebSocket(&types.WebSocketCapture{Timestamp: t0, RequestID: "9.2", URL: url, EventType: "created"}) // no frames
+
+	sessions := b.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %d", len(sessions))
+	}
+	return sessions[0]
+}
+
+func TestSessionTopics(t *testing.T) {
+	s := recordedSession(t)
+	want := []string{
+		"chart_create_session",
+		`quote_add_symbols|NASDAQ:AAPL`,
+		`quote_add_symbols|NASDAQ:MSFT`,
+		"quote_create_session",
+		`resolve_symbol|={"symbol":"BINANCE:BTCUSDT"}`,
+		"series|BINANCE:BTCUSDT|60",
+	}
+	if got := s.Topics(); !slices.Equal(got, want) {
+		t.Fatalf("topics = %q", got)
+	}
+	// hello, heartbeat, symbol_resolved, series_loading+du, two qsd
+	if s.Frames() != 6 || len(s.background) != 2 {
+		t.Fatalf("frames = %d, background = %d", s.Frames(), len(s.background))
+	}
+}
+
+func TestServerAnswersRequests(t *testing.T) {
+	srv := httptest.NewServer(NewServer([]*Session{recordedSession(t)}, 0))
+	defer srv.Close()
+	raw, br, _, err := ws.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/socket.io/websocket?from=chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	// The server speaks first, so the hello may already sit in br.
+	conn := clientConn{Conn: raw, r: raw}
+	if br != nil {
+		conn.r = io.MultiReader(br, raw)
+	}
+
+	spec := `"={\"symbol\":\"BINANCE:BTCUSDT\"}"`
+	req := tvproto.JoinFrames(
+		`{"m":"chart_create_session","p":["cs_live",""]}`,
+		`{"m":"resolve_symbol","p":["cs_live","sds_sym_2",`+spec+`]}`,
+		`{"m":"create_series","p":["cs_live","sds_9","s3","sds_sym_2","60",300,""]}`,
+		`{"m":"quote_add_symbols","p":["qs_live","NASDAQ:MSFT"]}`)
+	if err := wsutil.WriteClientText(conn, []byte(req)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for len(got) < 5 {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		data, err := wsutil.ReadServerText(conn)
+		if err != nil {
+			t.Fatalf("after %q: %v", got, err)
+		}
+		got = append(got, string(data))
+	}
+	slices.Sort(got)
+	want := []string{
+		tvproto.JoinFrames(`{"m":"qsd","p":["qs_live",{"n":"NASDAQ:MSFT","s":"ok","v":{"lp":400}}]}`),
+		tvproto.JoinFrames(`{"m":"series_loading","p":["cs_live","sds_9","s3"]}`,
+			`{"m":"du","p":["cs_live",{"sds_9":{"s":[{"i":0,"v":[1,2,3,4,5,6]}],"t":"s3"}}]}`),
+		tvproto.JoinFrames(`{"m":"symbol_resolved","p":["cs_live","sds_sym_2",{"pro_name":"BINANCE:BTCUSDT"}]}`),
+		tvproto.JoinFrames(`{"session_id":"abc"}`),
+		"~m~4~m~~h~1",
+	}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("frames =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// AAPL was not requested, so nothing more arrives.
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if data, err := wsutil.ReadServerText(conn); err == nil {
+		t.Fatalf("unexpected frame %s", data)
+	}
+}
@@ -0,0 +1,216 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// Server is an http.Handler that upgrades each request to a WebSocket and
+// replays one recorded session on it.
+//
+// The session is the one whose ID starts with the "session" query
+// parameter, else the next of those recorded on the same URL path (round
+// robin), else the next of all sessions. Background frames are sent on the
+// recorded timeline from the moment the client connects. Each client
+// request is matched to the next unplayed recording of the same topic,
+// whose responses are sent with the recorded session and series IDs
+// replaced by the client's, timed relative to the request.
+type Server struct {
+	sessions []*Session
+	speed    float64
+
+	mu   sync.Mutex
+	next map[string]int // candidate set -> round-robin position
+}
+
+// NewServer returns a Server for sessions. speed scales the recorded delays
+// (2 plays twice as fast); 0 sends everything without waiting.
+func NewServer(sessions []*Session, speed float64) *Server {
+	return &Server{sessions: sessions, speed: speed, next: make(map[string]int)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sess := s.pick(r)
+	if sess == nil {
+		http.Error(w, "no recorded session matches", http.StatusNotFound)
+		return
+	}
+	conn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		slog.Debug("replay: upgrade failed", "error", err)
+		return
+	}
+	slog.Info("Replaying session", "session", sess.ID, "url", sess.URL, "client", r.RemoteAddr)
+	c := &replayConn{
+		conn:  conn,
+		sess:  sess,
+		speed: s.speed,
+		reg:   tvproto.NewSeriesRegistry(),
+		used:  make(map[string]int),
+	}
+	c.serve(r.Context())
+}
+
+func (s *Server) pick(r *http.Request) *Session {
+	if id := strings.ToUpper(r.URL.Query().Get("session")); id != "" {
+		for _, sess := range s.sessions {
+			if strings.HasPrefix(strings.ToUpper(sess.ID), id) {
+				return sess
+			}
+		}
+		return nil
+	}
+	candidates, set := s.sessions, ""
+	var samePath []*Session
+	for _, sess := range s.sessions {
+		if urlPath(sess.URL) == r.URL.Path {
+			samePath = append(samePath, sess)
+		}
+	}
+	if len(samePath) > 0 {
+		candidates, set = samePath, r.URL.Path
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.next[set] % len(candidates)
+	s.next[set]++
+	return candidates[i]
+}
+
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+type replayConn struct {
+	conn  net.Conn
+	sess  *Session
+	speed float64
+	reg   *tvproto.SeriesRegistry
+	used  map[string]int // topic key -> recordings already played
+
+	writeMu sync.Mutex
+	wg      sync.WaitGroup
+}
+
+func (c *replayConn) serve(reqCtx context.Context) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(reqCtx))
+	defer c.conn.Close()
+
+	c.play(ctx, c.sess.Start, c.sess.background, nil)
+	for {
+		data, op, err := wsutil.ReadClientData(c.conn)
+		if err != nil {
+			break
+		}
+		if op != ws.OpText {
+			continue
+		}
+		for _, raw := range tvproto.SplitFrames(string(data)) {
+			if msg, ok := tvproto.ParseMessage(raw); ok {
+				c.request(ctx, msg)
+			}
+		}
+	}
+	cancel()
+	c.wg.Wait()
+}
+
+// request starts replaying the recorded responses to a client message.
+func (c *replayConn) request(ctx context.Context, msg tvproto.Message) {
+	c.reg.Observe("", msg)
+	for _, tp := range topicsOf(msg, c.reg) {
+		recorded := c.sess.topics[tp.key]
+		n := c.used[tp.key]
+		if n >= len(recorded) {
+			slog.Debug("replay: no recorded response", "session", c.sess.ID, "topic", tp.key)
+			continue
+		}
+		c.used[tp.key]++
+		r := recorded[n]
+		c.play(ctx, r.at, r.frames, idReplacer(r.trigger, msg))
+	}
+}
+
+// play sends frames in the background, each after its recorded delay from
+// start scaled by the speed, rewriting them with rep if non-nil.
+func (c *replayConn) play(ctx context.Context, start time.Time, frames []frame, rep *strings.Replacer) {
+	if len(frames) == 0 {
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		began := time.Now()
+		for _, f := range frames {
+			if c.speed > 0 {
+				due := began.Add(time.Duration(float64(f.at.Sub(start)) / c.speed))
+				if wait := time.Until(due); wait > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(wait):
+					}
+				}
+			}
+			msgs := f.msgs
+			if rep != nil {
+				msgs = make([]string, len(f.msgs))
+				for i, m := range f.msgs {
+					msgs[i] = rep.Replace(m)
+				}
+			}
+			if !c.write(tvproto.JoinFrames(msgs...)) {
+				return
+			}
+		}
+	}()
+}
+
+func (c *replayConn) write(payload string) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return wsutil.WriteServerMessage(c.conn, ws.OpText, []byte(payload)) == nil
+}
+
+// idReplacer maps the string params of a recorded request to those of the
+// live one (session, series, turn and symbol alias IDs). Quote requests
+// only map the session, since their symbols are matched by value.
+func idReplacer(recorded, live tvproto.Message) *strings.Replacer {
+	rp, lp := recorded.ParamList(), live.ParamList()
+	n := min(len(rp), len(lp))
+	if recorded.Type == "quote_add_symbols" || recorded.Type == "quote_fast_symbols" {
+		n = min(n, 1)
+	}
+	var pairs []string
+	for i := 0; i < n; i++ {
+		a, b := tvproto.ParamString(rp, i), tvproto.ParamString(lp, i)
+		if a == "" || b == "" || a == b {
+			continue
+		}
+		qa, _ := json.Marshal(a)
+		qb, _ := json.Marshal(b)
+		pairs = append(pairs, string(qa), string(qb))
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,265 @@
+// Package replay serves recorded chart-socket sessions back to clients as
+// a mock TradingView data server. Each recorded connection is split into
+// background frames (the hello message, heartbeats and anything not tied to
+// a request) and recorded responses keyed by the client request that
+// caused them, so a live client's create_series or quote_add_symbols is
+// answered with what the real server sent for the same symbol.
+package replay
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/tvproto"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+// frame is one recorded server frame, split into messages.
+type frame struct {
+	at   time.Time
+	msgs []string
+}
+
+// response is what the server sent on a recorded session after one client
+// request for a topic, until a later request on the same subject took over.
+type response struct {
+	trigger tvproto.Message
+	at      time.Time
+	frames  []frame
+}
+
+// Session is one recorded WebSocket connection.
+type Session struct {
+	ID    string    `json:"id"` // CDP request ID
+	URL   string    `json:"url"`
+	TabID string    `json:"tab_id"`
+	Start time.Time `json:"start"`
+
+	background []frame
+	topics     map[string][]*response // topic key -> recorded requests, in order
+}
+
+// Topics returns the request topics the session can answer, e.g.
+// "series|BINANCE:BTCUSDT|60" or "quote_add_symbols|NASDAQ:AAPL", sorted.
+func (s *Session) Topics() []string {
+	out := make([]string, 0, len(s.topics))
+	for k := range s.topics {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Frames returns the number of recorded server frames.
+func (s *Session) Frames() int {
+	n := len(s.background)
+	for _, rs := range s.topics {
+		for _, r := range rs {
+			n += len(r.frames)
+		}
+	}
+	return n
+}
+
+// Builder groups WebSocket capture records into sessions.
+type Builder struct {
+	conns map[string][]*types.WebSocketCapture
+	order []string
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{conns: make(map[string][]*types.WebSocketCapture)}
+}
+
+// AddHTTP ignores HTTP captures; it lets a Builder consume a mixed scan.
+func (b *Builder) AddHTTP(*types.HTTPCapture) {}
+
+// AddWebSocket adds a WebSocket event (created, frame_sent, frame_received
+// or closed).
+func (b *Builder) AddWebSocket(c *types.WebSocketCapture) {
+	if _, ok := b.conns[c.RequestID]; !ok {
+		b.order = append(b.order, c.RequestID)
+	}
+	b.conns[c.RequestID] = append(b.conns[c.RequestID], c)
+}
+
+// Sessions returns the connections that received at least one frame, in
+// the order they were first seen. Truncated and binary frames are skipped.
+func (b *Builder) Sessions() []*Session {
+	var out []*Session
+	for _, id := range b.order {
+		events := b.conns[id]
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+		s := buildSession(id, events)
+		if s.Frames() > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// trigger is a recorded client request and the topics it opened.
+type trigger struct {
+	session string
+	topics  []topic
+	resps   []*response
+}
+
+func buildSession(id string, events []*types.WebSocketCapture) *Session {
+	s := &Session{ID: id, topics: make(map[string][]*response)}
+	reg := tvproto.NewSeriesRegistry()
+	var triggers []*trigger
+	for _, e := range events {
+		if s.URL == "" {
+			s.URL, s.TabID = e.URL, e.TabID
+		}
+		if s.Start.IsZero() {
+			s.Start = e.Timestamp
+		}
+		if e.Truncated || (e.Opcode != 0 && e.Opcode != 1) {
+			continue
+		}
+		switch e.EventType {
+		case "frame_sent":
+			for _, raw := range tvproto.SplitFrames(e.PayloadData) {
+				msg, ok := tvproto.ParseMessage(raw)
+				if !ok {
+					continue
+				}
+				reg.Observe("", msg)
+				t := &trigger{session: msg.Session(), topics: topicsOf(msg, reg)}
+				for _, tp := range t.topics {
+					r := &response{trigger: msg, at: e.Timestamp}
+					s.topics[tp.key] = append(s.topics[tp.key], r)
+					t.resps = append(t.resps, r)
+				}
+				if len(t.topics) > 0 {
+					triggers = append(triggers, t)
+				}
+			}
+		case "frame_received":
+			// Messages of one recorded frame that go to the same place stay
+			// in one frame.
+			var last *[]frame
+			for _, raw := range tvproto.SplitFrames(e.PayloadData) {
+				dst := &s.background
+				if msg, ok := tvproto.ParseMessage(raw); ok {
+					reg.Observe("", msg)
+					if r := attribute(msg, triggers); r != nil {
+						dst = &r.frames
+					}
+				}
+				if dst == last {
+					f := &(*dst)[len(*dst)-1]
+					f.msgs = append(f.msgs, raw)
+				} else {
+					*dst = append(*dst, frame{at: e.Timestamp, msgs: []string{raw}})
+				}
+				last = dst
+			}
+		}
+	}
+	return s
+}
+
+// attribute returns the recorded response a server message belongs to: the
+// latest request in the same chart or quote session whose subject the
+// message names, else the latest request in that session. Messages outside
+// any requested session (the hello, heartbeats) return nil.
+func attribute(msg tvproto.Message, triggers []*trigger) *response {
+	session := msg.Session()
+	if session == "" {
+		return nil
+	}
+	subjects := subjectsOf(msg)
+	var fallback *response
+	for i := len(triggers) - 1; i >= 0; i-- {
+		t := triggers[i]
+		if t.session != session {
+			continue
+		}
+		for j, tp := range t.topics {
+			if tp.subject != "" && subjects[tp.subject] {
+				return t.resps[j]
+			}
+		}
+		if fallback == nil {
+			fallback = t.resps[0]
+		}
+	}
+	return fallback
+}
+
+// topic is one thing a client request asks for. key identifies it across
+// connections; subject is the ID server messages use to refer to it.
+type topic struct {
+	key     string
+	subject string
+}
+
+// topicsOf returns the topics of a client request. Series are keyed by
+// symbol and resolution rather than by the client-chosen series ID, and
+// each symbol of a quote request is its own topic.
+func topicsOf(msg tvproto.Message, reg *tvproto.SeriesRegistry) []topic {
+	p := msg.ParamList()
+	switch msg.Type {
+	case "":
+		return nil
+	case "create_series", "modify_series":
+		// [session, "sds_1", "s1", "sds_sym_1", "1", 300, ""]
+		id := tvproto.ParamString(p, 1)
+		info, _ := reg.Lookup("", msg.Session(), id)
+		sym := info.Symbol
+		if sym == "" {
+			sym = tvproto.ParamString(p, 3)
+		}
+		return []topic{{key: "series|" + sym + "|" + info.Resolution, subject: id}}
+	case "resolve_symbol":
+		// [session, "sds_sym_1", "={\"symbol\":\"BINANCE:BTCUSDT\",...}"]
+		return []topic{{key: "resolve_symbol|" + tvproto.ParamString(p, 2), subject: tvproto.ParamString(p, 1)}}
+	case "quote_add_symbols", "quote_fast_symbols":
+		// [session, "NASDAQ:AAPL", "BINANCE:BTCUSDT", ...]
+		var out []topic
+		for i := 1; i < len(p); i++ {
+			if sym := tvproto.ParamString(p, i); sym != "" {
+				out = append(out, topic{key: msg.Type + "|" + sym, subject: sym})
+			}
+		}
+		return out
+	}
+	if id := tvproto.ParamString(p, 1); id != "" {
+		return []topic{{key: msg.Type + "|" + id, subject: id}}
+	}
+	if msg.Session() != "" {
+		return []topic{{key: msg.Type}}
+	}
+	return nil
+}
+
+// subjectsOf returns the IDs a server message refers to: a string second
+// param ("sds_1", "sds_sym_1"), the "n" of a quote update, or the keys of
+// a data update object.
+func subjectsOf(msg tvproto.Message) map[string]bool {
+	p := msg.ParamList()
+	if len(p) < 2 {
+		return nil
+	}
+	if s := tvproto.ParamString(p, 1); s != "" {
+		return map[string]bool{s: true}
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(p[1], &obj) != nil {
+		return nil
+	}
+	var n string
+	if json.Unmarshal(obj["n"], &n) == nil && n != "" {
+		return map[string]bool{n: true}
+	}
+	out := make(map[string]bool, len(obj))
+	for k := range obj {
+		out[k] = true
+	}
+	return out
+}
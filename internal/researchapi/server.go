@@ -0,0 +1,299 @@
+// Package researchapi serves the researcher's local status and control
+// API: attached tabs, per-writer record counters, active WebSocket
+// connections and bytes on disk, plus pausing and resuming capture per tab
+// or per data type.
+package researchapi
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/capture"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Researcher is the running capture the API reports on.
+type Researcher interface {
+	Connected() bool
+	Tabs() []types.TabInfo
+	WriterStats() []storage.WriterStats
+	ActiveWebSockets() int
+	// WriteGap writes a capture_gap marker to the tab's stream of dataType
+	// (capture.DataHTTP or capture.DataWebSocket).
+	WriteGap(tab types.TabInfo, dataType string, gap types.CaptureGap)
+}
+
+// Tab is an attached tab.
+type Tab struct {
+	TargetID    string `json:"target_id"`
+	BrowserID   string `json:"browser_id"`
+	URL         string `json:"url"`
+	PathSegment string `json:"path_segment"`
+	Paused      bool   `json:"paused" doc:"Capture of this tab is paused (the tab itself, not a data type)."`
+}
+
+// Status summarises the researcher.
+type Status struct {
+	Connected            bool                  `json:"connected" doc:"False while reconnecting to a restarted browser."`
+	StartedAt            time.Time             `json:"started_at"`
+	Tabs                 []Tab                 `json:"tabs"`
+	WebSocketConnections int                   `json:"websocket_connections"`
+	Written              int64                 `json:"written" doc:"Records written by all JSONL writers."`
+	Dropped              int64                 `json:"dropped" doc:"Records dropped by all JSONL writers, e.g. on a full buffer."`
+	Writers              []storage.WriterStats `json:"writers"`
+	DiskBytes            int64                 `json:"disk_bytes" doc:"Size of the data directory."`
+	PausedTabs           []string              `json:"paused_tabs"`
+	PausedTypes          []string              `json:"paused_types"`
+}
+
+// DirUsage is the size of one top-level entry of the data directory.
+type DirUsage struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+type server struct {
+	r       Researcher
+	pauses  *capture.Pauses
+	dataDir string
+	started time.Time
+}
+
+// NewServer returns the API handler. pauses must be the set the captures
+// consult.
+func NewServer(r Researcher, pauses *capture.Pauses, dataDir, version string) http.Handler {
+	router := chi.NewMux()
+	router.Use(middleware.Recoverer)
+
+	api := humachi.New(router, huma.DefaultConfig("Researcher API", version))
+	s := &server{r: r, pauses: pauses, dataDir: dataDir, started: time.Now().UTC()}
+	s.register(api)
+	return router
+}
+
+func (s *server) register(api huma.API) {
+	huma.Register(api, huma.Operation{OperationID: "get-status", Method: http.MethodGet, Path: "/api/v1/status", Summary: "Researcher status", Tags: []string{"Status"}},
+		func(ctx context.Context, input *struct{}) (*struct{ Body Status }, error) {
+			st := Status{
+				Connected:            s.r.Connected(),
+				StartedAt:            s.started,
+				Tabs:                 s.tabs(),
+				WebSocketConnections: s.r.ActiveWebSockets(),
+				Writers:              s.r.WriterStats(),
+				PausedTabs:           s.pauses.Tabs(),
+				PausedTypes:          s.pauses.Types(),
+			}
+			for _, w := range st.Writers {
+				st.Written += w.Written
+				st.Dropped += w.Dropped
+			}
+			if st.Writers == nil {
+				st.Writers = []storage.WriterStats{}
+			}
+			for _, d := range diskUsage(s.dataDir) {
+				st.DiskBytes += d.Bytes
+			}
+			return &struct{ Body Status }{st}, nil
+		})
+
+	huma.Register(api, huma.Operation{OperationID: "list-tabs", Method: http.MethodGet, Path: "/api/v1/tabs", Summary: "List attached tabs", Tags: []string{"Tabs"}},
+		func(ctx context.Context, input *struct{}) (*struct {
+			Body struct {
+				Tabs []Tab `json:"tabs"`
+			}
+		}, error) {
+			out := &struct {
+				Body struct {
+					Tabs []Tab `json:"tabs"`
+				}
+			}{}
+			out.Body.Tabs = s.tabs()
+			return out, nil
+		})
+
+	huma.Register(api, huma.Operation{OperationID: "get-disk-usage", Method: http.MethodGet, Path: "/api/v1/disk", Summary: "Bytes on disk per day and blob store", Tags: []string{"Status"}},
+		func(ctx context.Context, input *struct{}) (*struct {
+			Body struct {
+				TotalBytes int64      `json:"total_bytes"`
+				Dirs       []DirUsage `json:"dirs"`
+			}
+		}, error) {
+			out := &struct {
+				Body struct {
+					TotalBytes int64      `json:"total_bytes"`
+					Dirs       []DirUsage `json:"dirs"`
+				}
+			}{}
+			out.Body.Dirs = diskUsage(s.dataDir)
+			for _, d := range out.Body.Dirs {
+				out.Body.TotalBytes += d.Bytes
+			}
+			return out, nil
+		})
+
+	type tabInput struct {
+		TabID string `path:"tab_id" doc:"Target ID or browser ID prefix, case-insensitive."`
+	}
+	for _, paused := range []bool{true, false} {
+		verb, summary := "resume", "Resume capturing a tab"
+		if paused {
+			verb, summary = "pause", "Pause capturing a tab"
+		}
+		huma.Register(api, huma.Operation{
+			OperationID: verb + "-tab",
+			Method:      http.MethodPost,
+			Path:        "/api/v1/tabs/{tab_id}/" + verb,
+			Summary:     summary,
+			Description: "While a tab is paused its HTTP, WebSocket and static resource records are dropped. " +
+				"Resuming writes a capture_gap marker with reason `paused` to the tab's streams.",
+			Tags: []string{"Tabs"},
+		}, func(ctx context.Context, input *tabInput) (*struct{ Body Tab }, error) {
+			tab, err := s.findTab(input.TabID)
+			if err != nil {
+				return nil, err
+			}
+			s.setPaused([]types.TabInfo{tab}, func() { s.pauses.SetTab(tab.TargetID, paused) })
+			return &struct{ Body Tab }{s.tab(tab)}, nil
+		})
+	}
+
+	type typeInput struct {
+		DataType string `path:"data_type" enum:"http,websocket,static"`
+	}
+	type typeOutput struct {
+		Body struct {
+			DataType string `json:"data_type"`
+			Paused   bool   `json:"paused"`
+		}
+	}
+	for _, paused := range []bool{true, false} {
+		verb, summary := "resume", "Resume capturing a data type"
+		if paused {
+			verb, summary = "pause", "Pause capturing a data type"
+		}
+		huma.Register(api, huma.Operation{
+			OperationID: verb + "-data-type",
+			Method:      http.MethodPost,
+			Path:        "/api/v1/capture/{data_type}/" + verb,
+			Summary:     summary,
+			Description: "Pauses or resumes one data type on every tab. Resuming http or websocket writes a capture_gap " +
+				"marker with reason `paused` to each attached tab's stream of that type.",
+			Tags: []string{"Capture"},
+		}, func(ctx context.Context, input *typeInput) (*typeOutput, error) {
+			s.setPaused(s.r.Tabs(), func() { s.pauses.SetType(input.DataType, paused) })
+			out := &typeOutput{}
+			out.Body.DataType = input.DataType
+			out.Body.Paused = slices.Contains(s.pauses.Types(), input.DataType)
+			return out, nil
+		})
+	}
+}
+
+// setPaused runs change and writes a capture_gap marker to each stream of
+// tabs that it resumed.
+func (s *server) setPaused(tabs []types.TabInfo, change func()) {
+	type stream struct {
+		tab      types.TabInfo
+		dataType string
+		since    time.Time
+	}
+	var before []stream
+	for _, tab := range tabs {
+		for _, dt := range []string{capture.DataHTTP, capture.DataWebSocket} {
+			if since, ok := s.pauses.Since(tab.TargetID, dt); ok {
+				before = append(before, stream{tab, dt, since})
+			}
+		}
+	}
+	change()
+	now := time.Now().UTC()
+	for _, st := range before {
+		if s.pauses.Paused(st.tab.TargetID, st.dataType) {
+			continue
+		}
+		s.r.WriteGap(st.tab, st.dataType, types.CaptureGap{
+			Timestamp: now,
+			EventType: types.EventCaptureGap,
+			TabID:     st.tab.TargetID,
+			URL:       st.tab.URL,
+			GapStart:  st.since,
+			GapEnd:    now,
+			Reason:    types.GapReasonPaused,
+		})
+	}
+}
+
+func (s *server) tabs() []Tab {
+	infos := s.r.Tabs()
+	out := make([]Tab, 0, len(infos))
+	for _, info := range infos {
+		out = append(out, s.tab(info))
+	}
+	return out
+}
+
+func (s *server) tab(info types.TabInfo) Tab {
+	return Tab{
+		TargetID:    info.TargetID,
+		BrowserID:   info.BrowserID,
+		URL:         info.URL,
+		PathSegment: info.PathSegment,
+		Paused:      slices.Contains(s.pauses.Tabs(), info.TargetID),
+	}
+}
+
+// findTab resolves a target ID or browser ID prefix to one attached tab.
+func (s *server) findTab(id string) (types.TabInfo, error) {
+	id = strings.ToUpper(id)
+	var matches []types.TabInfo
+	for _, tab := range s.r.Tabs() {
+		if strings.HasPrefix(strings.ToUpper(tab.TargetID), id) {
+			matches = append(matches, tab)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return types.TabInfo{}, huma.Error404NotFound("no attached tab matches " + id)
+	case 1:
+		return matches[0], nil
+	default:
+		return types.TabInfo{}, huma.Error409Conflict("tab ID prefix " + id + " is ambiguous")
+	}
+}
+
+// diskUsage returns the size of each top-level entry of dataDir, sorted by
+// name. Unreadable entries are skipped.
+func diskUsage(dataDir string) []DirUsage {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return []DirUsage{}
+	}
+	out := make([]DirUsage, 0, len(entries))
+	for _, e := range entries {
+		var size int64
+		filepath.WalkDir(filepath.Join(dataDir, e.Name()), func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+			return nil
+		})
+		out = append(out, DirUsage{Name: e.Name(), Bytes: size})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,104 @@
+package researchapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/capture"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/storage"
+	"github.com/dgnsrekt/MaudeViewTVCore/internal/types"
+)
+
+type fakeResearcher struct {
+	tabs []types.TabInfo
+	gaps []types.CaptureGap
+	gapT []string
+}
+
+func (f *fakeResearcher) Connected() bool       { return true }
+func (f *fakeResearcher) Tabs() []types.TabInfo { return f.tabs }
+func (f *fakeResearcher) ActiveWebSockets() int { return 2 }
+func (f *fakeResearcher) WriterStats() []storage.WriterStats {
+	return []storage.WriterStats{
+		{PathSegment: "chart", DataType: "http", Written: 10, Dropped: 1},
+		{PathSegment: "chart", DataType: "websocket", Written: 5},
+	}
+}
+func (f *fakeResearcher) WriteGap(tab types.TabInfo, dataType string, gap types.CaptureGap) {
+	f.gaps = append(f.gaps, gap)
+	f.gapT = append(f.gapT, dataType)
+}
+
+func do(t *testing.T, h http.Handler, method, path string, out any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestStatusAndPause(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "2026-03-01", "chart", "http"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2026-03-01", "chart", "http", "AAAA.jsonl"), []byte("12345"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeResearcher{tabs: []types.TabInfo{
+		{TargetID: "AAAA1111", BrowserID: "AAAA1111", PathSegment: "chart"},
+		{TargetID: "BBBB2222", BrowserID: "BBBB2222", PathSegment: "chart"},
+	}}
+	pauses := capture.NewPauses()
+	h := NewServer(r, pauses, dir, "test")
+
+	var st Status
+	if code := do(t, h, http.MethodGet, "/api/v1/status", &st); code != http.StatusOK {
+		t.Fatalf("status code = %d", code)
+	}
+	if !st.Connected || len(st.Tabs) != 2 || st.Written != 15 || st.Dropped != 1 || st.WebSocketConnections != 2 || st.DiskBytes != 5 {
+		t.Fatalf("status = %+v", st)
+	}
+
+	var tab Tab
+	if code := do(t, h, http.MethodPost, "/api/v1/tabs/aaaa/pause", &tab); code != http.StatusOK || !tab.Paused {
+		t.Fatalf("pause tab = %d, %+v", code, tab)
+	}
+	if !pauses.Paused("AAAA1111", capture.DataHTTP) || pauses.Paused("BBBB2222", capture.DataHTTP) {
+		t.Fatal("pause did not apply to the tab only")
+	}
+	if code := do(t, h, http.MethodPost, "/api/v1/tabs/CCCC/pause", nil); code != http.StatusNotFound {
+		t.Fatalf("unknown tab = %d", code)
+	}
+	if code := do(t, h, http.MethodPost, "/api/v1/capture/websocket/pause", nil); code != http.StatusOK {
+		t.Fatalf("pause type = %d", code)
+	}
+	if code := do(t, h, http.MethodPost, "/api/v1/capture/frames/pause", nil); code != http.StatusUnprocessableEntity {
+		t.Fatalf("bad type = %d", code)
+	}
+
+	// Resuming the tab reopens only its http stream; websocket stays paused.
+	if code := do(t, h, http.MethodPost, "/api/v1/tabs/AAAA1111/resume", &tab); code != http.StatusOK || tab.Paused {
+		t.Fatalf("resume tab = %d, %+v", code, tab)
+	}
+	if len(r.gaps) != 1 || r.gapT[0] != capture.DataHTTP || r.gaps[0].Reason != types.GapReasonPaused || r.gaps[0].TabID != "AAAA1111" {
+		t.Fatalf("gaps after tab resume = %+v %v", r.gaps, r.gapT)
+	}
+	if code := do(t, h, http.MethodPost, "/api/v1/capture/websocket/resume", nil); code != http.StatusOK {
+		t.Fatalf("resume type = %d", code)
+	}
+	if len(r.gaps) != 3 || r.gapT[1] != capture.DataWebSocket || r.gapT[2] != capture.DataWebSocket {
+		t.Fatalf("gaps after type resume = %+v %v", r.gaps, r.gapT)
+	}
+	if len(pauses.Tabs()) != 0 || len(pauses.Types()) != 0 {
+		t.Fatalf("pauses left = %v %v", pauses.Tabs(), pauses.Types())
+	}
+}
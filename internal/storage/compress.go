@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats for closed day directories.
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// compressedExts maps each compression format to the suffix appended to a
+// compressed ".jsonl" file.
+var compressedExts = map[string]string{
+	CompressGzip: ".gz",
+	CompressZstd: ".zst",
+}
+
+// ValidCompression reports whether format is a known compression format.
+func ValidCompression(format string) bool {
+	_, ok := compressedExts[format]
+	return ok || format == CompressNone
+}
+
+// trimJSONLExt strips ".jsonl", ".jsonl.gz" or ".jsonl.zst" from name.
+// ok is false for other names.
+func trimJSONLExt(name string) (base string, ok bool) {
+	for _, ext := range []string{".jsonl", ".jsonl.gz", ".jsonl.zst"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), true
+		}
+	}
+	return name, false
+}
+
+// openJSONL opens a JSONL file, decompressing it if its name ends in ".gz"
+// or ".zst".
+func openJSONL(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return readCloser{zr, func() error { zr.Close(); return file.Close() }}, nil
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return readCloser{zr, func() error { zr.Close(); return file.Close() }}, nil
+	}
+	return file, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
+
+// CompressFile replaces the file at path with a compressed copy named
+// path+".gz" or path+".zst" and returns the new path. The copy is synced
+// and renamed into place before the original is removed, so a crash leaves
+// at least one complete file.
+func CompressFile(path, format string) (string, error) {
+	ext, ok := compressedExts[format]
+	if !ok {
+		return "", fmt.Errorf("unknown compression %q", format)
+	}
+	dst := path + ext
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	var zw io.WriteCloser = gzip.NewWriter(tmp)
+	if format == CompressZstd {
+		if zw, err = zstd.NewWriter(tmp); err != nil {
+			tmp.Close()
+			return "", err
+		}
+	}
+	if _, err := io.Copy(zw, src); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return dst, os.Remove(path)
+}
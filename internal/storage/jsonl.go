@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -24,8 +25,30 @@ type JSONLWriter struct {
 	currentDate string
 	logger      *lumberjack.Logger
 	mu          sync.Mutex
+	filter      RecordFilter
+
+	written atomic.Int64
+	dropped atomic.Int64
+	bytes   atomic.Int64
+}
+
+// WriterStats are the counters of a JSONLWriter since it was created.
+// Dropped counts records lost to a full buffer, a closed writer, a close
+// timeout or a failed write.
+type WriterStats struct {
+	PathSegment string `json:"path_segment,omitempty"`
+	DataType    string `json:"data_type,omitempty"`
+	BrowserID   string `json:"browser_id,omitempty"`
+	Written     int64  `json:"written"`
+	Dropped     int64  `json:"dropped"`
+	Bytes       int64  `json:"bytes"`
+	Queued      int    `json:"queued"`
 }
 
+// RecordFilter rewrites a record in place before it is queued for writing,
+// e.g. to redact secrets.
+type RecordFilter func(record any)
+
 // NewJSONLWriter creates a new async JSONL writer (uses timestamp-based filenames)
 func NewJSONLWriter(baseDir, subDir string, bufferSize int, maxSizeMB int) *JSONLWriter {
 	return newJSONLWriter(baseDir, subDir, bufferSize, maxSizeMB, "")
@@ -53,17 +76,27 @@ func newJSONLWriter(baseDir, subDir string, bufferSize int, maxSizeMB int, brows
 	return w
 }
 
+// SetFilter sets a filter applied to every record passed to Write.
+func (w *JSONLWriter) SetFilter(f RecordFilter) {
+	w.filter = f
+}
+
 // Write queues a record for async writing
 func (w *JSONLWriter) Write(record any) error {
+	if w.filter != nil {
+		w.filter(record)
+	}
 	slog.Debug("JSONL write queued",
 		"subdir", w.subDir)
 	select {
 	case w.writeCh <- record:
 		return nil
 	case <-w.done:
+		w.dropped.Add(1)
 		return fmt.Errorf("writer is closed")
 	default:
 		// Channel full, log warning but don't block
+		w.dropped.Add(1)
 		slog.Warn("JSONL write buffer full, dropping record",
 			"subdir", w.subDir)
 		return fmt.Errorf("buffer full")
@@ -81,6 +114,7 @@ func (w *JSONLWriter) Close() error {
 		case record := <-w.writeCh:
 			w.writeRecord(record)
 		case <-timeout:
+			w.dropped.Add(int64(len(w.writeCh)))
 			slog.Warn("JSONL writer close timeout, some records may be lost",
 				"subdir", w.subDir)
 			goto done
@@ -116,6 +150,7 @@ func (w *JSONLWriter) writeLoop() {
 func (w *JSONLWriter) writeRecord(record any) {
 	data, err := json.Marshal(record)
 	if err != nil {
+		w.dropped.Add(1)
 		slog.Error("Failed to marshal record",
 			"error", err,
 			"subdir", w.subDir)
@@ -136,11 +171,26 @@ func (w *JSONLWriter) writeRecord(record any) {
 	}
 
 	// Write the JSON line
-	_, err = w.logger.Write(append(data, '\n'))
+	n, err := w.logger.Write(append(data, '\n'))
 	if err != nil {
+		w.dropped.Add(1)
 		slog.Error("Failed to write record",
 			"error", err,
 			"subdir", w.subDir)
+		return
+	}
+	w.written.Add(1)
+	w.bytes.Add(int64(n))
+}
+
+// Stats returns the writer's counters and current queue length.
+func (w *JSONLWriter) Stats() WriterStats {
+	return WriterStats{
+		BrowserID: w.browserID,
+		Written:   w.written.Load(),
+		Dropped:   w.dropped.Load(),
+		Bytes:     w.bytes.Load(),
+		Queued:    len(w.writeCh),
 	}
 }
 
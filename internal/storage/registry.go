@@ -2,6 +2,7 @@ package storage
 
 import (
 	"log/slog"
+	"sort"
 	"sync"
 )
 
@@ -16,6 +17,7 @@ type WriterRegistry struct {
 	// e.g., "flow_overview" -> "http" -> *JSONLWriter
 	writers map[string]map[string]*JSONLWriter
 	mu      sync.RWMutex
+	filter  RecordFilter
 }
 
 // NewWriterRegistry creates a new WriterRegistry for managing multiple JSONL writers.
@@ -28,6 +30,14 @@ func NewWriterRegistry(baseDir string, bufferSize int, maxSizeMB int) *WriterReg
 	}
 }
 
+// SetFilter sets a filter applied by every writer the registry creates.
+// Call it before the first GetWriter.
+func (r *WriterRegistry) SetFilter(f RecordFilter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filter = f
+}
+
 // GetWriter returns (or creates) a JSONLWriter for the given path segment and data type.
 // pathSegment is the transformed URL path (e.g., "flow_overview")
 // dataType is "http" or "websocket"
@@ -70,6 +80,7 @@ func (r *WriterRegistry) GetWriter(pathSegment, dataType, browserID string) *JSO
 		r.maxSizeMB,
 		browserID,
 	)
+	writer.SetFilter(r.filter)
 
 	r.writers[pathSegment][dataType] = writer
 
@@ -81,6 +92,28 @@ func (r *WriterRegistry) GetWriter(pathSegment, dataType, browserID string) *JSO
 	return writer
 }
 
+// Stats returns the counters of every writer, ordered by path segment and
+// data type.
+func (r *WriterRegistry) Stats() []WriterStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []WriterStats
+	for pathSeg, typeMap := range r.writers {
+		for dataType, writer := range typeMap {
+			s := writer.Stats()
+			s.PathSegment, s.DataType = pathSeg, dataType
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PathSegment != out[j].PathSegment {
+			return out[i].PathSegment < out[j].PathSegment
+		}
+		return out[i].DataType < out[j].DataType
+	})
+	return out
+}
+
 // Close closes all managed writers.
 func (r *WriterRegistry) Close() error {
 	r.mu.Lock()
@@ -0,0 +1,28 @@
+package storage
+
+import "testing"
+
+func TestWriterRegistryStats(t *testing.T) {
+	r := NewWriterRegistry(t.TempDir(), 10, 1)
+	http := r.GetWriter("chart", "http", "AAAA0000")
+	ws := r.GetWriter("chart", "websocket", "AAAA0000")
+	for i := 0; i < 3; i++ {
+		if err := http.Write(map[string]int{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := http.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := r.Stats()
+	if len(stats) != 2 || stats[0].DataType != "http" || stats[1].DataType != "websocket" {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if s := stats[0]; s.PathSegment != "chart" || s.BrowserID != "AAAA0000" || s.Written != 3 || s.Dropped != 0 || s.Bytes != int64(3*len(`{"i":0}`+"\n")) {
+		t.Fatalf("http stats = %+v", s)
+	}
+}
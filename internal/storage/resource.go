@@ -1,32 +1,180 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
-// ResourceWriter writes raw file content for static resources.
+// ResourceIndexFile is the per-day index of resource sightings, stored as
+// baseDir/<date>/resources.jsonl.
+const ResourceIndexFile = "resources.jsonl"
+
+// ResourceEntry records one sighting of a static resource: which URL and
+// tab served it on which day, and the blob holding its content.
+type ResourceEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	URL          string    `json:"url"`
+	TabID        string    `json:"tab_id"`
+	PathSegment  string    `json:"path_segment"`
+	Type         string    `json:"type"` // js, css, img, ...
+	Filename     string    `json:"filename"`
+	SHA256       string    `json:"sha256"`
+	Size         int       `json:"size"`
+	Truncated    bool      `json:"truncated,omitempty"`
+	OriginalSize int       `json:"original_size,omitempty"`
+}
+
+// ResourceWriter stores static resources in a content-addressed blob store
+// under baseDir/blobs/sha256/, so identical bundles are kept once and
+// different files with the same basename never overwrite each other. Each
+// sighting is appended to the day's index.
 type ResourceWriter struct {
 	baseDir string
+	filter  RecordFilter
+
+	mu        sync.Mutex
+	date      string
+	index     *os.File
+	indexSeen map[string]bool // url|tab|sha already indexed today
+	blobs     map[string]bool // blobs known to exist, reset daily
 }
 
 func NewResourceWriter(baseDir string) *ResourceWriter {
-	return &ResourceWriter{baseDir: baseDir}
+	return &ResourceWriter{baseDir: baseDir, blobs: make(map[string]bool)}
+}
+
+// SetFilter sets a filter applied to each *ResourceEntry before it is
+// indexed, e.g. to redact URLs. Call before the first Write.
+func (w *ResourceWriter) SetFilter(f RecordFilter) {
+	w.filter = f
+}
+
+// BlobPath returns the path of the blob with the given hex SHA-256.
+func BlobPath(baseDir, sha string) string {
+	if len(sha) < 2 {
+		return filepath.Join(baseDir, "blobs", "sha256", sha)
+	}
+	return filepath.Join(baseDir, "blobs", "sha256", sha[:2], sha)
 }
 
-// WriteRaw saves bytes to: baseDir/date/pathSegment/resources/resourceType/filename.
-func (w *ResourceWriter) WriteRaw(pathSegment, resourceType, filename string, data []byte) error {
-	date := time.Now().UTC().Format("2006-01-02")
-	dir := filepath.Join(w.baseDir, date, pathSegment, "resources", resourceType)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+// Write stores data as a blob (if not already present) and indexes the
+// sighting. e.SHA256, e.Size and e.Timestamp are filled in and returned.
+func (w *ResourceWriter) Write(e ResourceEntry, data []byte) (ResourceEntry, error) {
+	sum := sha256.Sum256(data)
+	e.SHA256 = hex.EncodeToString(sum[:])
+	e.Size = len(data)
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if err := w.writeBlob(e.SHA256, data); err != nil {
+		return e, err
+	}
+	if w.filter != nil {
+		w.filter(&e)
+	}
+	if err := w.writeIndex(e); err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+func (w *ResourceWriter) writeBlob(sha string, data []byte) error {
+	w.mu.Lock()
+	known := w.blobs[sha]
+	w.mu.Unlock()
+	if known {
+		return nil
+	}
+	path := BlobPath(w.baseDir, sha)
+	if _, err := os.Stat(path); err == nil {
+		// Refresh the mtime so a retention sweep running before this
+		// sighting is indexed keeps the blob.
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		w.markBlob(sha)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), sha+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
 		return err
 	}
-	filePath := filepath.Join(dir, filename)
-	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	w.markBlob(sha)
+	slog.Debug("Resource blob written", "path", path, "size", len(data))
+	return nil
+}
+
+func (w *ResourceWriter) markBlob(sha string) {
+	w.mu.Lock()
+	w.blobs[sha] = true
+	w.mu.Unlock()
+}
+
+// writeIndex appends e to the index of its day, once per URL, tab and
+// content per day.
+func (w *ResourceWriter) writeIndex(e ResourceEntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	date := e.Timestamp.UTC().Format(dateLayout)
+	if date != w.date || w.index == nil {
+		if w.index != nil {
+			w.index.Close()
+		}
+		dir := filepath.Join(w.baseDir, date)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(dir, ResourceIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		// Forget the blobs seen on the previous day: retention may remove
+		// them once that day's index is gone.
+		w.date, w.index, w.indexSeen, w.blobs = date, f, make(map[string]bool), make(map[string]bool)
+	}
+	key := e.URL + "|" + e.TabID + "|" + e.SHA256
+	if w.indexSeen[key] {
+		return nil
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
 		return err
 	}
-	slog.Debug("Resource file written", "path", filePath, "size", len(data))
+	if _, err := w.index.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("resource index: %w", err)
+	}
+	w.indexSeen[key] = true
 	return nil
 }
+
+// Close closes the current index file.
+func (w *ResourceWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.index == nil {
+		return nil
+	}
+	err := w.index.Close()
+	w.index = nil
+	return err
+}
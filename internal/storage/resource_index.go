@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ReadResourceIndex calls fn with each entry of the resource indexes of the
+// days selected by f, compressed or not. f.DataType is ignored. Lines that
+// do not decode are skipped.
+func ReadResourceIndex(dataDir string, f ScanFilter, fn func(ResourceEntry) error) error {
+	dates, err := listDates(dataDir, f)
+	if err != nil {
+		return err
+	}
+	for _, date := range dates {
+		files, err := jsonlFiles(filepath.Join(dataDir, date))
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(files, func(p string) bool {
+			base, _ := trimJSONLExt(filepath.Base(p))
+			return base+".jsonl" == ResourceIndexFile
+		})
+		if i < 0 {
+			continue
+		}
+		path := files[i]
+		err = ReadJSONL(path, func(line []byte) error {
+			var e ResourceEntry
+			if json.Unmarshal(line, &e) != nil {
+				return nil
+			}
+			if f.PathSegment != "" && e.PathSegment != f.PathSegment {
+				return nil
+			}
+			return fn(e)
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// hashToken matches the content hashes bundlers put in file names, e.g. the
+// "a1b2c3d4e5f6" in "runtime.a1b2c3d4e5f6.js".
+var hashToken = regexp.MustCompile(`(^|[.\-_])[0-9a-f]{8,}([.\-_]|$)`)
+
+// ResourceName returns a name for rawURL that stays the same across
+// deploys: host and path without the query, with content-hash tokens in the
+// file name replaced by "{hash}".
+func ResourceName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	dir, file := "", u.Path
+	if i := strings.LastIndexByte(u.Path, '/'); i >= 0 {
+		dir, file = u.Path[:i+1], u.Path[i+1:]
+	}
+	// Adjacent tokens share a separator, so replace until stable.
+	for {
+		next := hashToken.ReplaceAllString(file, "${1}{hash}${2}")
+		if next == file {
+			break
+		}
+		file = next
+	}
+	return u.Host + dir + file
+}
+
+// ResourceChange is the difference for one ResourceName between two sets
+// of index entries.
+type ResourceChange struct {
+	Name   string   `json:"name"`
+	Status string   `json:"status"` // added, removed or changed
+	Base   []string `json:"base,omitempty"`
+	Head   []string `json:"head,omitempty"`
+}
+
+// CompareResources groups both sets by ResourceName and reports names that
+// appear in only one of them or whose set of content hashes differs. The
+// result is sorted by name.
+func CompareResources(base, head []ResourceEntry) []ResourceChange {
+	b, h := resourceVersions(base), resourceVersions(head)
+	var out []ResourceChange
+	for name, bv := range b {
+		hv, ok := h[name]
+		switch {
+		case !ok:
+			out = append(out, ResourceChange{Name: name, Status: "removed", Base: bv})
+		case strings.Join(bv, ",") != strings.Join(hv, ","):
+			out = append(out, ResourceChange{Name: name, Status: "changed", Base: bv, Head: hv})
+		}
+	}
+	for name, hv := range h {
+		if _, ok := b[name]; !ok {
+			out = append(out, ResourceChange{Name: name, Status: "added", Head: hv})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// resourceVersions maps each ResourceName to its sorted, distinct hashes.
+func resourceVersions(entries []ResourceEntry) map[string][]string {
+	seen := make(map[string]map[string]bool)
+	for _, e := range entries {
+		name := ResourceName(e.URL)
+		if seen[name] == nil {
+			seen[name] = make(map[string]bool)
+		}
+		seen[name][e.SHA256] = true
+	}
+	out := make(map[string][]string, len(seen))
+	for name, shas := range seen {
+		for sha := range shas {
+			out[name] = append(out[name], sha)
+		}
+		sort.Strings(out[name])
+	}
+	return out
+}
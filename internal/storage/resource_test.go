@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceWriterDedupes(t *testing.T) {
+	dir := t.TempDir()
+	w := NewResourceWriter(dir)
+	w.SetFilter(func(record any) {
+		if e, ok := record.(*ResourceEntry); ok {
+			e.URL = strings.ReplaceAll(e.URL, "secret", "x")
+		}
+	})
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	write := func(ts time.Time, url, tab, data string) ResourceEntry {
+		t.Helper()
+		e, err := w.Write(ResourceEntry{Timestamp: ts, URL: url, TabID: tab, Type: "js", Filename: FilenameFromURL(url)}, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	a := write(day1, "https://s.test/a/app.js?t=secret", "T1", "one")
+	write(day1, "https://s.test/a/app.js?t=secret", "T1", "one") // same sighting
+	write(day1, "https://s.test/a/app.js?t=secret", "T2", "one")
+	b := write(day1, "https://s.test/b/app.js", "T1", "two") // same basename
+	write(day2, "https://s.test/a/app.js", "T1", "one")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.SHA256 == b.SHA256 || a.Size != 3 {
+		t.Fatalf("entries = %+v, %+v", a, b)
+	}
+	blobs, _ := filepath.Glob(filepath.Join(dir, "blobs", "sha256", "*", "*"))
+	if len(blobs) != 2 {
+		t.Fatalf("blobs = %v", blobs)
+	}
+	if data, err := os.ReadFile(BlobPath(dir, a.SHA256)); err != nil || string(data) != "one" {
+		t.Fatalf("blob = %q, %v", data, err)
+	}
+
+	var day1Entries []ResourceEntry
+	err := ReadResourceIndex(dir, ScanFilter{From: day1, To: day1}, func(e ResourceEntry) error {
+		day1Entries = append(day1Entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(day1Entries) != 3 || day1Entries[0].URL != "https://s.test/a/app.js?t=x" {
+		t.Fatalf("day 1 index = %+v", day1Entries)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	cases := map[string]string{
+		"https://static.tradingview.com/static/bundles/runtime.3f2a9c1e0b7d.js":     "static.tradingview.com/static/bundles/runtime.{hash}.js",
+		"https://static.tradingview.com/static/bundles/12345.deadbeefcafe.js?v=1":   "static.tradingview.com/static/bundles/12345.{hash}.js",
+		"https://static.tradingview.com/static/bundles/chart-0123abcd-89abcdef.css": "static.tradingview.com/static/bundles/chart-{hash}-{hash}.css",
+		"https://static.tradingview.com/static/images/logo.svg":                     "static.tradingview.com/static/images/logo.svg",
+		"https://static.tradingview.com/static/bundles/deadbeefcafe0123/facade.js":  "static.tradingview.com/static/bundles/deadbeefcafe0123/facade.js",
+	}
+	for in, want := range cases {
+		if got := ResourceName(in); got != want {
+			t.Errorf("ResourceName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCompareResources(t *testing.T) {
+	base := []ResourceEntry{
+		{URL: "https://s.test/runtime.aaaaaaaa.js", SHA256: "1"},
+		{URL: "https://s.test/vendor.bbbbbbbb.js", SHA256: "2"},
+		{URL: "https://s.test/old.js", SHA256: "3"},
+	}
+	head := []ResourceEntry{
+		{URL: "https://s.test/runtime.cccccccc.js", SHA256: "4"},
+		{URL: "https://s.test/vendor.bbbbbbbb.js", SHA256: "2"},
+		{URL: "https://s.test/new.js", SHA256: "5"},
+	}
+	got := CompareResources(base, head)
+	want := []ResourceChange{
+		{Name: "s.test/new.js", Status: "added", Head: []string{"5"}},
+		{Name: "s.test/old.js", Status: "removed", Base: []string{"3"}},
+		{Name: "s.test/runtime.{hash}.js", Status: "changed", Base: []string{"1"}, Head: []string{"4"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %+v", got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Status != w.Status || strings.Join(g.Base, ",") != strings.Join(w.Base, ",") || strings.Join(g.Head, ",") != strings.Join(w.Head, ",") {
+			t.Errorf("change %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
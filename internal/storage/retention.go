@@ -0,0 +1,401 @@
+package storage
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// closeGrace is how long after midnight UTC a day directory counts as
+// closed. Writers move to the new day on their next record, and a blob is
+// indexed right after it is written, so by then nothing still appends to
+// the previous day or needs an unindexed blob.
+const closeGrace = time.Hour
+
+// RetentionResources is the RetentionPolicy.MaxAge key for the per-day
+// resource indexes; "http" and "websocket" select the JSONL streams.
+const RetentionResources = "resources"
+
+// RetentionPolicy configures a Retention.
+type RetentionPolicy struct {
+	// MaxAge maps "http", "websocket" or RetentionResources to how long
+	// after a day ends its files of that type are kept. Zero or missing
+	// keeps them forever.
+	MaxAge map[string]time.Duration
+	// QuotaBytes caps the size of the data directory. When it is exceeded,
+	// the oldest closed days are removed until it fits. Zero disables it.
+	QuotaBytes int64
+	// Compression is CompressGzip, CompressZstd or CompressNone, applied to
+	// the JSONL files of closed days.
+	Compression string
+}
+
+// RetentionReport summarises one sweep. Paths are relative to the data
+// directory.
+type RetentionReport struct {
+	Removed      []string `json:"removed"`
+	Compressed   int      `json:"compressed"`
+	BlobsRemoved int      `json:"blobs_removed"`
+	FreedBytes   int64    `json:"freed_bytes"`
+	TotalBytes   int64    `json:"total_bytes"`
+	OverQuota    bool     `json:"over_quota"`
+}
+
+// Retention prunes, compresses and caps the data directory written by the
+// JSONL and resource writers. Blobs no longer referenced by any resource
+// index are removed along with the days that referenced them.
+type Retention struct {
+	dataDir string
+	policy  RetentionPolicy
+	now     func() time.Time
+}
+
+// NewRetention returns a Retention for dataDir.
+func NewRetention(dataDir string, policy RetentionPolicy) *Retention {
+	return &Retention{dataDir: dataDir, policy: policy, now: time.Now}
+}
+
+// Run sweeps immediately and then every interval until ctx is cancelled.
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		rep, err := r.Sweep(false)
+		if err != nil {
+			slog.Error("Retention sweep failed", "error", err)
+		} else {
+			if len(rep.Removed) > 0 || rep.Compressed > 0 || rep.BlobsRemoved > 0 {
+				slog.Info("Retention sweep",
+					"removed", len(rep.Removed),
+					"compressed", rep.Compressed,
+					"blobs_removed", rep.BlobsRemoved,
+					"freed_bytes", rep.FreedBytes,
+					"total_bytes", rep.TotalBytes)
+			}
+			if rep.OverQuota {
+				slog.Warn("Data directory is over quota but only open days are left",
+					"total_bytes", rep.TotalBytes,
+					"quota_bytes", r.policy.QuotaBytes)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep applies the policy once: it removes files past their max age,
+// compresses closed days, removes unreferenced blobs and then removes the
+// oldest closed days while the directory is over quota. The current day
+// and closeGrace after it are never compressed or removed for quota. With
+// dryRun nothing is changed and the report lists what would be removed;
+// the bytes compression would save are not known in advance.
+func (r *Retention) Sweep(dryRun bool) (RetentionReport, error) {
+	s := &sweep{Retention: r, dryRun: dryRun, now: r.now().UTC()}
+	dates, err := listDates(r.dataDir, ScanFilter{})
+	if err != nil {
+		return s.rep, err
+	}
+
+	for _, date := range dates {
+		if err := s.expire(date); err != nil {
+			return s.rep, err
+		}
+	}
+
+	var closed []string
+	for _, date := range dates {
+		if s.closed(date) {
+			closed = append(closed, date)
+		}
+	}
+	if r.policy.Compression != "" && r.policy.Compression != CompressNone {
+		for _, date := range closed {
+			if err := s.compress(date); err != nil {
+				return s.rep, err
+			}
+		}
+	}
+
+	blobs, err := s.blobRefs(dates)
+	if err != nil {
+		return s.rep, err
+	}
+	for sha, b := range blobs.files {
+		if blobs.refs[sha] == 0 {
+			s.removeBlob(sha, b)
+		}
+	}
+
+	total := s.size(r.dataDir)
+	if r.policy.QuotaBytes > 0 {
+		for _, date := range closed {
+			if total <= r.policy.QuotaBytes {
+				break
+			}
+			dir := filepath.Join(r.dataDir, date)
+			if s.isGone(dir) {
+				continue
+			}
+			total -= s.remove(dir)
+			for sha := range blobs.byDay[date] {
+				blobs.refs[sha]--
+				if b, ok := blobs.files[sha]; ok && blobs.refs[sha] == 0 {
+					total -= s.removeBlob(sha, b)
+				}
+			}
+		}
+		s.rep.OverQuota = total > r.policy.QuotaBytes
+	}
+	s.rep.TotalBytes = total
+	return s.rep, nil
+}
+
+// sweep is the state of one Sweep. gone holds the paths removed so far, so
+// a dry run sizes and skips them as if they were deleted.
+type sweep struct {
+	*Retention
+	dryRun bool
+	now    time.Time
+	gone   []string
+	rep    RetentionReport
+}
+
+// closed reports whether date ended at least closeGrace ago.
+func (s *sweep) closed(date string) bool {
+	day, err := time.Parse(dateLayout, date)
+	return err == nil && !s.now.Before(day.AddDate(0, 0, 1).Add(closeGrace))
+}
+
+// expire removes the files of date whose data type is past its max age.
+func (s *sweep) expire(date string) error {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return nil
+	}
+	end := day.AddDate(0, 0, 1)
+	dayDir := filepath.Join(s.dataDir, date)
+	for _, dataType := range []string{"http", "websocket", RetentionResources} {
+		maxAge := s.policy.MaxAge[dataType]
+		if maxAge <= 0 || s.now.Sub(end) < maxAge {
+			continue
+		}
+		var paths []string
+		if dataType == RetentionResources {
+			paths, err = filepath.Glob(filepath.Join(dayDir, ResourceIndexFile+"*"))
+		} else {
+			paths, err = filepath.Glob(filepath.Join(dayDir, "*", dataType))
+		}
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			if !s.isGone(p) {
+				s.remove(p)
+			}
+		}
+	}
+	if !s.dryRun {
+		removeEmptyDirs(dayDir)
+	}
+	return nil
+}
+
+// compress compresses the uncompressed JSONL files of a closed day.
+func (s *sweep) compress(date string) error {
+	dayDir := filepath.Join(s.dataDir, date)
+	if s.isGone(dayDir) {
+		return nil
+	}
+	var paths []string
+	err := filepath.WalkDir(dayDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".jsonl") && !s.isGone(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		s.rep.Compressed++
+		if s.dryRun {
+			continue
+		}
+		before := fileSize(path)
+		dst, err := CompressFile(path, s.policy.Compression)
+		if err != nil {
+			return err
+		}
+		s.rep.FreedBytes += before - fileSize(dst)
+	}
+	return nil
+}
+
+type blobFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// blobIndex holds the blobs on disk and how many days reference each.
+type blobIndex struct {
+	files map[string]blobFile
+	refs  map[string]int
+	byDay map[string]map[string]bool
+}
+
+func (s *sweep) blobRefs(dates []string) (*blobIndex, error) {
+	idx := &blobIndex{files: make(map[string]blobFile), refs: make(map[string]int), byDay: make(map[string]map[string]bool)}
+	root := filepath.Join(s.dataDir, "blobs", "sha256")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			idx.files[d.Name()] = blobFile{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, date := range dates {
+		indexes, err := filepath.Glob(filepath.Join(s.dataDir, date, ResourceIndexFile+"*"))
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(indexes, func(p string) bool { return !s.isGone(p) }) {
+			continue
+		}
+		day, _ := time.Parse(dateLayout, date)
+		shas := make(map[string]bool)
+		err = ReadResourceIndex(s.dataDir, ScanFilter{From: day, To: day}, func(e ResourceEntry) error {
+			shas[e.SHA256] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for sha := range shas {
+			idx.refs[sha]++
+		}
+		idx.byDay[date] = shas
+	}
+	return idx, nil
+}
+
+// removeBlob removes an unreferenced blob unless it was written or seen
+// within closeGrace, i.e. it may belong to a sighting not yet indexed.
+func (s *sweep) removeBlob(sha string, b blobFile) int64 {
+	if s.now.Sub(b.modTime) < closeGrace {
+		return 0
+	}
+	path := BlobPath(s.dataDir, sha)
+	if s.isGone(path) {
+		return 0
+	}
+	s.rep.BlobsRemoved++
+	s.rep.FreedBytes += b.size
+	s.gone = append(s.gone, path)
+	if !s.dryRun {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			slog.Warn("Failed to remove blob", "path", path, "error", err)
+		}
+		os.Remove(filepath.Dir(path)) // only succeeds once empty
+	}
+	return b.size
+}
+
+// remove removes path (a file or directory) and returns the bytes freed.
+func (s *sweep) remove(path string) int64 {
+	size := s.size(path)
+	rel, err := filepath.Rel(s.dataDir, path)
+	if err != nil {
+		rel = path
+	}
+	s.rep.Removed = append(s.rep.Removed, filepath.ToSlash(rel))
+	s.rep.FreedBytes += size
+	s.gone = append(s.gone, path)
+	if !s.dryRun {
+		if err := os.RemoveAll(path); err != nil {
+			slog.Warn("Failed to remove expired capture data", "path", path, "error", err)
+		}
+	}
+	return size
+}
+
+func (s *sweep) isGone(path string) bool {
+	for _, g := range s.gone {
+		if path == g || strings.HasPrefix(path, g+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// size returns the bytes of the files under path that are not gone.
+func (s *sweep) size(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if s.isGone(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// removeEmptyDirs removes the empty directories under and including dir,
+// deepest first.
+func removeEmptyDirs(dir string) {
+	var dirs []string
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, d := range dirs {
+		os.Remove(d) // fails unless empty
+	}
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetentionSweep(t *testing.T) {
+	dir := t.TempDir()
+	for _, date := range []string{"2026-03-01", "2026-03-02", "2026-03-10"} {
+		writeFile(t, filepath.Join(dir, date, "chart", "http", "AAAA0000.jsonl"), `{"d":"`+date+`"}`+"\n")
+		writeFile(t, filepath.Join(dir, date, "chart", "websocket", "AAAA0000.jsonl"), `{"d":"`+date+`"}`+"\n")
+	}
+	rw := NewResourceWriter(dir)
+	day1 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	today := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	var shared, old ResourceEntry
+	for _, w := range []struct {
+		ts   time.Time
+		data string
+		out  *ResourceEntry
+	}{{day1, "shared", &shared}, {day1, "old", &old}, {today, "shared", nil}} {
+		e, err := rw.Write(ResourceEntry{Timestamp: w.ts, URL: "https://s.test/" + w.data + ".js", TabID: "T1"}, []byte(w.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.out != nil {
+			*w.out = e
+		}
+	}
+	rw.Close()
+	past := today.Add(-48 * time.Hour)
+	for _, sha := range []string{shared.SHA256, old.SHA256} {
+		os.Chtimes(BlobPath(dir, sha), past, past)
+	}
+
+	r := NewRetention(dir, RetentionPolicy{MaxAge: map[string]time.Duration{"http": 5 * 24 * time.Hour}, Compression: CompressGzip})
+	r.now = func() time.Time { return today.Add(3 * time.Hour) }
+
+	rep, err := r.Sweep(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRemoved := []string{"2026-03-01/chart/http", "2026-03-02/chart/http"}
+	if !slices.Equal(rep.Removed, wantRemoved) || rep.Compressed != 3 || rep.BlobsRemoved != 0 {
+		t.Fatalf("dry run = %+v", rep)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-03-01", "chart", "http")); err != nil {
+		t.Fatalf("dry run removed files: %v", err)
+	}
+
+	if rep, err = r.Sweep(false); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(rep.Removed, wantRemoved) || rep.Compressed != 3 {
+		t.Fatalf("sweep = %+v", rep)
+	}
+	files, err := ListCaptureFiles(dir, ScanFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		err := ReadJSONL(f.Path, func(line []byte) error {
+			got = append(got, filepath.ToSlash(rel)+" "+string(line))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		`2026-03-01/chart/websocket/AAAA0000.jsonl.gz {"d":"2026-03-01"}`,
+		`2026-03-02/chart/websocket/AAAA0000.jsonl.gz {"d":"2026-03-02"}`,
+		`2026-03-10/chart/http/AAAA0000.jsonl {"d":"2026-03-10"}`,
+		`2026-03-10/chart/websocket/AAAA0000.jsonl {"d":"2026-03-10"}`,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("records = %q", got)
+	}
+	var indexed int
+	if err := ReadResourceIndex(dir, ScanFilter{}, func(ResourceEntry) error { indexed++; return nil }); err != nil || indexed != 3 {
+		t.Fatalf("indexed = %d, %v", indexed, err)
+	}
+
+	// A quota smaller than today's data removes every closed day and the
+	// blobs only they referenced, but never today.
+	r.policy = RetentionPolicy{QuotaBytes: 1, Compression: CompressZstd}
+	if rep, err = r.Sweep(false); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(rep.Removed, []string{"2026-03-01", "2026-03-02"}) || rep.BlobsRemoved != 1 || !rep.OverQuota {
+		t.Fatalf("quota sweep = %+v", rep)
+	}
+	if _, err := os.Stat(BlobPath(dir, old.SHA256)); !os.IsNotExist(err) {
+		t.Fatalf("unreferenced blob kept: %v", err)
+	}
+	if _, err := os.Stat(BlobPath(dir, shared.SHA256)); err != nil {
+		t.Fatalf("blob referenced today removed: %v", err)
+	}
+	if dates, _ := listDates(dir, ScanFilter{}); !slices.Equal(dates, []string{"2026-03-10"}) {
+		t.Fatalf("dates = %v", dates)
+	}
+}
+
+func TestCompressFileZstd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "AAAA0000.jsonl")
+	writeFile(t, path, "{\"a\":1}\n{\"a\":2}\n")
+	dst, err := CompressFile(path, CompressZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dst) != "AAAA0000.jsonl.zst" {
+		t.Fatalf("dst = %s", dst)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("original kept: %v", err)
+	}
+	var lines []string
+	if err := ReadJSONL(dst, func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{`{"a":1}`, `{"a":2}`}) {
+		t.Fatalf("lines = %q", lines)
+	}
+
+	// An interrupted compression leaves both; only the original is listed.
+	writeFile(t, path, "{\"a\":1}\n")
+	files, err := jsonlFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != path {
+		t.Fatalf("files = %v", files)
+	}
+}
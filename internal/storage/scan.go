@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of the per-day directories under the data dir.
+const dateLayout = "2006-01-02"
+
+// maxLineBytes bounds a single JSONL record read back from disk. Bodies and
+// frames are already truncated at capture time, so this only guards against
+// corrupt files.
+const maxLineBytes = 256 * 1024 * 1024
+
+// CaptureFile is one JSONL file written by a JSONLWriter, either the active
+// file or a lumberjack-rotated backup.
+type CaptureFile struct {
+	Date        string // YYYY-MM-DD directory
+	PathSegment string // e.g. "chart"
+	DataType    string // "http" or "websocket"
+	Path        string
+}
+
+// ScanFilter selects capture files. Zero fields match everything; From and
+// To are inclusive and compared by UTC date.
+type ScanFilter struct {
+	From        time.Time
+	To          time.Time
+	PathSegment string
+	DataType    string
+}
+
+// ListCaptureFiles returns the capture files under dataDir laid out as
+// <date>/<path segment>/<data type>/*.jsonl, ordered by date, path segment,
+// data type, then oldest file first (rotated backups before the active file).
+func ListCaptureFiles(dataDir string, f ScanFilter) ([]CaptureFile, error) {
+	dates, err := listDates(dataDir, f)
+	if err != nil {
+		return nil, err
+	}
+	var out []CaptureFile
+	for _, date := range dates {
+		segs, err := os.ReadDir(filepath.Join(dataDir, date))
+		if err != nil {
+			return nil, err
+		}
+		for _, seg := range segs {
+			if !seg.IsDir() || (f.PathSegment != "" && seg.Name() != f.PathSegment) {
+				continue
+			}
+			for _, dataType := range []string{"http", "websocket"} {
+				if f.DataType != "" && dataType != f.DataType {
+					continue
+				}
+				dir := filepath.Join(dataDir, date, seg.Name(), dataType)
+				files, err := jsonlFiles(dir)
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range files {
+					out = append(out, CaptureFile{Date: date, PathSegment: seg.Name(), DataType: dataType, Path: p})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// listDates returns the per-day directory names under dataDir within the
+// From/To range of f, in ascending order.
+func listDates(dataDir string, f ScanFilter) ([]string, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("read data dir: %w", err)
+	}
+	var from, to string
+	if !f.From.IsZero() {
+		from = f.From.UTC().Format(dateLayout)
+	}
+	if !f.To.IsZero() {
+		to = f.To.UTC().Format(dateLayout)
+	}
+	var dates []string
+	for _, d := range entries {
+		if !d.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, d.Name()); err != nil {
+			continue
+		}
+		if (from != "" && d.Name() < from) || (to != "" && d.Name() > to) {
+			continue
+		}
+		dates = append(dates, d.Name())
+	}
+	return dates, nil
+}
+
+// jsonlFiles lists the JSONL files in dir, oldest first, including files
+// compressed by the retention manager (".jsonl.gz", ".jsonl.zst").
+// Lumberjack names backups <name>-<timestamp>.jsonl, so a backup sorts
+// before its active file once the extension is stripped. If a compressed
+// copy sits next to its original (a compression interrupted before the
+// original was removed), only the original is listed.
+func jsonlFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string) // name without extension -> file name
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		base, ok := trimJSONLExt(e.Name())
+		if !ok {
+			continue
+		}
+		prev, seen := files[base]
+		if !seen {
+			names = append(names, base)
+		}
+		if !seen || !strings.HasSuffix(prev, ".jsonl") {
+			files[base] = e.Name()
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		bi, ti := splitBackup(names[i])
+		bj, tj := splitBackup(names[j])
+		if bi != bj {
+			return bi < bj
+		}
+		// The active file (no timestamp) is the newest.
+		if ti == "" || tj == "" {
+			return tj == "" && ti != ""
+		}
+		return ti < tj
+	})
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = filepath.Join(dir, files[n])
+	}
+	return out, nil
+}
+
+// splitBackup splits a lumberjack backup name "B0D5A8E8-2026-01-02T15-04-05.000"
+// into its base and timestamp. Non-backup names return an empty timestamp.
+func splitBackup(name string) (base, stamp string) {
+	const stampLen = len("2006-01-02T15-04-05.000")
+	if len(name) > stampLen+1 && name[len(name)-stampLen-1] == '-' {
+		if _, err := time.Parse("2006-01-02T15-04-05.000", name[len(name)-stampLen:]); err == nil {
+			return name[:len(name)-stampLen-1], name[len(name)-stampLen:]
+		}
+	}
+	return name, ""
+}
+
+// ReadJSONL calls fn with each non-empty line of the file at path, which may
+// be gzip (".gz") or zstd (".zst") compressed. The line is only valid until
+// fn returns. Reading stops at the first error from fn.
+func ReadJSONL(path string, fn func(line []byte) error) error {
+	file, err := openJSONL(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return readLines(file, fn)
+}
+
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(sc.Bytes()); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
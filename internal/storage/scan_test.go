@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListCaptureFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "2026-01-01", "chart", "http", "AAAA0000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "2026-01-02", "chart", "http", "AAAA0000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "2026-01-02", "chart", "http", "AAAA0000-2026-01-02T10-00-00.000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "2026-01-02", "chart", "http", "AAAA0000-2026-01-02T09-00-00.000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "2026-01-02", "chart", "websocket", "AAAA0000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "2026-01-02", "screener", "http", "BBBB0000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "2026-01-03", "chart", "http", "AAAA0000.jsonl"), "{}\n")
+	writeFile(t, filepath.Join(dir, "resources", "chart", "js", "app.js"), "")
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	files, err := ListCaptureFiles(dir, ScanFilter{From: day("2026-01-02"), To: day("2026-01-02"), PathSegment: "chart"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		got = append(got, rel)
+	}
+	want := []string{
+		"2026-01-02/chart/http/AAAA0000-2026-01-02T09-00-00.000.jsonl",
+		"2026-01-02/chart/http/AAAA0000-2026-01-02T10-00-00.000.jsonl",
+		"2026-01-02/chart/http/AAAA0000.jsonl",
+		"2026-01-02/chart/websocket/AAAA0000.jsonl",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+
+	files, err = ListCaptureFiles(dir, ScanFilter{DataType: "websocket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].DataType != "websocket" || files[0].Date != "2026-01-02" {
+		t.Fatalf("websocket files = %+v", files)
+	}
+}
+
+func TestReadJSONLSkipsBlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.jsonl")
+	writeFile(t, path, "{\"a\":1}\n\n{\"a\":2}\n")
+	var lines []string
+	if err := ReadJSONL(path, func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[1] != `{"a":2}` {
+		t.Fatalf("lines = %q", lines)
+	}
+}
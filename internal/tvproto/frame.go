@@ -0,0 +1,92 @@
+package tvproto
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	frameMarker     = "~m~"
+	heartbeatPrefix = "~h~"
+)
+
+// SplitFrames splits a chart-socket payload into its individual messages.
+//
+// TradingView frames every message as ~m~<len>~m~<payload> and may pack
+// several messages into one WebSocket frame. Payloads without the ~m~ prefix
+// (e.g. the plain-JSON pushstream feeds) are returned as a single message.
+// Malformed trailing data is returned as-is so nothing is silently lost.
+func SplitFrames(payload string) []string {
+	if !strings.HasPrefix(payload, frameMarker) {
+		if payload == "" {
+			return nil
+		}
+		return []string{payload}
+	}
+
+	var msgs []string
+	rest := payload
+	for len(rest) > 0 {
+		if !strings.HasPrefix(rest, frameMarker) {
+			msgs = append(msgs, rest)
+			break
+		}
+		header := rest[len(frameMarker):]
+		end := strings.Index(header, frameMarker)
+		if end < 0 {
+			msgs = append(msgs, rest)
+			break
+		}
+		n, err := strconv.Atoi(header[:end])
+		if err != nil || n < 0 {
+			msgs = append(msgs, rest)
+			break
+		}
+		body := header[end+len(frameMarker):]
+		size := frameBodySize(body, n)
+		msgs = append(msgs, body[:size])
+		rest = body[size:]
+	}
+	return msgs
+}
+
+// JoinFrames frames each message as ~m~<len>~m~<payload> and concatenates
+// them into one chart-socket payload, the inverse of SplitFrames. Lengths
+// are byte counts, as the TradingView server sends them.
+func JoinFrames(msgs ...string) string {
+	var b strings.Builder
+	for _, m := range msgs {
+		b.WriteString(frameMarker)
+		b.WriteString(strconv.Itoa(len(m)))
+		b.WriteString(frameMarker)
+		b.WriteString(m)
+	}
+	return b.String()
+}
+
+// frameBodySize returns the number of bytes of body covered by a declared
+// frame length of n. The length is normally a byte count, but TradingView's
+// JS client computes it in UTF-16 code units, so when the byte count does not
+// land on a frame boundary the length is re-measured in code units.
+func frameBodySize(body string, n int) int {
+	if n >= len(body) {
+		return len(body)
+	}
+	if strings.HasPrefix(body[n:], frameMarker) {
+		return n
+	}
+	units := 0
+	for i, r := range body {
+		if units >= n {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(body)
+}
+
+// IsHeartbeat reports whether a split message is a ~h~ keep-alive ping.
+func IsHeartbeat(msg string) bool {
+	return strings.HasPrefix(msg, heartbeatPrefix)
+}
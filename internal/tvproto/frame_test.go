@@ -0,0 +1,70 @@
+package tvproto
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{
+			name:    "plain_json",
+			payload: `{"m":"alert_fired"}`,
+			want:    []string{`{"m":"alert_fired"}`},
+		},
+		{
+			name:    "single_frame",
+			payload: `~m~10~m~{"m":"du"}`,
+			want:    []string{`{"m":"du"}`},
+		},
+		{
+			name:    "concatenated_frames",
+			payload: `~m~11~m~{"m":"qsd"}~m~10~m~{"m":"du"}~m~4~m~~h~1`,
+			want:    []string{`{"m":"qsd"}`, `{"m":"du"}`, `~h~1`},
+		},
+		{
+			name:    "utf16_length",
+			payload: `~m~11~m~{"n":"😀€"}~m~10~m~{"m":"du"}`,
+			want:    []string{`{"n":"😀€"}`, `{"m":"du"}`},
+		},
+		{
+			name:    "truncated_frame",
+			payload: `~m~99~m~{"m":"du"}`,
+			want:    []string{`{"m":"du"}`},
+		},
+		{
+			name:    "empty",
+			payload: "",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitFrames(tt.payload)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitFrames(%q) = %q, want %q", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinFrames(t *testing.T) {
+	msgs := []string{`{"m":"qsd"}`, `{"n":"€"}`, `~h~1`}
+	joined := JoinFrames(msgs...)
+	if want := `~m~11~m~{"m":"qsd"}~m~11~m~{"n":"€"}~m~4~m~~h~1`; joined != want {
+		t.Fatalf("JoinFrames() = %q, want %q", joined, want)
+	}
+	if got := SplitFrames(joined); !reflect.DeepEqual(got, msgs) {
+		t.Fatalf("SplitFrames(JoinFrames()) = %q", got)
+	}
+}
+
+func TestParseMessageRejectsHeartbeat(t *testing.T) {
+	if _, ok := ParseMessage("~h~12"); ok {
+		t.Fatalf("ParseMessage(heartbeat) ok = true, want false")
+	}
+}
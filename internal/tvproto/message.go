@@ -0,0 +1,108 @@
+// Package tvproto parses TradingView's chart-socket protocol: the ~m~
+// framing, the JSON messages inside it and the series IDs those messages
+// refer to. It is shared by the controller's relay and the researcher tools.
+package tvproto
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Message is a single JSON message split out of a chart-socket or pushstream frame.
+// Type is the "m" field; Params holds the raw "p" value.
+type Message struct {
+	Type   string          `json:"m"`
+	Params json.RawMessage `json:"p,omitempty"`
+}
+
+// ParseMessage parses a single (already split) message. It returns false for
+// heartbeats and anything that is not a JSON object. Pushstream envelopes
+// ({"id":N,"text":{"content":...}}) are unwrapped to the inner message.
+func ParseMessage(raw string) (Message, bool) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return Message{}, false
+	}
+	var msg struct {
+		Message
+		Text *struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return Message{}, false
+	}
+	if msg.Type == "" && msg.Text != nil {
+		return parseEnvelopeContent(msg.Text.Content)
+	}
+	return msg.Message, true
+}
+
+// parseEnvelopeContent decodes text.content, which is either the message
+// object itself or a JSON string holding it.
+func parseEnvelopeContent(content json.RawMessage) (Message, bool) {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		content = json.RawMessage(s)
+	}
+	var msg Message
+	if err := json.Unmarshal(content, &msg); err != nil || msg.Type == "" {
+		return Message{}, false
+	}
+	return msg, true
+}
+
+// ParamList returns Params as an array. Chart-socket messages always carry an
+// array; pushstream messages may carry an object, which yields a one-element list.
+func (m Message) ParamList() []json.RawMessage {
+	p := strings.TrimSpace(string(m.Params))
+	if p == "" || p == "null" {
+		return nil
+	}
+	if p[0] != '[' {
+		return []json.RawMessage{m.Params}
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(m.Params, &list); err != nil {
+		return nil
+	}
+	return list
+}
+
+// Session returns the session ID (first param) of a chart-socket message,
+// e.g. "cs_abc123" or "qs_multiplexer_watchlist_abc123".
+func (m Message) Session() string {
+	list := m.ParamList()
+	if len(list) == 0 {
+		return ""
+	}
+	return ParamString(list, 0)
+}
+
+// ParamString returns p[i] as a string, or "" if absent or not a string.
+func ParamString(p []json.RawMessage, i int) string {
+	if i >= len(p) {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(p[i], &s); err != nil {
+		return ""
+	}
+	return s
+}
+
+// ParseSymbolSpec returns the plain ticker of a symbol spec, unwrapping the
+// "={\"symbol\":\"COINBASE:BTCUSD\",...}" encoded form TradingView uses
+// when a symbol carries session or adjustment settings.
+func ParseSymbolSpec(s string) string {
+	if !strings.HasPrefix(s, "=") {
+		return s
+	}
+	var enc struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal([]byte(s[1:]), &enc); err != nil {
+		return s
+	}
+	return enc.Symbol
+}
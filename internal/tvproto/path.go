@@ -0,0 +1,57 @@
+package tvproto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathStep is one segment of a JSON path: an object key, an array index,
+// or a wildcard ("*" key or [*] index) when Any is set.
+type PathStep struct {
+	Key   string
+	Index int
+	Any   bool
+}
+
+// ParsePath parses a JSON path such as "p[1].n", "$.user.email" or
+// "items[*].token". The leading "$" is optional.
+func ParsePath(s string) ([]PathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(s, "$"), ".")
+	if rest == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	var steps []PathStep
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed [", s)
+			}
+			idx := rest[1:end]
+			if idx == "*" {
+				steps = append(steps, PathStep{Any: true})
+			} else {
+				n, err := strconv.Atoi(idx)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("path %q: bad index %q", s, idx)
+				}
+				steps = append(steps, PathStep{Index: n})
+			}
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q: empty key", s)
+			}
+			key := rest[:end]
+			steps = append(steps, PathStep{Key: key, Any: key == "*"})
+			rest = strings.TrimPrefix(rest[end:], ".")
+		}
+	}
+	return steps, nil
+}
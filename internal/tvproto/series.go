@@ -0,0 +1,266 @@
+package tvproto
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// SeriesInfo describes what a chart-session series or study ID refers to.
+// Studies inherit Symbol and Resolution from their parent series.
+type SeriesInfo struct {
+	ChartID    string `json:"chart_id,omitempty"`
+	Session    string `json:"session"`
+	SeriesID   string `json:"series_id"`
+	Kind       string `json:"kind"` // "series" or "study"
+	SymbolID   string `json:"symbol_id,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+	Parent     string `json:"parent,omitempty"`
+	StudyName  string `json:"study_name,omitempty"`
+}
+
+type seriesRef struct {
+	chartID, session, id string
+}
+
+// SeriesRegistry correlates chart-session IDs with what they represent by
+// watching the client's resolve_symbol, create_series, modify_series and
+// create_study messages and the server's symbol_resolved replies. It also
+// records the symbols added to each quote session. Safe for concurrent use;
+// Observe never blocks, so it can run on the CDP event loop.
+type SeriesRegistry struct {
+	mu      sync.RWMutex
+	symbols map[seriesRef]string // resolve_symbol alias (sds_sym_N) → symbol
+	series  map[seriesRef]*SeriesInfo
+	main    map[seriesRef]string          // chart session → first series created
+	quotes  map[seriesRef]map[string]bool // quote session → symbols
+}
+
+// NewSeriesRegistry creates an empty registry.
+func NewSeriesRegistry() *SeriesRegistry {
+	return &SeriesRegistry{
+		symbols: make(map[seriesRef]string),
+		series:  make(map[seriesRef]*SeriesInfo),
+		main:    make(map[seriesRef]string),
+		quotes:  make(map[seriesRef]map[string]bool),
+	}
+}
+
+// Observe updates the registry from one chart-socket message seen on the
+// socket of chartID, in either direction.
+func (r *SeriesRegistry) Observe(chartID string, msg Message) {
+	switch msg.Type {
+	case "resolve_symbol", "symbol_resolved", "create_series", "modify_series",
+		"remove_series", "create_study", "modify_study", "remove_study",
+		"quote_add_symbols", "quote_remove_symbols", "chart_delete_session":
+	default:
+		return
+	}
+	p := msg.ParamList()
+	session := ParamString(p, 0)
+	if session == "" {
+		return
+	}
+	ref := func(id string) seriesRef { return seriesRef{chartID: chartID, session: session, id: id} }
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch msg.Type {
+	case "resolve_symbol":
+		// [session, "sds_sym_1", "={\"symbol\":\"BINANCE:BTCUSDT\",...}"]
+		if sym := ParseSymbolSpec(ParamString(p, 2)); sym != "" {
+			r.symbols[ref(ParamString(p, 1))] = sym
+			r.relabelLocked(chartID, session, ParamString(p, 1), sym)
+		}
+	case "symbol_resolved":
+		// [session, "sds_sym_1", {"pro_name":"BINANCE:BTCUSDT",...}]
+		if len(p) > 2 {
+			var info struct {
+				ProName  string `json:"pro_name"`
+				FullName string `json:"full_name"`
+			}
+			if json.Unmarshal(p[2], &info) == nil {
+				sym := info.ProName
+				if sym == "" {
+					sym = info.FullName
+				}
+				if sym != "" {
+					r.symbols[ref(ParamString(p, 1))] = sym
+					r.relabelLocked(chartID, session, ParamString(p, 1), sym)
+				}
+			}
+		}
+	case "create_series", "modify_series":
+		// [session, "sds_1", "s1", "sds_sym_1", "1", 300, ""]
+		id := ParamString(p, 1)
+		info := r.series[ref(id)]
+		if info == nil {
+			info = &SeriesInfo{ChartID: chartID, Session: session, SeriesID: id, Kind: "series"}
+			r.series[ref(id)] = info
+		}
+		if _, ok := r.main[ref("")]; !ok && msg.Type == "create_series" {
+			r.main[ref("")] = id
+		}
+		info.SymbolID = ParamString(p, 3)
+		info.Symbol = r.symbols[ref(info.SymbolID)]
+		if res := ParamString(p, 4); res != "" {
+			info.Resolution = res
+		}
+	case "create_study", "modify_study":
+		// [session, "st4", "st1", "sds_1", "Script@tv-scripting-101!", {...}]
+		id := ParamString(p, 1)
+		info := r.series[ref(id)]
+		if info == nil {
+			info = &SeriesInfo{ChartID: chartID, Session: session, SeriesID: id, Kind: "study"}
+			r.series[ref(id)] = info
+		}
+		if msg.Type == "create_study" {
+			info.Parent = ParamString(p, 3)
+			info.StudyName = ParamString(p, 4)
+		}
+	case "remove_series", "remove_study":
+		id := ParamString(p, 1)
+		delete(r.series, ref(id))
+		if r.main[ref("")] == id {
+			delete(r.main, ref(""))
+		}
+	case "quote_add_symbols", "quote_remove_symbols":
+		set := r.quotes[ref("")]
+		if set == nil {
+			set = make(map[string]bool)
+			r.quotes[ref("")] = set
+		}
+		for i := 1; i < len(p); i++ {
+			sym := ParseSymbolSpec(ParamString(p, i))
+			if sym == "" {
+				continue
+			}
+			if msg.Type == "quote_add_symbols" {
+				set[sym] = true
+			} else {
+				delete(set, sym)
+			}
+		}
+	case "chart_delete_session":
+		r.dropSessionLocked(chartID, session)
+	}
+}
+
+func (r *SeriesRegistry) relabelLocked(chartID, session, symbolID, symbol string) {
+	for k, info := range r.series {
+		if k.chartID == chartID && k.session == session && info.SymbolID == symbolID {
+			info.Symbol = symbol
+		}
+	}
+}
+
+func (r *SeriesRegistry) dropSessionLocked(chartID, session string) {
+	for k := range r.symbols {
+		if k.chartID == chartID && k.session == session {
+			delete(r.symbols, k)
+		}
+	}
+	for k := range r.series {
+		if k.chartID == chartID && k.session == session {
+			delete(r.series, k)
+		}
+	}
+	delete(r.main, seriesRef{chartID: chartID, session: session})
+	delete(r.quotes, seriesRef{chartID: chartID, session: session})
+}
+
+// Forget drops every symbol, series, study and quote session recorded for
+// chartID, e.g. once its chart socket has closed.
+func (r *SeriesRegistry) Forget(chartID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k := range r.symbols {
+		if k.chartID == chartID {
+			delete(r.symbols, k)
+		}
+	}
+	for k := range r.series {
+		if k.chartID == chartID {
+			delete(r.series, k)
+		}
+	}
+	for k := range r.main {
+		if k.chartID == chartID {
+			delete(r.main, k)
+		}
+	}
+	for k := range r.quotes {
+		if k.chartID == chartID {
+			delete(r.quotes, k)
+		}
+	}
+}
+
+// Lookup returns what a series or study ID in a chart session refers to.
+// For a study, Symbol and Resolution are taken from its parent series.
+func (r *SeriesRegistry) Lookup(chartID, session, id string) (SeriesInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	info, ok := r.series[seriesRef{chartID: chartID, session: session, id: id}]
+	if !ok {
+		return SeriesInfo{}, false
+	}
+	out := *info
+	if out.Kind == "study" && out.Parent != "" {
+		if parent, ok := r.series[seriesRef{chartID: chartID, session: session, id: out.Parent}]; ok {
+			out.Symbol, out.Resolution = parent.Symbol, parent.Resolution
+		}
+	}
+	return out, true
+}
+
+// MainSeries returns the ID of the first series created in a chart session,
+// which TradingView uses for the chart's main symbol.
+func (r *SeriesRegistry) MainSeries(chartID, session string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.main[seriesRef{chartID: chartID, session: session}]
+	return id, ok
+}
+
+// QuoteSymbols returns the symbols currently added to a quote session.
+func (r *SeriesRegistry) QuoteSymbols(chartID, session string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := r.quotes[seriesRef{chartID: chartID, session: session}]
+	out := make([]string, 0, len(set))
+	for sym := range set {
+		out = append(out, sym)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// List returns every known series and study, ordered by chart, session and ID.
+func (r *SeriesRegistry) List() []SeriesInfo {
+	r.mu.RLock()
+	out := make([]SeriesInfo, 0, len(r.series))
+	for _, info := range r.series {
+		out = append(out, *info)
+	}
+	r.mu.RUnlock()
+	for i := range out {
+		if out[i].Kind == "study" {
+			if full, ok := r.Lookup(out[i].ChartID, out[i].Session, out[i].SeriesID); ok {
+				out[i] = full
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.ChartID != b.ChartID {
+			return a.ChartID < b.ChartID
+		}
+		if a.Session != b.Session {
+			return a.Session < b.Session
+		}
+		return a.SeriesID < b.SeriesID
+	})
+	return out
+}
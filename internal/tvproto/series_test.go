@@ -0,0 +1,45 @@
+package tvproto
+
+import "testing"
+
+func mustParse(t *testing.T, raw string) Message {
+	t.Helper()
+	msg, ok := ParseMessage(raw)
+	if !ok {
+		t.Fatalf("parse %s", raw)
+	}
+	return msg
+}
+
+func TestSeriesRegistryMapsSeriesAndStudies(t *testing.T) {
+	r := NewSeriesRegistry()
+	r.Observe("abc", mustParse(t, `{"m":"resolve_symbol","p":["cs_1","sds_sym_1","={\"symbol\":\"NASDAQ:AAPL\",\"adjustment\":\"splits\"}"]}`))
+	r.Observe("abc", mustParse(t, `{"m":"create_series","p":["cs_1","sds_1","s1","sds_sym_1","60",300,""]}`))
+	r.Observe("abc", mustParse(t, `{"m":"create_study","p":["cs_1","st4","st1","sds_1","Volume@tv-basicstudies-246",{}]}`))
+	r.Observe("abc", mustParse(t, `{"m":"quote_add_symbols","p":["qs_1","NASDAQ:AAPL","={\"symbol\":\"NYSE:IBM\"}"]}`))
+
+	s, ok := r.Lookup("abc", "cs_1", "sds_1")
+	if !ok || s.Symbol != "NASDAQ:AAPL" || s.Resolution != "60" || s.Kind != "series" {
+		t.Fatalf("series = %+v, %v", s, ok)
+	}
+	st, ok := r.Lookup("abc", "cs_1", "st4")
+	if !ok || st.Symbol != "NASDAQ:AAPL" || st.Resolution != "60" || st.StudyName != "Volume@tv-basicstudies-246" {
+		t.Fatalf("study = %+v, %v", st, ok)
+	}
+	if got := r.QuoteSymbols("abc", "qs_1"); len(got) != 2 || got[0] != "NASDAQ:AAPL" || got[1] != "NYSE:IBM" {
+		t.Fatalf("quote symbols = %v", got)
+	}
+
+	// A symbol change re-resolves under a new alias and modifies the series.
+	r.Observe("abc", mustParse(t, `{"m":"resolve_symbol","p":["cs_1","sds_sym_2","NASDAQ:MSFT"]}`))
+	r.Observe("abc", mustParse(t, `{"m":"modify_series","p":["cs_1","sds_1","s2","sds_sym_2","D",""]}`))
+	r.Observe("abc", mustParse(t, `{"m":"symbol_resolved","p":["cs_1","sds_sym_2",{"pro_name":"NASDAQ:MSFT.X"}]}`))
+	if s, _ := r.Lookup("abc", "cs_1", "sds_1"); s.Symbol != "NASDAQ:MSFT.X" || s.Resolution != "D" {
+		t.Fatalf("modified series = %+v", s)
+	}
+
+	r.Observe("abc", mustParse(t, `{"m":"chart_delete_session","p":["cs_1"]}`))
+	if _, ok := r.Lookup("abc", "cs_1", "sds_1"); ok || len(r.List()) != 0 {
+		t.Fatalf("session not dropped: %+v", r.List())
+	}
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"bytes"
+	"time"
+)
+
+// EventCaptureGap is the event_type of a CaptureGap marker.
+const EventCaptureGap = "capture_gap"
+
+// CaptureGap reasons.
+const (
+	GapReasonDisconnected = "browser_disconnected"
+	GapReasonPaused       = "paused"
+)
+
+// CaptureGap marks a window in which a tab's traffic was not captured, e.g.
+// while the researcher reconnected to a restarted browser. It is written to
+// the tab's http and websocket streams alongside the regular records.
+type CaptureGap struct {
+	Timestamp time.Time `json:"timestamp"`
+	EventType string    `json:"event_type"`
+	TabID     string    `json:"tab_id"`
+	URL       string    `json:"url"`
+	GapStart  time.Time `json:"gap_start"`
+	GapEnd    time.Time `json:"gap_end"`
+	Reason    string    `json:"reason"`
+	Attempts  int       `json:"attempts,omitempty"`
+}
+
+// IsCaptureGap reports whether a JSONL line is a CaptureGap marker.
+func IsCaptureGap(line []byte) bool {
+	return bytes.Contains(line, []byte(`"event_type":"`+EventCaptureGap+`"`))
+}